@@ -1,20 +1,28 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"net"
 	"net/http"
 	"os"
+	"os/signal"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/golang/glog"
+	"github.com/ryutah/kubernetes-transcribe/pkg/api"
+	"github.com/ryutah/kubernetes-transcribe/pkg/api/latest"
 	"github.com/ryutah/kubernetes-transcribe/pkg/apiserver"
+	"github.com/ryutah/kubernetes-transcribe/pkg/audit"
 	"github.com/ryutah/kubernetes-transcribe/pkg/capabilities"
 	"github.com/ryutah/kubernetes-transcribe/pkg/client"
 	"github.com/ryutah/kubernetes-transcribe/pkg/cloudprovider"
+	"github.com/ryutah/kubernetes-transcribe/pkg/healthz"
 	"github.com/ryutah/kubernetes-transcribe/pkg/master"
+	"github.com/ryutah/kubernetes-transcribe/pkg/runtime"
 	"github.com/ryutah/kubernetes-transcribe/pkg/util"
 	"github.com/ryutah/kubernetes-transcribe/pkg/version/verflag"
 )
@@ -24,6 +32,7 @@ var (
 	address               = flag.String("address", "127.0.0.1", "The address on the local server to listen to. Default 127.0.0.1")
 	apiPrefix             = flag.String("api_prefix", "/api", "The prefix for API requests on the server. Default '/api'")
 	storageVersion        = flag.String("storage_version", "", "The version to store resources with. Defaults to server preferred")
+	storageCodec          = flag.String("storage_codec", "json", "The encoding to store resources with in etcd, 'json' or 'protobuf'. Default 'json'")
 	cloudProvider         = flag.String("cloud_provider", "", "The provider for cloud services.  Empty string for no provider.")
 	cloudConfigFile       = flag.String("cloud_config", "", "The path to the cloud provider configuration file.  Empty string for no configuration file.")
 	minionRegexp          = flag.String("minion_regexp", "", "If non empty, and -cloud_provider is specified, a regular expression for matching minion VMs")
@@ -33,13 +42,25 @@ var (
 	etcdServerList        util.StringList
 	machineList           util.StringList
 	corsAllowedOriginList util.StringList
+	validateComponents    util.StringList
+	validateTimeout       = flag.Duration("validate_timeout", 5*time.Second, "Maximum duration of each component health check made by /validate. Default 5 seconds")
 	allowPrivileged       = flag.Bool("allow_privileged", false, "If true, allow privileged containers.")
+	maxPendingOperations  = flag.Int("max_pending_operations", 500, "/healthz fails while more than this many operations are outstanding. Default 500")
+	drainDelay            = flag.Duration("drain_delay", 5*time.Second, "Duration to keep serving after SIGTERM while /healthz reports failure, so that load balancers can take the server out of rotation. Default 5 seconds")
+	drainTimeout          = flag.Duration("drain_timeout", 60*time.Second, "Maximum duration to wait for in-flight requests and operations to finish on shutdown. Default 60 seconds")
+	auditLogPath          = flag.String("audit_log_path", "", "If non empty, mutating API requests are recorded to this file as JSON lines.")
+	auditLogMaxSize       = flag.Int64("audit_log_max_size", 100<<20, "Size in bytes at which the audit log is rotated. 0 disables rotation. Default 100MB")
+	auditLogMaxBackups    = flag.Int("audit_log_max_backups", 5, "Number of rotated audit logs to keep. Default 5")
+	auditLogMaxBodySize   = flag.Int("audit_log_max_body_size", 0, "If positive, up to this many bytes of each request and response body are recorded in the audit log. Default 0")
+	proxyTimeout          = flag.Duration("proxy_timeout", 30*time.Second, "Maximum duration to connect to a proxied service and wait for its response headers. 0 for no limit. Default 30 seconds")
+	proxyMaxBodySize      = flag.Int64("proxy_max_body_size", 0, "If positive, the largest request or response body in bytes passed through the proxy. Default 0")
 )
 
 func init() {
 	flag.Var(&etcdServerList, "etcd_servers", "List of etcd servers to watch (http://ip:port), comma separated")
 	flag.Var(&machineList, "machines", "List of machines to schedule onto, comma separated.")
 	flag.Var(&corsAllowedOriginList, "cors_allowed_origins", "List of allowed origins for CORS, comma separated.  An allowed origin can be a regular expression to support subdomain matching.  If this list is empty CORS will not be enabled.")
+	flag.Var(&validateComponents, "validate_components", "List of name=host:port components whose /healthz is checked by /validate, comma separated. Defaults to controller-manager=127.0.0.1:10252,scheduler=127.0.0.1:10251")
 }
 
 func verifyMinionFlags() {
@@ -54,6 +75,25 @@ func verifyMinionFlags() {
 	}
 }
 
+// validatorServers returns the components listed by -validate_components, keyed by name.
+func validatorServers() map[string]string {
+	if len(validateComponents) == 0 {
+		return map[string]string{
+			"controller-manager": "127.0.0.1:10252",
+			"scheduler":          "127.0.0.1:10251",
+		}
+	}
+	servers := map[string]string{}
+	for _, component := range validateComponents {
+		parts := strings.SplitN(component, "=", 2)
+		if len(parts) != 2 {
+			glog.Fatalf("Invalid component %q, expected name=host:port", component)
+		}
+		servers[parts[0]] = parts[1]
+	}
+	return servers
+}
+
 func initCloudProvider(name, configFilePath string) cloudprovider.Interface {
 	var config *os.File
 
@@ -114,6 +154,18 @@ func main() {
 	if err != nil {
 		glog.Fatalf("Invalid storage version: %v", err)
 	}
+	switch *storageCodec {
+	case "json":
+	case "protobuf":
+		version := *storageVersion
+		if version == "" {
+			version = latest.Version
+		}
+		// Data written as JSON before the switch stays readable.
+		helper.Codec = runtime.Base64Codec(runtime.ProtobufCodecFor(api.Scheme, version))
+	default:
+		glog.Fatalf("Invalid storage codec %q, expected 'json' or 'protobuf'", *storageCodec)
+	}
 
 	m := master.New(&master.Config{
 		Client:             cli,
@@ -127,10 +179,59 @@ func main() {
 	})
 
 	mux := http.NewServeMux()
-	apiserver.NewAPIGroup(m.API_v1beta1()).InstallREST(mux, *apiPrefix+"/v1beta1")
-	apiserver.NewAPIGroup(m.API_v1beta2()).InstallREST(mux, *apiPrefix+"/v1beta2")
+	proxyConfig := apiserver.ProxyConfig{
+		Timeout:     *proxyTimeout,
+		MaxBodySize: *proxyMaxBodySize,
+	}
+	v1beta1 := apiserver.NewAPIGroup(m.API_v1beta1())
+	v1beta1.SetProtobufCodec(runtime.ProtobufCodecFor(api.Scheme, "v1beta1"))
+	v1beta1.SetProxyConfig(proxyConfig)
+	v1beta1.InstallREST(mux, *apiPrefix+"/v1beta1")
+	v1beta2 := apiserver.NewAPIGroup(m.API_v1beta2())
+	v1beta2.SetProtobufCodec(runtime.ProtobufCodecFor(api.Scheme, "v1beta2"))
+	v1beta2.SetProxyConfig(proxyConfig)
+	v1beta2.InstallREST(mux, *apiPrefix+"/v1beta2")
+	podLogs := &apiserver.PodLogStreamer{Client: http.DefaultClient, Port: int(*minionPort)}
+	v1beta1.SetPodLogStreamer(podLogs)
+	v1beta2.SetPodLogStreamer(podLogs)
+	podExec := &apiserver.PodExecProxy{Port: int(*minionPort)}
+	v1beta1.SetPodExecProxy(podExec)
+	v1beta2.SetPodExecProxy(podExec)
+	podPortForwarder := &apiserver.PodPortForwarder{}
+	v1beta1.SetPodPortForwarder(podPortForwarder)
+	v1beta2.SetPodPortForwarder(podPortForwarder)
+	validatorConfig := apiserver.ValidatorConfig{
+		Servers:     validatorServers(),
+		KubeletPort: int(*minionPort),
+		Timeout:     *validateTimeout,
+	}
+	if err := v1beta1.InstallValidator(mux, validatorConfig, *apiPrefix+"/v1beta1"); err != nil {
+		glog.Fatalf("Failed to set up validator: %v", err)
+	}
+	if err := v1beta2.InstallValidator(mux, validatorConfig, *apiPrefix+"/v1beta2"); err != nil {
+		glog.Fatalf("Failed to set up validator: %v", err)
+	}
 	apiserver.InstallSupport(mux)
 
+	if *auditLogPath != "" {
+		sink, err := audit.NewFileSink(*auditLogPath, *auditLogMaxSize, *auditLogMaxBackups)
+		if err != nil {
+			glog.Fatalf("Failed to open audit log: %v", err)
+		}
+		defer sink.Close()
+		auditor := audit.New(sink, *auditLogMaxBodySize)
+		v1beta1.SetAuditor(auditor)
+		v1beta2.SetAuditor(auditor)
+	}
+
+	healthz.AddHealthzCheck(
+		apiserver.EtcdHealthCheck(helper),
+		apiserver.OperationsHealthCheck(*maxPendingOperations, v1beta1, v1beta2),
+	)
+	if cloud != nil {
+		healthz.AddHealthzCheck(apiserver.CloudHealthCheck(cloud, *minionRegexp))
+	}
+
 	handler := http.Handler(mux)
 	if len(corsAllowedOriginList) > 0 {
 		allowedOriginRegexp, err := util.CompileRegexps(corsAllowedOriginList)
@@ -149,5 +250,48 @@ func main() {
 		MaxHeaderBytes: 1 << 20,
 	}
 
-	glog.Fatal(s.ListenAndServe())
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		drainOnSignal(s, v1beta1, v1beta2)
+	}()
+
+	if err := s.ListenAndServe(); err != http.ErrServerClosed {
+		glog.Fatal(err)
+	}
+	<-drained
+}
+
+// drainOnSignal blocks until SIGTERM or SIGINT is received and then shuts s down
+// gracefully. /healthz starts failing at once; after -drain_delay the listener is
+// closed, open watches are ended, and in-flight requests and operations are given
+// until -drain_timeout to finish.
+func drainOnSignal(s *http.Server, groups ...*apiserver.APIGroup) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+	drain(signals, s, groups...)
+}
+
+// drain is drainOnSignal, for the signals received on signals.
+func drain(signals <-chan os.Signal, s *http.Server, groups ...*apiserver.APIGroup) {
+	glog.Infof("Received %v, draining", <-signals)
+
+	healthz.SetDraining(true)
+	time.Sleep(*drainDelay)
+
+	deadline := time.Now().Add(*drainTimeout)
+	for _, group := range groups {
+		group.StopWatches()
+	}
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != nil {
+		glog.Errorf("Failed to close all connections before the deadline: %v", err)
+	}
+	for _, group := range groups {
+		if !group.WaitForOperations(time.Until(deadline)) {
+			glog.Errorf("Operations were still running at the deadline")
+		}
+	}
+	glog.Infof("Drained, exiting")
 }
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/ryutah/kubernetes-transcribe/pkg/api"
+	"github.com/ryutah/kubernetes-transcribe/pkg/apiserver"
+	"github.com/ryutah/kubernetes-transcribe/pkg/healthz"
+	"github.com/ryutah/kubernetes-transcribe/pkg/labels"
+	"github.com/ryutah/kubernetes-transcribe/pkg/runtime"
+	"github.com/ryutah/kubernetes-transcribe/pkg/watch"
+)
+
+// watchStorage serves watches which never send anything, and nothing else.
+type watchStorage struct{}
+
+func (watchStorage) New() runtime.Object { return &api.Pod{} }
+func (watchStorage) List(label, field labels.Selector) (runtime.Object, error) {
+	return &api.PodList{}, nil
+}
+func (watchStorage) Get(id string) (runtime.Object, error)                    { return nil, nil }
+func (watchStorage) Delete(id string) (<-chan runtime.Object, error)          { return nil, nil }
+func (watchStorage) Create(obj runtime.Object) (<-chan runtime.Object, error) { return nil, nil }
+func (watchStorage) Update(obj runtime.Object) (<-chan runtime.Object, error) { return nil, nil }
+func (watchStorage) Watch(label, field labels.Selector, resourceVersion uint64) (watch.Interface, error) {
+	return watch.NewFake(), nil
+}
+
+func TestDrain(t *testing.T) {
+	defer func(delay, timeout time.Duration) {
+		*drainDelay, *drainTimeout = delay, timeout
+		healthz.SetDraining(false)
+	}(*drainDelay, *drainTimeout)
+	*drainDelay, *drainTimeout = 100*time.Millisecond, 10*time.Second
+
+	// The versioned API types are not registered yet, so tests use the internal version.
+	group := apiserver.NewAPIGroup(map[string]apiserver.RESTStorage{"pods": watchStorage{}}, runtime.CodecFor(api.Scheme, ""))
+	mux := http.NewServeMux()
+	group.InstallREST(mux, "/api/v1beta1")
+	apiserver.InstallSupport(mux)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s := &http.Server{Handler: mux}
+	go s.Serve(listener)
+	url := "http://" + listener.Addr().String()
+
+	watching, err := http.Get(url + "/api/v1beta1/watch/pods")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer watching.Body.Close()
+
+	signals := make(chan os.Signal, 1)
+	drained := make(chan struct{})
+	start := time.Now()
+	go func() {
+		defer close(drained)
+		drain(signals, s, group)
+	}()
+	signals <- syscall.SIGTERM
+
+	// Until the delay is over, /healthz fails but requests are still served.
+	for {
+		resp, err := http.Get(url + "/healthz")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusServiceUnavailable {
+			break
+		}
+		if time.Since(start) > *drainDelay {
+			t.Fatalf("expected /healthz to fail while draining")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	select {
+	case <-drained:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("expected the open watch not to hold up the shutdown")
+	}
+	if d := time.Since(start); d < *drainDelay {
+		t.Errorf("expected the server to keep serving for %v, drained after %v", *drainDelay, d)
+	}
+	if _, err := ioutil.ReadAll(watching.Body); err != nil {
+		t.Errorf("expected the watch to end cleanly, got %v", err)
+	}
+	if _, err := http.Get(url + "/healthz"); err == nil {
+		t.Errorf("expected the listener to be closed")
+	}
+}
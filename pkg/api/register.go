@@ -23,5 +23,10 @@ func init() {
 		&Endpoints{},
 		&EndpointsList{},
 		&Binding{},
+		&ComponentStatus{},
+		&ComponentStatusList{},
+		&Diff{},
+		&DeleteOptions{},
+		&StatusList{},
 	)
 }
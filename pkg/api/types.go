@@ -209,6 +209,9 @@ type JSONBase struct {
 	SelfLink          string    `json:"selfLink,omitempty" yaml:"selfLink,omitempty"`
 	ResourceVersion   uint64    `json:"resourceVersion,omitempty" yaml:"resourceVersion,omitempty"`
 	APIVersion        string    `json:"apiVersion,omitempty" yaml:"apiVersion,omitempty"`
+	// DeletionTimestamp is set by the storage when a graceful deletion is requested,
+	// to the time after which the object will be removed.
+	DeletionTimestamp *util.Time `json:"deletionTimestamp,omitempty" yaml:"deletionTimestamp,omitempty"`
 }
 
 // PodStatus represents a status of a pod.
@@ -521,6 +524,12 @@ const (
 	//                   field attributes will be set.
 	// Status code 422
 	StatusReasonInvalid StatusReason = "invalid"
+
+	// StatusReasonExpired means the resource version a watch was asked to start
+	// from is too old: the changes since are no longer available. The client
+	// should list the resources again, and watch from the version listed.
+	// Status code 410
+	StatusReasonExpired StatusReason = "expired"
 )
 
 // StatusCause provides more information about an api.Status failure, including
@@ -581,3 +590,72 @@ type ServerOpList struct {
 }
 
 func (*ServerOpList) IsAnAPIObject() {}
+
+// ComponentStatus is the health of a single cluster component, as reported by
+// the /validate endpoint. The component name is in JSONBase.ID.
+type ComponentStatus struct {
+	JSONBase `json:",inline" yaml:",inline"`
+	// One of: "healthy", "unhealthy", "unknown" (the check did not complete in time)
+	Health string `json:"health,omitempty" yaml:"health,omitempty"`
+	// A human-readable description of why the component isn't healthy.
+	Error string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+func (*ComponentStatus) IsAnAPIObject() {}
+
+// ComponentStatusList is a list of component statuses.
+type ComponentStatusList struct {
+	JSONBase `json:",inline" yaml:",inline"`
+	Items    []ComponentStatus `json:"items,omitempty" yaml:"items,omitempty"`
+}
+
+func (*ComponentStatusList) IsAnAPIObject() {}
+
+// Diff lists the fields in which a candidate object differs from the stored
+// version of it, as returned by POST /<resource>/<id>/diff. JSONBase.ID is the
+// object's ID.
+type Diff struct {
+	JSONBase `json:",inline" yaml:",inline"`
+	Changes  []FieldChange `json:"changes,omitempty" yaml:"changes,omitempty"`
+}
+
+func (*Diff) IsAnAPIObject() {}
+
+// FieldChange is a difference in a single field.
+type FieldChange struct {
+	// The path of the field, e.g. "desiredState.manifest.containers[0].image".
+	Path string `json:"path" yaml:"path"`
+	// The JSON encoding of the stored value; empty if the candidate adds the field.
+	Old string `json:"old,omitempty" yaml:"old,omitempty"`
+	// The JSON encoding of the candidate value; empty if the candidate removes the field.
+	New string `json:"new,omitempty" yaml:"new,omitempty"`
+}
+
+// DeleteOptions may be sent as the body of a DELETE request.
+type DeleteOptions struct {
+	JSONBase `json:",inline" yaml:",inline"`
+	// Preconditions must hold for the object to be deleted.
+	Preconditions *Preconditions `json:"preconditions,omitempty" yaml:"preconditions,omitempty"`
+	// GracePeriodSeconds is how long the object is kept, with its DeletionTimestamp
+	// set, before it is removed. Zero removes it at once; if unset, the storage
+	// chooses. Storage which doesn't support graceful deletion ignores it.
+	GracePeriodSeconds *int64 `json:"gracePeriodSeconds,omitempty" yaml:"gracePeriodSeconds,omitempty"`
+}
+
+func (*DeleteOptions) IsAnAPIObject() {}
+
+// Preconditions are conditions on the stored object for an operation to be carried out.
+type Preconditions struct {
+	// ResourceVersion, if not zero, is the version the object must be at. It is
+	// equivalent to an If-Match header.
+	ResourceVersion uint64 `json:"resourceVersion,omitempty" yaml:"resourceVersion,omitempty"`
+}
+
+// StatusList is the result of a batch request: one Status per item, in the order
+// the items were sent.
+type StatusList struct {
+	JSONBase `json:",inline" yaml:",inline"`
+	Items    []Status `json:"items,omitempty" yaml:"items,omitempty"`
+}
+
+func (*StatusList) IsAnAPIObject() {}
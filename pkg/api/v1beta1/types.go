@@ -33,23 +33,23 @@ import (
 // This is used as the representation of Kubernrtes workloads.
 type ContainerManifest struct {
 	// Required: This must be a supported version string, such as "v1beta1".
-	Version string `json:"version" yaml:"version"`
+	Version string `json:"version" yaml:"version" protobuf:"1"`
 	// Required: This must be a DNS_SUBDOMAIN.
 	// TODO: ID on Manifest is deprecated and will be removed in the future.
-	ID string `json:"id" yaml:"id"`
+	ID string `json:"id" yaml:"id" protobuf:"2"`
 	// TODO: UUID on Manifest is deprecated in the future once we are done
 	// with the API refactory. It is required for now to determine the instance
 	// of pod.
-	UUID          string        `json:"uuid,omitempty" yaml:"uuid,omitempty"`
-	Volumes       []Volume      `json:"volumes" yaml:"volumes"`
-	Containers    []Container   `json:"containers" yaml:"containers"`
-	RestartPolicy RestartPolicy `json:"restartPolicy,omitempty" yaml:"restartPolicy,omitempty"`
+	UUID          string        `json:"uuid,omitempty" yaml:"uuid,omitempty" protobuf:"3"`
+	Volumes       []Volume      `json:"volumes" yaml:"volumes" protobuf:"4"`
+	Containers    []Container   `json:"containers" yaml:"containers" protobuf:"5"`
+	RestartPolicy RestartPolicy `json:"restartPolicy,omitempty" yaml:"restartPolicy,omitempty" protobuf:"6"`
 }
 
 // ContainerManifestList is used to communicate container manifests to kubelet.
 type ContainerManifestList struct {
-	JSONBase `json:",inline" yaml:",inline"`
-	Items    []ContainerManifest `json:"items,omitempty" yaml:"items,omitempty"`
+	JSONBase `json:",inline" yaml:",inline" protobuf:"1"`
+	Items    []ContainerManifest `json:"items,omitempty" yaml:"items,omitempty" protobuf:"2"`
 }
 
 func (*ContainerManifestList) IsAnAPIObject() {}
@@ -58,11 +58,11 @@ func (*ContainerManifestList) IsAnAPIObject() {}
 type Volume struct {
 	// Required: This must be a DNS_LABEL.  Each volume in a pod must have
 	// a unique name.
-	Name string `json:"name" yaml:"name"`
+	Name string `json:"name" yaml:"name" protobuf:"1"`
 	// Source represents the location and type of a volume to mount.
 	// This is optional for now, If not specified, the Volume is impiled to be an EmptyDir.
 	// This impiled behavior is deprecated and will be removed in a future version.
-	Source *VolumeSource `json:"source" yaml:"source"`
+	Source *VolumeSource `json:"source" yaml:"source" protobuf:"2"`
 }
 
 type VolumeSource struct {
@@ -72,14 +72,14 @@ type VolumeSource struct {
 	// things that are allowed to see the host machine. Most containers will NOT need this.
 	// TODO: we need to restrict who can use host directory mounts and
 	// who can/can not mount host directoreis as read/write.
-	HostDirectory *HostDirectory `json:"hostDir" yaml:"hostDir"`
+	HostDirectory *HostDirectory `json:"hostDir" yaml:"hostDir" protobuf:"1"`
 	// EmptyDirectory represents a temporary directory that shares a pod's lifetime.
-	EmptyDirectory *EmptyDirectory `json:"emptyDir" yaml:"emptyDir"`
+	EmptyDirectory *EmptyDirectory `json:"emptyDir" yaml:"emptyDir" protobuf:"2"`
 }
 
 // HostDirectory represents bare host directory volume.
 type HostDirectory struct {
-	Path string `json:"path" yaml:"path"`
+	Path string `json:"path" yaml:"path" protobuf:"1"`
 }
 
 type EmptyDirectory struct{}
@@ -88,30 +88,30 @@ type EmptyDirectory struct{}
 type Port struct {
 	// Optional: If specified, this must be a DNS_LABEL.  Each named port
 	// in a pod must have a unique name.
-	Name string `yaml:"name,omitempty" json:"name,omitempty"`
+	Name string `yaml:"name,omitempty" json:"name,omitempty" protobuf:"1"`
 	// Optional: If specified, this must be a valid port number, 0 < x < 65536.
-	HostPort int `yaml:"hostPort,omitempty" json:"hostPort,omitempty"`
+	HostPort int `yaml:"hostPort,omitempty" json:"hostPort,omitempty" protobuf:"2"`
 	// Required: This must be a valid port number, 0 < x < 65536.
-	ContainerPort int `yaml:"containerPort" json:"containerPort"`
+	ContainerPort int `yaml:"containerPort" json:"containerPort" protobuf:"3"`
 	// Optional: Supports "TCP" and "UDP".  Defaults to "TCP".
-	Protocol string `yaml:"protocol,omitempty" json:"protocol,omitempty"`
+	Protocol string `yaml:"protocol,omitempty" json:"protocol,omitempty" protobuf:"4"`
 	// Optional: What host IP to bind the external port to.
-	HostIP string `yaml:"hostIP,omitempty" json:"hostIP,omitempty"`
+	HostIP string `yaml:"hostIP,omitempty" json:"hostIP,omitempty" protobuf:"5"`
 }
 
 // VolumeMount descrives a mounting of a Volume within a container.
 type VolumeMount struct {
 	// Required: This must match the Name of a Volume [above].
-	Name string `yaml:"name" json:"name"`
+	Name string `yaml:"name" json:"name" protobuf:"1"`
 	// Optional: Defaults to false (read-write).
-	ReadOnly bool `yaml:"readOnly,omitempty" json:"readOnly,omitempty"`
+	ReadOnly bool `yaml:"readOnly,omitempty" json:"readOnly,omitempty" protobuf:"2"`
 	// Required.
 	// Exactly one of the following must be set.  If both are set, prefer MountPath.  DEPRECATED: Path will be removed in a future version of the API.
-	MountPath string `yaml:"mountPath,omitempty" json:"mountPath,omitempty"`
-	Path      string `yaml:"path,omitempty" json:"path,omitempty"`
+	MountPath string `yaml:"mountPath,omitempty" json:"mountPath,omitempty" protobuf:"3"`
+	Path      string `yaml:"path,omitempty" json:"path,omitempty" protobuf:"4"`
 	// One of: "LOCAL" (local volume) or "HOST" (external mount from the host). Default: LOCAL.
 	// DEPRECATED: MountType will be removed in a future version of the API.
-	MountType string `yaml:"mountType,omitempty" json:"mountType,omitempty"`
+	MountType string `yaml:"mountType,omitempty" json:"mountType,omitempty" protobuf:"5"`
 }
 
 // EnvVar represents an environment variable present in a Container.
@@ -119,26 +119,26 @@ type EnvVar struct {
 	// Required: This must be a C_IDENTIFIER.
 	// Exactly one of the following must be set.  If both are set, prefer Name.
 	// DEPRECATED: EnvVar.Key will be removed in a future version of the API.
-	Name string `yaml:"name" json:"name"`
-	Key  string `yaml:"key,omitempty" json:"key,omitempty"`
+	Name string `yaml:"name" json:"name" protobuf:"1"`
+	Key  string `yaml:"key,omitempty" json:"key,omitempty" protobuf:"2"`
 	// Optional: defaults to "".
-	Value string `yaml:"value,omitempty" json:"value,omitempty"`
+	Value string `yaml:"value,omitempty" json:"value,omitempty" protobuf:"3"`
 }
 
 // HTTPGetAction describes an action based on HTTP Get requests.
 type HTTPGetAction struct {
 	// Optional: Path to access on the HTTP server.
-	Path string `yaml:"path,omitempty" json:"path,omitempty"`
+	Path string `yaml:"path,omitempty" json:"path,omitempty" protobuf:"1"`
 	// Required: Name or number of the port to access on the container.
-	Port util.IntOrString `yaml:"port,omitempty" json:"port,omitempty"`
+	Port util.IntOrString `yaml:"port,omitempty" json:"port,omitempty" protobuf:"2"`
 	// Optional: Host name to connect to, defaults to the pod IP.
-	Host string `yaml:"host,omitempty" json:"host,omitempty"`
+	Host string `yaml:"host,omitempty" json:"host,omitempty" protobuf:"3"`
 }
 
 // TCPSocketAction describes an action based on opening a socket.
 type TCPSocketAction struct {
 	// Required: Port to connect to.
-	Port util.IntOrString `yaml:"port,omitempty" json:"port,omitempty"`
+	Port util.IntOrString `yaml:"port,omitempty" json:"port,omitempty" protobuf:"1"`
 }
 
 // ExecAction describes a "run in container" action.
@@ -148,46 +148,46 @@ type ExecAction struct {
 	// not run inside a shell, so traditional shell instructions ('|', etc) won't work.
 	// Tu use a shell, you need to explicitly call out to that shell.
 	// A return code of zero is treated as 'Healthy', non-zero is 'Unhealthy'
-	Command []string `yaml:"command,omitempty" json:"command,omitempty"`
+	Command []string `yaml:"command,omitempty" json:"command,omitempty" protobuf:"1"`
 }
 
 // LivenessProbe describes a liveness probe to be examined to the container.
 // TODO: pass structured data to the actions. and document that data here.
 type LivenessProbe struct {
 	// Type of liveness probe.  Current legal values "http", "tcp"
-	Type string `yaml:"type,omitempty" json:"type,omitempty"`
+	Type string `yaml:"type,omitempty" json:"type,omitempty" protobuf:"1"`
 	// HTTPGetProbe parameters, required if Type == 'http'
-	HTTPGet *HTTPGetAction `yaml:"httpGet,omitempty" json:"httpGet,omitempty"`
+	HTTPGet *HTTPGetAction `yaml:"httpGet,omitempty" json:"httpGet,omitempty" protobuf:"2"`
 	// TCPSocketProbe parameter, required if Type == 'tcp'
-	TCPSocket *TCPSocketAction `yaml:"tcpSocket,omitempty" json:"tcpSocket,omitempty"`
+	TCPSocket *TCPSocketAction `yaml:"tcpSocket,omitempty" json:"tcpSocket,omitempty" protobuf:"3"`
 	// ExecProbe parameter, required if Type == 'exec'
-	Exec *ExecAction `yaml:"exec,omitempty" json:"exec,omitempty"`
+	Exec *ExecAction `yaml:"exec,omitempty" json:"exec,omitempty" protobuf:"4"`
 	// Length of time before health checking is activated.  In seconds.
-	InitialDelaySeconds int64 `yaml:"initialDelaySeconds,omitempty" json:"initialDelaySeconds,omitempty"`
+	InitialDelaySeconds int64 `yaml:"initialDelaySeconds,omitempty" json:"initialDelaySeconds,omitempty" protobuf:"5"`
 }
 
 // Container represents a single container that is expected to be run on the host.
 type Container struct {
 	// Required: This must be a DNS_LABEL.  Each container in a pod must
 	// have a unique name.
-	Name string `yaml:"name" json:"name"`
+	Name string `yaml:"name" json:"name" protobuf:"1"`
 	// Required.
-	Image string `yaml:"image" json:"image"`
+	Image string `yaml:"image" json:"image" protobuf:"2"`
 	// Optional: Defaults to whatever is defined in the image.
-	Command []string `yaml:"command,omitempty" json:"command,omitempty"`
+	Command []string `yaml:"command,omitempty" json:"command,omitempty" protobuf:"3"`
 	// Optional: Defaults to Docker's default.
-	WorkingDir string   `yaml:"workingDir,omitempty" json:"workingDir,omitempty"`
-	Ports      []Port   `yaml:"ports,omitempty" json:"ports,omitempty"`
-	Env        []EnvVar `yaml:"env,omitempty" json:"env,omitempty"`
+	WorkingDir string   `yaml:"workingDir,omitempty" json:"workingDir,omitempty" protobuf:"4"`
+	Ports      []Port   `yaml:"ports,omitempty" json:"ports,omitempty" protobuf:"5"`
+	Env        []EnvVar `yaml:"env,omitempty" json:"env,omitempty" protobuf:"6"`
 	// Optional: Defaults to unlimited.
-	Memory int `yaml:"memory,omitempty" json:"memory,omitempty"`
+	Memory int `yaml:"memory,omitempty" json:"memory,omitempty" protobuf:"7"`
 	// Optional: Defaults to unlimited.
-	CPU           int            `yaml:"cpu,omitempty" json:"cpu,omitempty"`
-	VolumeMounts  []VolumeMount  `yaml:"volumeMounts,omitempty" json:"volumeMounts,omitempty"`
-	LivenessProbe *LivenessProbe `yaml:"livenessProbe,omitempty" json:"livenessProbe,omitempty"`
-	Lifecycle     *Lifecycle     `yaml:"lifecycle,omitempty" json:"lifecycle,omitempty"`
+	CPU           int            `yaml:"cpu,omitempty" json:"cpu,omitempty" protobuf:"8"`
+	VolumeMounts  []VolumeMount  `yaml:"volumeMounts,omitempty" json:"volumeMounts,omitempty" protobuf:"9"`
+	LivenessProbe *LivenessProbe `yaml:"livenessProbe,omitempty" json:"livenessProbe,omitempty" protobuf:"10"`
+	Lifecycle     *Lifecycle     `yaml:"lifecycle,omitempty" json:"lifecycle,omitempty" protobuf:"11"`
 	// Optional: Default to false.
-	Privileged bool `json:"privileged,omitempty" yaml:"privileged,omitempty"`
+	Privileged bool `json:"privileged,omitempty" yaml:"privileged,omitempty" protobuf:"12"`
 }
 
 // Handler defines a specific action that should be taken
@@ -196,9 +196,9 @@ type Container struct {
 type Handler struct {
 	// One and only one of the following should be specified.
 	// Exec specifies the action to take.
-	Exec *ExecAction `yaml:"exec,omitempty" json:"exec,omitempty"`
+	Exec *ExecAction `yaml:"exec,omitempty" json:"exec,omitempty" protobuf:"1"`
 	// HTTPGet specifies the http request to perform.
-	HTTPGet *HTTPGetAction `yaml:"httpGet,omitempty" json:"httpGet,omitempty"`
+	HTTPGet *HTTPGetAction `yaml:"httpGet,omitempty" json:"httpGet,omitempty" protobuf:"2"`
 }
 
 // Lifecycle describes actions that the management system should take in response to container lifecycle
@@ -207,30 +207,33 @@ type Handler struct {
 type Lifecycle struct {
 	// PostStart is called immediately after a container is created.  If the handler fails, the container
 	// is terminated and restarted.
-	PostStart *Handler `yaml:"postStart,omitempty" json:"postStart,omitempty"`
+	PostStart *Handler `yaml:"postStart,omitempty" json:"postStart,omitempty" protobuf:"1"`
 	// PreStop is called immediately before a container is terminated.  The reason for termination is
 	// passed to the handler.  Regardless of the outcome of the handler, the container is eventually terminated.
-	PreStop *Handler `yaml:"preStop,omitempty" json:"preStop,omitempty"`
+	PreStop *Handler `yaml:"preStop,omitempty" json:"preStop,omitempty" protobuf:"2"`
 }
 
 // Event is the representation of an event logged to etcd backend.
 type Event struct {
-	Event     string             `json:"event,omitempty"`
-	Manifest  *ContainerManifest `json:"manifest,omitempty"`
-	Container *Container         `json:"container,omitempty"`
-	Timestamp int64              `json:"timestamp"`
+	Event     string             `json:"event,omitempty" protobuf:"1"`
+	Manifest  *ContainerManifest `json:"manifest,omitempty" protobuf:"2"`
+	Container *Container         `json:"container,omitempty" protobuf:"3"`
+	Timestamp int64              `json:"timestamp" protobuf:"4"`
 }
 
 // The below types are used by kube_client and api_server
 
 // JSONBase shared by all objects send to, or returnd form the client.
 type JSONBase struct {
-	Kind              string    `json:"kind,omitempty" yaml:"kind,omitempty"`
-	ID                string    `json:"id,omitempty" yaml:"id,omitempty"`
-	CreationTimestamp util.Time `json:"creationTimestamp,omitempty" yaml:"creationTimestamp,omitempty"`
-	SelfLink          string    `json:"selfLink,omitempty" yaml:"selfLink,omitempty"`
-	ResourceVersion   uint64    `json:"resourceVersion,omitempty" yaml:"resourceVersion,omitempty"`
-	APIVersion        string    `json:"apiVersion,omitempty" yaml:"apiVersion,omitempty"`
+	Kind              string    `json:"kind,omitempty" yaml:"kind,omitempty" protobuf:"1"`
+	ID                string    `json:"id,omitempty" yaml:"id,omitempty" protobuf:"2"`
+	CreationTimestamp util.Time `json:"creationTimestamp,omitempty" yaml:"creationTimestamp,omitempty" protobuf:"3"`
+	SelfLink          string    `json:"selfLink,omitempty" yaml:"selfLink,omitempty" protobuf:"4"`
+	ResourceVersion   uint64    `json:"resourceVersion,omitempty" yaml:"resourceVersion,omitempty" protobuf:"5"`
+	APIVersion        string    `json:"apiVersion,omitempty" yaml:"apiVersion,omitempty" protobuf:"6"`
+	// DeletionTimestamp is set by the storage when a graceful deletion is requested,
+	// to the time after which the object will be removed.
+	DeletionTimestamp *util.Time `json:"deletionTimestamp,omitempty" yaml:"deletionTimestamp,omitempty" protobuf:"7"`
 }
 
 func (*JSONBase) IsAnAPIObject() {}
@@ -250,37 +253,37 @@ const (
 
 type ContainerStateWaiting struct {
 	// Reason could be pulling image,
-	Reason string `json:"reason,omitempty" yaml:"reason,omitempty"`
+	Reason string `json:"reason,omitempty" yaml:"reason,omitempty" protobuf:"1"`
 }
 
 type ContainerStateRunning struct {
 }
 
 type ContainerStateTerminated struct {
-	ExitCode int    `json:"exitCode,omitempty" yaml:"exitCode,omitempty"`
-	Signal   int    `json:"signal,omitempty" yaml:"signal,omitempty"`
-	Reason   string `json:"reason,omitempty" yaml:"reason,omitempty"`
+	ExitCode int    `json:"exitCode,omitempty" yaml:"exitCode,omitempty" protobuf:"1"`
+	Signal   int    `json:"signal,omitempty" yaml:"signal,omitempty" protobuf:"2"`
+	Reason   string `json:"reason,omitempty" yaml:"reason,omitempty" protobuf:"3"`
 }
 
 type ContainerState struct {
 	// Only one of the following ContainerState may be specified.
 	// If none of them is specified, the default one is ContainerStateWaiting.
-	Waiting     *ContainerStateWaiting    `json:"waiting,omitempty" yaml:"waiting,omitempty"`
-	Running     *ContainerStateRunning    `json:"running,omitempty" yaml:"running,omitempty"`
-	Termination *ContainerStateTerminated `json:"termination,omitempty" yaml:"termination,omitempty"`
+	Waiting     *ContainerStateWaiting    `json:"waiting,omitempty" yaml:"waiting,omitempty" protobuf:"1"`
+	Running     *ContainerStateRunning    `json:"running,omitempty" yaml:"running,omitempty" protobuf:"2"`
+	Termination *ContainerStateTerminated `json:"termination,omitempty" yaml:"termination,omitempty" protobuf:"3"`
 }
 
 type ContainerStatus struct {
 	// TODO(dchen1107): Should we rename PodStatus to a more generic name or have a separate states
 	// defined for container?
-	State        ContainerState `json:"state,omitempty" yaml:"state,omitempty"`
-	RestartCount int            `json:"restartCount" yaml:"restartCount"`
+	State        ContainerState `json:"state,omitempty" yaml:"state,omitempty" protobuf:"1"`
+	RestartCount int            `json:"restartCount" yaml:"restartCount" protobuf:"2"`
 	// TODO(dchen1107): Introduce our own NetworkSettings struct here?
 	// TODO(dchen1107): Once we have done with integration with cadvisor, resource
 	// usage should be included.
 	// TODO(dchen1107):  In long run, I think we should replace this with our own struct to remove
 	// the dependency on docker.
-	DetailInfo docker.Container `json:"detailInfo,omitempty" yaml:"detailInfo,omitempty"`
+	DetailInfo docker.Container `json:"detailInfo,omitempty" yaml:"detailInfo,omitempty" protobuf:"3"`
 }
 
 // PodInfo contains one entry for every container with available info.
@@ -298,18 +301,18 @@ type RestartPolicy struct {
 	// Only one of the following restart policy may be specified.
 	// If none of the following policies is specified, the default one
 	// is RestartPolicyAlways.
-	Always    *RestartPolicyAlways    `json:"always,omitempty" yaml:"always,omitempty"`
-	OnFailure *RestartPolicyOnFailure `json:"onFailure,omitempty" yaml:"onFailure,omitempty"`
-	Never     *RestartPolicyNever     `json:"never,omitempty" yaml:"never,omitempty"`
+	Always    *RestartPolicyAlways    `json:"always,omitempty" yaml:"always,omitempty" protobuf:"1"`
+	OnFailure *RestartPolicyOnFailure `json:"onFailure,omitempty" yaml:"onFailure,omitempty" protobuf:"2"`
+	Never     *RestartPolicyNever     `json:"never,omitempty" yaml:"never,omitempty" protobuf:"3"`
 }
 
 // PodState is the state of a pod, used as either input (desired state) or output (current state).
 type PodState struct {
-	Manifest ContainerManifest `json:"manifest,omitempty" yaml:"manifest,omitempty"`
-	Status   PodStatus         `json:"status,omitempty" yaml:"status,omitempty"`
-	Host     string            `json:"host,omitempty" yaml:"host,omitempty"`
-	HostIP   string            `json:"hostIP,omitempty" yaml:"hostIP,omitempty"`
-	PodIP    string            `json:"podIP,omitempty" yaml:"podIP,omitempty"`
+	Manifest ContainerManifest `json:"manifest,omitempty" yaml:"manifest,omitempty" protobuf:"1"`
+	Status   PodStatus         `json:"status,omitempty" yaml:"status,omitempty" protobuf:"2"`
+	Host     string            `json:"host,omitempty" yaml:"host,omitempty" protobuf:"3"`
+	HostIP   string            `json:"hostIP,omitempty" yaml:"hostIP,omitempty" protobuf:"4"`
+	PodIP    string            `json:"podIP,omitempty" yaml:"podIP,omitempty" protobuf:"5"`
 
 	// The key of this map is the *name* of the container within the manifest; it has one
 	// entry per container in the manifest. The value of this map is currently the output
@@ -317,60 +320,60 @@ type PodState struct {
 	// upon. To allow marshalling/unmarshalling, we copied the client's structs and added
 	// json/yaml tags.
 	// TODO: Make real decisions about what our info should look like.
-	Info PodInfo `json:"info,omitempty" yaml:"info,omitempty"`
+	Info PodInfo `json:"info,omitempty" yaml:"info,omitempty" protobuf:"6"`
 }
 
 // PodList is a list of Pods.
 type PodList struct {
-	JSONBase `json:",inline" yaml:",inline"`
-	Items    []Pod `json:"items" yaml:"items,omitempty"`
+	JSONBase `json:",inline" yaml:",inline" protobuf:"1"`
+	Items    []Pod `json:"items" yaml:"items,omitempty" protobuf:"2"`
 }
 
 func (*PodList) IsAnAPIObject() {}
 
 // Pod is a collection of containers, used as either input (create, update) or as output (list, get).
 type Pod struct {
-	JSONBase     `json:",inline" yaml:",inline"`
-	Labels       map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
-	DesiredState PodState          `json:"desiredState,omitempty" yaml:"desiredState,omitempty"`
-	CurrentState PodState          `json:"currentState,omitempty" yaml:"currentState,omitempty"`
+	JSONBase     `json:",inline" yaml:",inline" protobuf:"1"`
+	Labels       map[string]string `json:"labels,omitempty" yaml:"labels,omitempty" protobuf:"2"`
+	DesiredState PodState          `json:"desiredState,omitempty" yaml:"desiredState,omitempty" protobuf:"3"`
+	CurrentState PodState          `json:"currentState,omitempty" yaml:"currentState,omitempty" protobuf:"4"`
 }
 
 // ReplicationControllerState is the state of a replication controller, either input (create, update) or as output (list, get).
 type ReplicationControllerState struct {
-	Replicas        int               `json:"replicas" yaml:"replicas"`
-	ReplicaSelector map[string]string `json:"replicaSelector,omitempty" yaml:"replicaSelector,omitempty"`
-	PodTemplate     PodTemplate       `json:"podTemplate,omitempty" yaml:"podTemplate,omitempty"`
+	Replicas        int               `json:"replicas" yaml:"replicas" protobuf:"1"`
+	ReplicaSelector map[string]string `json:"replicaSelector,omitempty" yaml:"replicaSelector,omitempty" protobuf:"2"`
+	PodTemplate     PodTemplate       `json:"podTemplate,omitempty" yaml:"podTemplate,omitempty" protobuf:"3"`
 }
 
 // ReplicationControllerList is a collection of replication controllers.
 type ReplicationControllerList struct {
-	JSONBase `json:",inline" yaml:",inline"`
-	Items    []ReplicationController `json:"items,omitempty" yaml:"items,omitempty"`
+	JSONBase `json:",inline" yaml:",inline" protobuf:"1"`
+	Items    []ReplicationController `json:"items,omitempty" yaml:"items,omitempty" protobuf:"2"`
 }
 
 func (*ReplicationControllerList) IsAnAPIObject() {}
 
 // ReplicationController represents the configuration of a replication controller.
 type ReplicationController struct {
-	JSONBase     `json:",inline" yaml:",inline"`
-	DesiredState ReplicationControllerState `json:"desiredState,omitempty" yaml:"desiredState,omitempty"`
-	CurrentState ReplicationControllerState `json:"currentState,omitempty" yaml:"currentState,omitempty"`
-	Labels       map[string]string          `json:"labels,omitempty" yaml:"labels,omitempty"`
+	JSONBase     `json:",inline" yaml:",inline" protobuf:"1"`
+	DesiredState ReplicationControllerState `json:"desiredState,omitempty" yaml:"desiredState,omitempty" protobuf:"2"`
+	CurrentState ReplicationControllerState `json:"currentState,omitempty" yaml:"currentState,omitempty" protobuf:"3"`
+	Labels       map[string]string          `json:"labels,omitempty" yaml:"labels,omitempty" protobuf:"4"`
 }
 
 func (*ReplicationController) IsAnAPIObject() {}
 
 // PodTemplate holds the information used for creating pods.
 type PodTemplate struct {
-	DesiredState PodState          `json:"desiredState,omitempty" yaml:"desiredState,omitempty"`
-	Labels       map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	DesiredState PodState          `json:"desiredState,omitempty" yaml:"desiredState,omitempty" protobuf:"1"`
+	Labels       map[string]string `json:"labels,omitempty" yaml:"labels,omitempty" protobuf:"2"`
 }
 
 // ServiceList holds a list of services.
 type ServiceList struct {
-	JSONBase `json:",inline" yaml:",inline"`
-	Items    []Service `json:"items" yaml:"items"`
+	JSONBase `json:",inline" yaml:",inline" protobuf:"1"`
+	Items    []Service `json:"items" yaml:"items" protobuf:"2"`
 }
 
 func (*ServiceList) IsAnAPIObject() {}
@@ -379,23 +382,23 @@ func (*ServiceList) IsAnAPIObject() {}
 // (for example 3306) that the proxy listens on, and the selector that determines which pods
 // will answer requests sent through the proxy.
 type Service struct {
-	JSONBase `json:",inline" yaml:",inline"`
+	JSONBase `json:",inline" yaml:",inline" protobuf:"1"`
 
 	// Required.
-	Port int `json:"port" yaml:"port"`
+	Port int `json:"port" yaml:"port" protobuf:"2"`
 	// Optional: Supports "TCP" and "UDP".  Defaults to "TCP".
-	Protocol string `yaml:"protocol,omitempty" json:"protocol,omitempty"`
+	Protocol string `yaml:"protocol,omitempty" json:"protocol,omitempty" protobuf:"3"`
 
 	// This service's labels.
-	Labels map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	Labels map[string]string `json:"labels,omitempty" yaml:"labels,omitempty" protobuf:"4"`
 
 	// This service will route traffic to pods having labels matching this selector.
-	Selector                   map[string]string `json:"selector,omitempty" yaml:"selector,omitempty"`
-	CreateExternalLoadBalancer bool              `json:"createExternalLoadBalancer,omitempty" yaml:"createExternalLoadBalancer,omitempty"`
+	Selector                   map[string]string `json:"selector,omitempty" yaml:"selector,omitempty" protobuf:"5"`
+	CreateExternalLoadBalancer bool              `json:"createExternalLoadBalancer,omitempty" yaml:"createExternalLoadBalancer,omitempty" protobuf:"6"`
 
 	// ContainerPort is the name of the port on the container to direct traffic to.
 	// Optional, if unspecified use the first port on the container.
-	ContainerPort util.IntOrString `json:"containerPort,omitempty" yaml:"containerPort,omitempty"`
+	ContainerPort util.IntOrString `json:"containerPort,omitempty" yaml:"containerPort,omitempty" protobuf:"7"`
 }
 
 func (*Service) IsAnAPIObject() {}
@@ -403,16 +406,16 @@ func (*Service) IsAnAPIObject() {}
 // Endpoints is a collection of endpoints that implement the actual service, for example:
 // Name: "mysql", Endpoints: ["10.10.1.1:1909", "10.10.2.2:8834"]
 type Endpoints struct {
-	JSONBase  `json:",inline" yaml:",inline"`
-	Endpoints []string `json:"endpoints,omitempty" yaml:"endpoints,omitempty"`
+	JSONBase  `json:",inline" yaml:",inline" protobuf:"1"`
+	Endpoints []string `json:"endpoints,omitempty" yaml:"endpoints,omitempty" protobuf:"2"`
 }
 
 func (*Endpoints) IsAnAPIObject() {}
 
 // EndpointsList is a list of endpoints.
 type EndpointsList struct {
-	JSONBase `json:",inline" yaml:",inline"`
-	Items    []Endpoints `json:"items,omitempty" yaml:"items,omitempty"`
+	JSONBase `json:",inline" yaml:",inline" protobuf:"1"`
+	Items    []Endpoints `json:"items,omitempty" yaml:"items,omitempty" protobuf:"2"`
 }
 
 func (*EndpointsList) IsAnAPIObject() {}
@@ -420,29 +423,29 @@ func (*EndpointsList) IsAnAPIObject() {}
 // Minion is a worker node in Kubernetenes.
 // The name of the minion according to etcd is in JSONBase.ID.
 type Minion struct {
-	JSONBase `json:",inline" yaml:",inline"`
+	JSONBase `json:",inline" yaml:",inline" protobuf:"1"`
 	// Queried from cloud provider, if available.
-	HostIP string `json:"hostIP,omitempty" yaml:"hostIP,omitempty"`
+	HostIP string `json:"hostIP,omitempty" yaml:"hostIP,omitempty" protobuf:"2"`
 }
 
 func (*Minion) IsAnAPIObject() {}
 
 // MinionList is a list of minions.
 type MinionList struct {
-	JSONBase `json:",inline" yaml:",inline"`
+	JSONBase `json:",inline" yaml:",inline" protobuf:"1"`
 	// DEPRECATED: the below Minions is due to a naming mistake and
 	// will be replaced with Items in the future.
-	Minions []Minion `json:"minions,omitempty" yaml:"minions,omitempty"`
-	Items   []Minion `json:"items,omitempty" yaml:"items,omitempty"`
+	Minions []Minion `json:"minions,omitempty" yaml:"minions,omitempty" protobuf:"2"`
+	Items   []Minion `json:"items,omitempty" yaml:"items,omitempty" protobuf:"3"`
 }
 
 func (*MinionList) IsAnAPIObject() {}
 
 // Binding is written by a scheduler to cause a pod to be bound to a host.
 type Binding struct {
-	JSONBase `json:",inline" yaml:",inline"`
-	PodID    string `json:"podID" yaml:"podID"`
-	Host     string `json:"host" yaml:"host"`
+	JSONBase `json:",inline" yaml:",inline" protobuf:"1"`
+	PodID    string `json:"podID" yaml:"podID" protobuf:"2"`
+	Host     string `json:"host" yaml:"host" protobuf:"3"`
 }
 
 func (*Binding) IsAnAPIObject() {}
@@ -451,23 +454,23 @@ func (*Binding) IsAnAPIObject() {}
 // TODO: this could go in apiserver, but I'm including it here so clients needn't
 // import both.
 type Status struct {
-	JSONBase `json:",inline" yaml:",inline"`
+	JSONBase `json:",inline" yaml:",inline" protobuf:"1"`
 	// One of: "success", "failure", "working" (for operations not yet completed)
-	Status string `json:"status,omitempty" yaml:"status,omitempty"`
+	Status string `json:"status,omitempty" yaml:"status,omitempty" protobuf:"2"`
 	// A human-readable description of the status of this operation.
-	Message string `json:"message,omitempty" yaml:"message,omitempty"`
+	Message string `json:"message,omitempty" yaml:"message,omitempty" protobuf:"3"`
 	// A machine-readable description of why this operation is in the
 	// "failure" or "working" status. If this value is empty there
 	// is no information available. A Reason clarifies an HTTP status
 	// code but does not override it.
-	Reason StatusReason `json:"reason,omitempty" yaml:"reason,omitempty"`
+	Reason StatusReason `json:"reason,omitempty" yaml:"reason,omitempty" protobuf:"4"`
 	// Extended data associated with the reason.  Each reason may define its
 	// own extended details. This field is optional and the data returned
 	// is not guaranteed to conform to any schema except that defined by
 	// the reason type.
-	Details *StatusDetails `json:"details,omitempty" yaml:"details,omitempty"`
+	Details *StatusDetails `json:"details,omitempty" yaml:"details,omitempty" protobuf:"5"`
 	// Suggested HTTP return code for this status, 0 if not set.
-	Code int `json:"code,omitempty" yaml:"code,omitempty"`
+	Code int `json:"code,omitempty" yaml:"code,omitempty" protobuf:"6"`
 }
 
 func (*Status) IsAnAPIObject() {}
@@ -481,13 +484,13 @@ func (*Status) IsAnAPIObject() {}
 type StatusDetails struct {
 	// The ID attribute of the resource associated with the status StatusReason
 	// (when there is a single ID which can be described).
-	ID string `json:"id,omitempty" yaml:"id,omitempty"`
+	ID string `json:"id,omitempty" yaml:"id,omitempty" protobuf:"1"`
 	// The kind attribute of the resource associated with the status StatusReason.
 	// On some operations may differ from the requested resource Kind.
-	Kind string `json:"kind,omitempty" yaml:"kind,omitempty"`
+	Kind string `json:"kind,omitempty" yaml:"kind,omitempty" protobuf:"2"`
 	// The Causes array includes more details associated with the StatusReason
 	// failure. Not all StatusReasons may provide detailed causes.
-	Causes []StatusCause `json:"causes,omitempty" yaml:"causes,omitempty"`
+	Causes []StatusCause `json:"causes,omitempty" yaml:"causes,omitempty" protobuf:"3"`
 }
 
 // Values of Status.Status
@@ -544,6 +547,12 @@ const (
 	// conflict.
 	// Status code 409
 	StatusReasonConflict StatusReason = "conflict"
+
+	// StatusReasonExpired means the resource version a watch was asked to start
+	// from is too old: the changes since are no longer available. The client
+	// should list the resources again, and watch from the version listed.
+	// Status code 410
+	StatusReasonExpired StatusReason = "expired"
 )
 
 // StatusCause provides more information about an api.Status failure, including
@@ -551,10 +560,10 @@ const (
 type StatusCause struct {
 	// A machine-readable description of the cause of the error. If this value is
 	// empty there is no information available.
-	Type CauseType `json:"reason,omitempty" yaml:"reason,omitempty"`
+	Type CauseType `json:"reason,omitempty" yaml:"reason,omitempty" protobuf:"1"`
 	// A human-readable description of the cause of the error.  This field may be
 	// presented as-is to a reader.
-	Message string `json:"message,omitempty" yaml:"message,omitempty"`
+	Message string `json:"message,omitempty" yaml:"message,omitempty" protobuf:"2"`
 	// The field of the resource that has caused this error, as named by its JSON
 	// serialization. May include dot and postfix notation for nested attributes.
 	// Arrays are zero-indexed.  Fields may appear more than once in an array of
@@ -564,7 +573,7 @@ type StatusCause struct {
 	// Examples:
 	//   "name" - the field "name" on the current resource
 	//   "items[0].name" - the field "name" on the first array entry in "items"
-	Field string `json:"field,omitempty" yaml:"field,omitempty"`
+	Field string `json:"field,omitempty" yaml:"field,omitempty" protobuf:"3"`
 }
 
 // CauseType is a machine readable value providing more detail about what
@@ -592,15 +601,84 @@ const (
 
 // ServerOp is an operation delivered to API clients.
 type ServerOp struct {
-	JSONBase `yaml:",inline" json:",inline"`
+	JSONBase `yaml:",inline" json:",inline" protobuf:"1"`
 }
 
 func (*ServerOp) IsAnAPIObject() {}
 
 // ServerOpList is a list of operations, as delivered to API clients.
 type ServerOpList struct {
-	JSONBase `yaml:",inline" json:",inline"`
-	Items    []ServerOp `yaml:"items,omitempty" json:"items,omitempty"`
+	JSONBase `yaml:",inline" json:",inline" protobuf:"1"`
+	Items    []ServerOp `yaml:"items,omitempty" json:"items,omitempty" protobuf:"2"`
 }
 
 func (*ServerOpList) IsAnAPIObject() {}
+
+// ComponentStatus is the health of a single cluster component, as reported by
+// the /validate endpoint. The component name is in JSONBase.ID.
+type ComponentStatus struct {
+	JSONBase `yaml:",inline" json:",inline" protobuf:"1"`
+	// One of: "healthy", "unhealthy", "unknown" (the check did not complete in time)
+	Health string `yaml:"health,omitempty" json:"health,omitempty" protobuf:"2"`
+	// A human-readable description of why the component isn't healthy.
+	Error string `yaml:"error,omitempty" json:"error,omitempty" protobuf:"3"`
+}
+
+func (*ComponentStatus) IsAnAPIObject() {}
+
+// ComponentStatusList is a list of component statuses.
+type ComponentStatusList struct {
+	JSONBase `yaml:",inline" json:",inline" protobuf:"1"`
+	Items    []ComponentStatus `yaml:"items,omitempty" json:"items,omitempty" protobuf:"2"`
+}
+
+func (*ComponentStatusList) IsAnAPIObject() {}
+
+// Diff lists the fields in which a candidate object differs from the stored
+// version of it, as returned by POST /<resource>/<id>/diff. JSONBase.ID is the
+// object's ID.
+type Diff struct {
+	JSONBase `yaml:",inline" json:",inline" protobuf:"1"`
+	Changes  []FieldChange `yaml:"changes,omitempty" json:"changes,omitempty" protobuf:"2"`
+}
+
+func (*Diff) IsAnAPIObject() {}
+
+// FieldChange is a difference in a single field.
+type FieldChange struct {
+	// The path of the field, e.g. "desiredState.manifest.containers[0].image".
+	Path string `yaml:"path" json:"path" protobuf:"1"`
+	// The JSON encoding of the stored value; empty if the candidate adds the field.
+	Old string `yaml:"old,omitempty" json:"old,omitempty" protobuf:"2"`
+	// The JSON encoding of the candidate value; empty if the candidate removes the field.
+	New string `yaml:"new,omitempty" json:"new,omitempty" protobuf:"3"`
+}
+
+// DeleteOptions may be sent as the body of a DELETE request.
+type DeleteOptions struct {
+	JSONBase `yaml:",inline" json:",inline" protobuf:"1"`
+	// Preconditions must hold for the object to be deleted.
+	Preconditions *Preconditions `yaml:"preconditions,omitempty" json:"preconditions,omitempty" protobuf:"2"`
+	// GracePeriodSeconds is how long the object is kept, with its DeletionTimestamp
+	// set, before it is removed. Zero removes it at once; if unset, the storage
+	// chooses. Storage which doesn't support graceful deletion ignores it.
+	GracePeriodSeconds *int64 `yaml:"gracePeriodSeconds,omitempty" json:"gracePeriodSeconds,omitempty" protobuf:"3"`
+}
+
+func (*DeleteOptions) IsAnAPIObject() {}
+
+// Preconditions are conditions on the stored object for an operation to be carried out.
+type Preconditions struct {
+	// ResourceVersion, if not zero, is the version the object must be at. It is
+	// equivalent to an If-Match header.
+	ResourceVersion uint64 `yaml:"resourceVersion,omitempty" json:"resourceVersion,omitempty" protobuf:"1"`
+}
+
+// StatusList is the result of a batch request: one Status per item, in the order
+// the items were sent.
+type StatusList struct {
+	JSONBase `yaml:",inline" json:",inline" protobuf:"1"`
+	Items    []Status `yaml:"items,omitempty" json:"items,omitempty" protobuf:"2"`
+}
+
+func (*StatusList) IsAnAPIObject() {}
@@ -5,9 +5,11 @@ import (
 	"io/ioutil"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/golang/glog"
+	"github.com/ryutah/kubernetes-transcribe/pkg/audit"
 	"github.com/ryutah/kubernetes-transcribe/pkg/healthz"
 	"github.com/ryutah/kubernetes-transcribe/pkg/runtime"
 	"github.com/ryutah/kubernetes-transcribe/pkg/version"
@@ -49,6 +51,13 @@ func Handle(storage map[string]RESTStorage, codec runtime.Codec, prefix string)
 // TODO: consider migrating this to go-restful which is a more full-featured version of the same thing.
 type APIGroup struct {
 	handler RESTHandler
+
+	// proxyConfig bounds the requests made by the group's proxy.
+	proxyConfig ProxyConfig
+
+	// closing is closed by StopWatches to end every watch served by this group.
+	closing   chan struct{}
+	closeOnce sync.Once
 }
 
 // NewAPIGroup returns an object that will serve a set of REST resources and their
@@ -58,13 +67,14 @@ type APIGroup struct {
 // TODO: add multitype codec serialization.
 func NewAPIGroup(storage map[string]RESTStorage, codec runtime.Codec) *APIGroup {
 	return &APIGroup{
-		RESTHandler{
+		handler: RESTHandler{
 			storage: storage,
 			codec:   codec,
 			ops:     NewOperations(),
 			// Delay just long enough to handle most simple write operations
 			asyncOpWait: time.Millisecond * 25,
 		},
+		closing: make(chan struct{}),
 	}
 }
 
@@ -73,33 +83,96 @@ func NewAPIGroup(storage map[string]RESTStorage, codec runtime.Codec) *APIGroup
 // in a slask.
 func (g *APIGroup) InstallREST(mux mux, paths ...string) {
 	restHandler := &g.handler
-	watchHandler := &WatchHandler{g.handler.storage, g.handler.codec}
+	watchHandler := &WatchHandler{g.handler.storage, g.handler.codec, g.closing}
 	redirectHandler := &RedirectHandler{g.handler.storage, g.handler.codec}
-	opHandler := &OperationHandler{g.handler.ops, g.handler.codec}
+	opHandler := &OperationHandler{g.handler.ops, g.handler.codec, g.handler.protobufCodec}
+	batchHandler := &BatchHandler{&g.handler}
 
-	servers := map[string]string{
-		"controller-manager": "127.0.0.1:10252",
-		"scheduler":          "127.0.0.1:10251",
-		// TODO: Add minion health checks here too.
-	}
-	validator, err := NewValidator(servers)
-	if err != nil {
-		glog.Errorf("failed to set up validator: %v", err)
-		validator = nil
-	}
 	for _, prefix := range paths {
 		prefix = strings.TrimRight(prefix, "/")
-		proxyHandler := &ProxyHandler{prefix + "/proxy/", g.handler.storage, g.handler.codec}
+		proxyHandler := newProxyHandler(prefix+"/proxy/", g.handler.storage, g.handler.codec, g.proxyConfig)
 		mux.Handle(prefix+"/", http.StripPrefix(prefix, restHandler))
 		mux.Handle(prefix+"/watch/", http.StripPrefix(prefix+"/watch/", watchHandler))
 		mux.Handle(prefix+"/proxy/", http.StripPrefix(prefix+"/proxy/", proxyHandler))
 		mux.Handle(prefix+"/redirect/", http.StripPrefix(prefix+"/redirect/", redirectHandler))
 		mux.Handle(prefix+"/operations", http.StripPrefix(prefix+"/operations", opHandler))
 		mux.Handle(prefix+"/operations/", http.StripPrefix(prefix+"/operations/", opHandler))
-		if validator != nil {
-			mux.Handle(prefix+"/validate", validator)
-		}
+		mux.Handle(prefix+"/batch", batchHandler)
+	}
+}
+
+// InstallValidator registers a handler at <path>/validate for each of paths, which
+// reports the health of the components in config, and of the kubelet on every
+// minion in the group's "minions" storage, as an api.ComponentStatusList.
+func (g *APIGroup) InstallValidator(mux mux, config ValidatorConfig, paths ...string) error {
+	var minions func() ([]string, error)
+	if storage, ok := g.handler.storage["minions"]; ok {
+		minions = func() ([]string, error) { return listMinions(storage) }
 	}
+	validator, err := NewValidator(config, g.handler.codec, minions)
+	if err != nil {
+		return err
+	}
+	for _, prefix := range paths {
+		mux.Handle(strings.TrimRight(prefix, "/")+"/validate", validator)
+	}
+	return nil
+}
+
+// SetProtobufCodec lets clients of the group read and write objects with codec, by
+// asking for application/vnd.kubernetes.protobuf. It must be called before
+// InstallREST.
+func (g *APIGroup) SetProtobufCodec(codec runtime.Codec) {
+	g.handler.protobufCodec = codec
+}
+
+// SetPodLogStreamer lets clients of the group read the logs of pods with
+// GET /pods/<id>/log, through streamer.
+func (g *APIGroup) SetPodLogStreamer(streamer *PodLogStreamer) {
+	g.handler.logs = streamer
+}
+
+// SetPodExecProxy lets clients of the group run commands in, and attach to, the
+// containers of pods with GET /pods/<id>/exec and /pods/<id>/attach, through proxy.
+func (g *APIGroup) SetPodExecProxy(proxy *PodExecProxy) {
+	g.handler.exec = proxy
+}
+
+// SetPodPortForwarder lets clients of the group connect to the ports of pods with
+// GET /pods/<id>/portforward, through forwarder.
+func (g *APIGroup) SetPodPortForwarder(forwarder *PodPortForwarder) {
+	g.handler.portForward = forwarder
+}
+
+// SetProxyConfig bounds the requests made by the proxy of the group, at
+// <prefix>/proxy/. It must be called before InstallREST.
+func (g *APIGroup) SetProxyConfig(config ProxyConfig) {
+	g.proxyConfig = config
+}
+
+// SetAuditor makes the group record every mutating request it serves with auditor.
+// Passing nil turns auditing off.
+func (g *APIGroup) SetAuditor(auditor *audit.Auditor) {
+	g.handler.auditor = auditor
+}
+
+// StopWatches ends every watch stream served by the group and refuses new ones.
+// It is meant to be called when the server is shutting down, since open watches
+// would otherwise keep their connections busy indefinitely.
+func (g *APIGroup) StopWatches() {
+	g.closeOnce.Do(func() { close(g.closing) })
+}
+
+// WaitForOperations waits, at most for timeout, until the operations started by
+// the group have finished. Returns false if some were still running.
+func (g *APIGroup) WaitForOperations(timeout time.Duration) bool {
+	return g.handler.ops.WaitForPending(timeout)
+}
+
+// PendingOperations returns the number of operations started by the group that
+// have not finished yet.
+func (g *APIGroup) PendingOperations() int {
+	return g.handler.ops.Pending()
 }
 
 // InstallSupport registers the APIServer support functions into a mux.
@@ -116,14 +189,18 @@ func handleVersion(w http.ResponseWriter, r *http.Request) {
 	writeRawJSON(http.StatusOK, version.Get(), w)
 }
 
-// writeJSON renders an object as JSON to the response.
+// writeJSON renders an object as JSON to the response, or as YAML if codec is
+// a YAML codec. Successful responses carry the object's resource version as ETag.
 func writeJSON(statusCode int, codec runtime.Codec, object runtime.Object, w http.ResponseWriter) {
 	output, err := codec.Encode(object)
 	if err != nil {
 		errorJSON(err, codec, w)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Type", contentTypeOf(codec))
+	if etag := etagFor(object); etag != "" && statusCode < http.StatusMultipleChoices {
+		w.Header().Set("ETag", etag)
+	}
 	w.WriteHeader(statusCode)
 	w.Write(output)
 }
@@ -157,9 +234,14 @@ func parseTimeout(str string) time.Duration {
 	return 30 * time.Second
 }
 
+// readBody reads the body of req. YAML bodies are converted to JSON.
 func readBody(req *http.Request) ([]byte, error) {
 	defer req.Body.Close()
-	return ioutil.ReadAll(req.Body)
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil || !isYAMLRequest(req) {
+		return body, err
+	}
+	return yamlToJSON(body)
 }
 
 // splitPath returns the segments for a URL path.
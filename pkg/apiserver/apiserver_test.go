@@ -0,0 +1,82 @@
+package apiserver
+
+import (
+	"fmt"
+
+	"github.com/ryutah/kubernetes-transcribe/pkg/api"
+	"github.com/ryutah/kubernetes-transcribe/pkg/api/errors"
+	"github.com/ryutah/kubernetes-transcribe/pkg/labels"
+	"github.com/ryutah/kubernetes-transcribe/pkg/runtime"
+	"github.com/ryutah/kubernetes-transcribe/pkg/watch"
+)
+
+// The versioned API types are not registered yet, so tests use the internal version.
+var codec = runtime.CodecFor(api.Scheme, "")
+
+// SimpleRESTStorage serves a single pod, and redirects to location.
+type SimpleRESTStorage struct {
+	pod      *api.Pod
+	location string
+	err      error
+}
+
+func (s *SimpleRESTStorage) New() runtime.Object {
+	return &api.Pod{}
+}
+
+func (s *SimpleRESTStorage) List(label, field labels.Selector) (runtime.Object, error) {
+	list := &api.PodList{}
+	if s.pod != nil {
+		list.Items = append(list.Items, *s.pod)
+	}
+	return list, s.err
+}
+
+func (s *SimpleRESTStorage) Get(id string) (runtime.Object, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	if s.pod == nil || s.pod.ID != id {
+		return nil, errors.NewNotFound("pod", id)
+	}
+	return s.pod, nil
+}
+
+func (s *SimpleRESTStorage) Delete(id string) (<-chan runtime.Object, error) {
+	return MakeAsync(func() (runtime.Object, error) {
+		return &api.Status{Status: api.StatusSuccess}, s.err
+	}), nil
+}
+
+func (s *SimpleRESTStorage) Create(obj runtime.Object) (<-chan runtime.Object, error) {
+	return MakeAsync(func() (runtime.Object, error) { return obj, s.err }), nil
+}
+
+// Update fails with a conflict, as etcd would, if obj has a resource version
+// other than that of the stored pod.
+func (s *SimpleRESTStorage) Update(obj runtime.Object) (<-chan runtime.Object, error) {
+	return MakeAsync(func() (runtime.Object, error) {
+		jsonBase, err := runtime.FindJSONBase(obj)
+		if err != nil {
+			return nil, err
+		}
+		if version := jsonBase.ResourceVersion(); s.pod != nil && version != 0 && version != s.pod.ResourceVersion {
+			return nil, errors.NewConflict("pod", jsonBase.ID(), fmt.Errorf("resource version %d is stale", version))
+		}
+		return obj, s.err
+	}), nil
+}
+
+func (s *SimpleRESTStorage) ResourceLocation(id string) (string, error) {
+	return s.location, s.err
+}
+
+// WatchingRESTStorage is a SimpleRESTStorage which serves fakeWatch to watches.
+type WatchingRESTStorage struct {
+	SimpleRESTStorage
+	fakeWatch *watch.FakeWatcher
+}
+
+func (s *WatchingRESTStorage) Watch(label, field labels.Selector, resourceVersion uint64) (watch.Interface, error) {
+	return s.fakeWatch, s.err
+}
@@ -0,0 +1,325 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/ryutah/kubernetes-transcribe/pkg/api"
+	"github.com/ryutah/kubernetes-transcribe/pkg/api/errors"
+	"github.com/ryutah/kubernetes-transcribe/pkg/runtime"
+	"github.com/ryutah/kubernetes-transcribe/pkg/tools"
+	"github.com/ryutah/kubernetes-transcribe/pkg/util"
+)
+
+// batchKindOrder is the order in which objects of a batch are applied, so that
+// objects are in place before the ones depending on them: services before the
+// pods which find them through environment variables, and so on. Kinds which are
+// not listed come last. Items of the same kind keep the order they were sent in.
+var batchKindOrder = []string{"Minion", "Service", "Endpoints", "ReplicationController", "Pod", "Binding"}
+
+// batchRequest is the body of a batch request. Items may be of any kind, in any
+// version; each is decoded according to its own kind and apiVersion.
+type batchRequest struct {
+	Items []json.RawMessage `json:"items"`
+}
+
+// batchItem is an item of a batch request, with the storage it is applied through.
+type batchItem struct {
+	index    int
+	kind     string
+	resource string
+	storage  RESTStorage
+	obj      runtime.Object
+	// previous is the object the item replaces, or nil if it is created.
+	previous runtime.Object
+	// undo reverts the item once it has been applied.
+	undo func() error
+	// late receives the result of the item if it wasn't applied in time.
+	late <-chan runtime.Object
+}
+
+// BatchHandler handles POST /batch, which creates or updates a list of objects of
+// mixed kinds, in dependency order. An item is updated if an object with its ID
+// exists, and created otherwise. With allOrNothing=true, the batch stops at the
+// first failure, and the items applied before it are reverted. The response is an
+// api.StatusList with the outcome of each item, in the order they were sent.
+type BatchHandler struct {
+	handler *RESTHandler
+}
+
+// ServeHTTP implements http.Handler.
+func (h *BatchHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r := h.handler
+	codec := negotiateCodec(req, r.codec, r.protobufCodec)
+	if req.Method != "POST" {
+		notFound(w, req)
+		return
+	}
+	if r.auditor != nil {
+		defer r.auditor.NewAudited(req, &w, "batch", "").Log()
+	}
+	body, err := readBody(req)
+	if err != nil {
+		errorJSON(err, codec, w)
+		return
+	}
+	var batch batchRequest
+	if err := json.Unmarshal(body, &batch); err != nil {
+		writeJSON(http.StatusBadRequest, codec, badRequest("invalid batch: %v", err), w)
+		return
+	}
+	allOrNothing := req.URL.Query().Get("allOrNothing") == "true"
+	timeout := parseTimeout(req.URL.Query().Get("timeout"))
+
+	statuses := make([]api.Status, len(batch.Items))
+	items := []*batchItem{}
+	for i, data := range batch.Items {
+		item, status := h.decodeItem(i, data)
+		if status != nil {
+			statuses[i] = *status
+			if allOrNothing {
+				writeJSON(statuses[i].Code, codec, h.skipped(statuses, i), w)
+				return
+			}
+			continue
+		}
+		items = append(items, item)
+	}
+	sort.SliceStable(items, func(i, j int) bool { return kindRank(items[i].kind) < kindRank(items[j].kind) })
+
+	for n, item := range items {
+		statuses[item.index] = *h.apply(item, timeout)
+		if item.late != nil {
+			h.awaitLate(item, allOrNothing)
+			if allOrNothing {
+				statuses[item.index].Message += "; it will be rolled back if it is applied later"
+			}
+		}
+		if statuses[item.index].Status == api.StatusSuccess || !allOrNothing {
+			continue
+		}
+		for i := n - 1; i >= 0; i-- {
+			statuses[items[i].index] = *h.revert(items[i], &statuses[items[i].index])
+		}
+		writeJSON(statuses[item.index].Code, codec, h.skipped(statuses, item.index), w)
+		return
+	}
+	writeJSON(http.StatusOK, codec, &api.StatusList{Items: statuses}, w)
+}
+
+// decodeItem decodes the item of a batch at index, and finds the storage for it. A
+// failure status is returned if that isn't possible.
+func (h *BatchHandler) decodeItem(index int, data []byte) (*batchItem, *api.Status) {
+	var typeMeta struct {
+		Kind string `json:"kind"`
+	}
+	if err := json.Unmarshal(data, &typeMeta); err != nil || typeMeta.Kind == "" {
+		return nil, badRequest("item %d has no kind", index)
+	}
+	obj, err := h.handler.codec.Decode(data)
+	if err != nil {
+		return nil, badRequest("item %d: %v", index, err)
+	}
+	resource, storage := h.storageFor(obj)
+	if storage == nil {
+		return nil, badRequest("item %d: %s objects can't be stored", index, typeMeta.Kind)
+	}
+	return &batchItem{index: index, kind: typeMeta.Kind, resource: resource, storage: storage, obj: obj}, nil
+}
+
+// storageFor returns the storage holding objects of the type of obj, if any.
+func (h *BatchHandler) storageFor(obj runtime.Object) (string, RESTStorage) {
+	resources := []string{}
+	for resource := range h.handler.storage {
+		resources = append(resources, resource)
+	}
+	sort.Strings(resources)
+	for _, resource := range resources {
+		storage := h.handler.storage[resource]
+		if reflect.TypeOf(storage.New()) == reflect.TypeOf(obj) {
+			return resource, storage
+		}
+	}
+	return "", nil
+}
+
+// apply creates or updates item, and records how to undo it. If the item isn't
+// applied within timeout, its result is left in item.late.
+func (h *BatchHandler) apply(item *batchItem, timeout time.Duration) *api.Status {
+	jsonBase, err := runtime.FindJSONBase(item.obj)
+	if err != nil {
+		return errToAPIStatus(err)
+	}
+	id := jsonBase.ID()
+	if id != "" {
+		item.previous, err = item.storage.Get(id)
+		if err != nil && !errors.IsNotFound(err) && !tools.IsEtcdNotFound(err) {
+			return errToAPIStatus(err)
+		}
+	}
+
+	var out <-chan runtime.Object
+	code := http.StatusCreated
+	if item.previous != nil {
+		code = http.StatusOK
+		if jsonBase.ResourceVersion() == 0 {
+			// Update the version that was read, so that concurrent changes aren't lost.
+			if current, err := runtime.FindJSONBase(item.previous); err == nil {
+				jsonBase.SetResourceVersion(current.ResourceVersion())
+			}
+		}
+		out, err = item.storage.Update(item.obj)
+	} else {
+		out, err = item.storage.Create(item.obj)
+	}
+	if err != nil {
+		return errToAPIStatus(err)
+	}
+	result, status, done := waitForResult(out, timeout)
+	if !done {
+		item.late = out
+		return status
+	}
+	if status.Status != api.StatusSuccess {
+		return status
+	}
+	status.Code = code
+	status.Details = &api.StatusDetails{ID: h.recordUndo(item, result), Kind: item.kind}
+	return status
+}
+
+// recordUndo sets item.undo to revert item, which has been applied with result, and
+// returns the ID of the object. The object is only reverted if it is still at the
+// version the batch wrote, so that changes made since aren't lost.
+func (h *BatchHandler) recordUndo(item *batchItem, result runtime.Object) string {
+	var id string
+	if jsonBase, err := runtime.FindJSONBase(item.obj); err == nil {
+		id = jsonBase.ID()
+	}
+	var written uint64
+	if resultBase, err := runtime.FindJSONBase(result); err == nil {
+		if resultBase.ID() != "" {
+			id = resultBase.ID()
+		}
+		written = resultBase.ResourceVersion()
+	}
+
+	if item.previous == nil {
+		item.undo = func() error {
+			if deleter, ok := item.storage.(ConditionalDeleter); ok && written != 0 {
+				return waitForUndo(deleter.DeleteIfVersion(id, written))
+			}
+			return waitForUndo(item.storage.Delete(id))
+		}
+		return id
+	}
+	previous := item.previous
+	item.undo = func() error {
+		if written == 0 {
+			return fmt.Errorf("the version written by the batch is unknown")
+		}
+		previousBase, err := runtime.FindJSONBase(previous)
+		if err != nil {
+			return err
+		}
+		// The update fails with a conflict if the object has changed since.
+		previousBase.SetResourceVersion(written)
+		return waitForUndo(item.storage.Update(previous))
+	}
+	return id
+}
+
+// awaitLate receives the result of item, which wasn't applied in time, and reverts
+// the item if revert is true and it was applied after all.
+func (h *BatchHandler) awaitLate(item *batchItem, revert bool) {
+	go func() {
+		defer util.HandleCrash()
+		result, ok := <-item.late
+		if !ok || !revert {
+			return
+		}
+		if status, ok := result.(*api.Status); ok && status.Status != api.StatusSuccess {
+			return
+		}
+		h.recordUndo(item, result)
+		if err := item.undo(); err != nil {
+			glog.Errorf("Failed to roll back %s in batch after it was applied late: %v", item.resource, err)
+		}
+	}()
+}
+
+// revert undoes an applied item of an all-or-nothing batch, and returns its new status.
+func (h *BatchHandler) revert(item *batchItem, applied *api.Status) *api.Status {
+	status := &api.Status{
+		Status:  api.StatusFailure,
+		Code:    http.StatusConflict,
+		Message: "rolled back, since another item of the batch failed",
+		Details: applied.Details,
+	}
+	if err := item.undo(); err != nil {
+		glog.Errorf("Failed to roll back %s in batch: %v", item.resource, err)
+		status.Code = http.StatusInternalServerError
+		status.Message = fmt.Sprintf("applied, but could not be rolled back: %v", err)
+	}
+	return status
+}
+
+// skipped returns statuses as a list, after marking every item without a status
+// as not attempted because of the failure of the item at index failed.
+func (h *BatchHandler) skipped(statuses []api.Status, failed int) *api.StatusList {
+	for i := range statuses {
+		if statuses[i].Status == "" {
+			statuses[i] = api.Status{
+				Status:  api.StatusFailure,
+				Code:    http.StatusConflict,
+				Message: fmt.Sprintf("not attempted, since item %d of the batch failed", failed),
+			}
+		}
+	}
+	return &api.StatusList{Items: statuses}
+}
+
+// kindRank returns the position of kind in batchKindOrder.
+func kindRank(kind string) int {
+	for i, k := range batchKindOrder {
+		if k == kind {
+			return i
+		}
+	}
+	return len(batchKindOrder)
+}
+
+// waitForResult waits, at most for timeout, for the result of a storage operation.
+// The returned status is a success unless the operation failed or took too long, in
+// which case done is false.
+func waitForResult(out <-chan runtime.Object, timeout time.Duration) (result runtime.Object, status *api.Status, done bool) {
+	select {
+	case result := <-out:
+		if status, ok := result.(*api.Status); ok && status.Status != api.StatusSuccess {
+			return nil, status, true
+		}
+		return result, &api.Status{Status: api.StatusSuccess}, true
+	case <-time.After(timeout):
+		return nil, &api.Status{
+			Status:  api.StatusFailure,
+			Code:    http.StatusGatewayTimeout,
+			Message: fmt.Sprintf("no result after %v", timeout),
+		}, false
+	}
+}
+
+// waitForUndo returns the error of a storage operation undoing a batch item.
+func waitForUndo(out <-chan runtime.Object, err error) error {
+	if err != nil {
+		return err
+	}
+	if _, status, _ := waitForResult(out, parseTimeout("")); status.Status != api.StatusSuccess {
+		return fmt.Errorf("%s", status.Message)
+	}
+	return nil
+}
@@ -0,0 +1,380 @@
+package apiserver
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ryutah/kubernetes-transcribe/pkg/api"
+	"github.com/ryutah/kubernetes-transcribe/pkg/api/errors"
+	"github.com/ryutah/kubernetes-transcribe/pkg/labels"
+	"github.com/ryutah/kubernetes-transcribe/pkg/runtime"
+)
+
+// batchLog records the operations on the storages of a batch test, in order.
+type batchLog struct {
+	lock sync.Mutex
+	ops  []string
+}
+
+func (l *batchLog) add(op string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.ops = append(l.ops, op)
+}
+
+func (l *batchLog) get() []string {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return append([]string{}, l.ops...)
+}
+
+// batchStorage keeps objects of one kind in memory, versioned like etcd would.
+// Operations named in fail ("create foo", "update foo", "delete foo") fail, and
+// those named in block wait until the channel is closed.
+type batchStorage struct {
+	kind    string
+	newFunc func() runtime.Object
+	log     *batchLog
+
+	lock    sync.Mutex
+	objects map[string]runtime.Object
+	version uint64
+	fail    map[string]bool
+	block   map[string]chan struct{}
+}
+
+func newBatchStorage(kind string, newFunc func() runtime.Object, log *batchLog) *batchStorage {
+	return &batchStorage{
+		kind:    kind,
+		newFunc: newFunc,
+		log:     log,
+		objects: map[string]runtime.Object{},
+		fail:    map[string]bool{},
+		block:   map[string]chan struct{}{},
+	}
+}
+
+func (s *batchStorage) New() runtime.Object {
+	return s.newFunc()
+}
+
+func (s *batchStorage) List(label, field labels.Selector) (runtime.Object, error) {
+	return nil, fmt.Errorf("not supported")
+}
+
+func (s *batchStorage) Get(id string) (runtime.Object, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	obj, ok := s.objects[id]
+	if !ok {
+		return nil, errors.NewNotFound(s.kind, id)
+	}
+	return obj, nil
+}
+
+// put stores obj with a new version.
+func (s *batchStorage) put(obj runtime.Object) {
+	jsonBase, _ := runtime.FindJSONBase(obj)
+	s.version++
+	jsonBase.SetResourceVersion(s.version)
+	s.objects[jsonBase.ID()] = obj
+}
+
+// do runs the operation op on id, unless it is made to fail.
+func (s *batchStorage) do(op, id string, fn func() (runtime.Object, error)) <-chan runtime.Object {
+	name := op + " " + id
+	s.lock.Lock()
+	block := s.block[name]
+	s.lock.Unlock()
+	return MakeAsync(func() (runtime.Object, error) {
+		if block != nil {
+			<-block
+		}
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		if s.fail[name] {
+			return nil, fmt.Errorf("%s failed", name)
+		}
+		s.log.add(name + " " + s.kind)
+		return fn()
+	})
+}
+
+func (s *batchStorage) Create(obj runtime.Object) (<-chan runtime.Object, error) {
+	jsonBase, err := runtime.FindJSONBase(obj)
+	if err != nil {
+		return nil, err
+	}
+	return s.do("create", jsonBase.ID(), func() (runtime.Object, error) {
+		if _, ok := s.objects[jsonBase.ID()]; ok {
+			return nil, errors.NewAlreadyExists(s.kind, jsonBase.ID())
+		}
+		s.put(obj)
+		return obj, nil
+	}), nil
+}
+
+func (s *batchStorage) Update(obj runtime.Object) (<-chan runtime.Object, error) {
+	jsonBase, err := runtime.FindJSONBase(obj)
+	if err != nil {
+		return nil, err
+	}
+	return s.do("update", jsonBase.ID(), func() (runtime.Object, error) {
+		stored, ok := s.objects[jsonBase.ID()]
+		if !ok {
+			return nil, errors.NewNotFound(s.kind, jsonBase.ID())
+		}
+		storedBase, _ := runtime.FindJSONBase(stored)
+		if version := jsonBase.ResourceVersion(); version != 0 && version != storedBase.ResourceVersion() {
+			return nil, errors.NewConflict(s.kind, jsonBase.ID(), fmt.Errorf("resource version %d is stale", version))
+		}
+		s.put(obj)
+		return obj, nil
+	}), nil
+}
+
+func (s *batchStorage) Delete(id string) (<-chan runtime.Object, error) {
+	return s.do("delete", id, func() (runtime.Object, error) {
+		if _, ok := s.objects[id]; !ok {
+			return nil, errors.NewNotFound(s.kind, id)
+		}
+		delete(s.objects, id)
+		return &api.Status{Status: api.StatusSuccess}, nil
+	}), nil
+}
+
+func newBatchTest() (*batchLog, *batchStorage, *batchStorage, *httptest.Server) {
+	log := &batchLog{}
+	pods := newBatchStorage("pod", func() runtime.Object { return &api.Pod{} }, log)
+	services := newBatchStorage("service", func() runtime.Object { return &api.Service{} }, log)
+	server := httptest.NewServer(Handle(map[string]RESTStorage{
+		"pods":     pods,
+		"services": services,
+	}, codec, "/prefix/version"))
+	return log, pods, services, server
+}
+
+func postBatch(t *testing.T, server *httptest.Server, query string, items ...string) (int, *api.StatusList) {
+	body := `{"items": [`
+	for i, item := range items {
+		if i > 0 {
+			body += ","
+		}
+		body += item
+	}
+	body += `]}`
+	resp, err := http.Post(server.URL+"/prefix/version/batch"+query, "application/json", bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	list := &api.StatusList{}
+	if err := codec.DecodeInto(data, list); err != nil {
+		t.Fatalf("unexpected error: %v: %s", err, data)
+	}
+	return resp.StatusCode, list
+}
+
+func codesOf(list *api.StatusList) []int {
+	codes := []int{}
+	for _, status := range list.Items {
+		codes = append(codes, status.Code)
+	}
+	return codes
+}
+
+func TestBatchOrderAndStatuses(t *testing.T) {
+	log, pods, _, server := newBatchTest()
+	defer server.Close()
+	pods.put(&api.Pod{JSONBase: api.JSONBase{ID: "existing"}})
+
+	code, list := postBatch(t, server, "",
+		`{"kind": "Pod", "id": "new"}`,
+		`{"kind": "Pod", "id": "existing"}`,
+		`{"kind": "Service", "id": "svc"}`,
+	)
+	if e, a := http.StatusOK, code; e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+	// Services are applied before the pods which depend on them; pods keep their order.
+	if e, a := []string{"create svc service", "create new pod", "update existing pod"}, log.get(); !reflect.DeepEqual(e, a) {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+	// Statuses are in the order the items were sent.
+	if e, a := []int{http.StatusCreated, http.StatusOK, http.StatusCreated}, codesOf(list); !reflect.DeepEqual(e, a) {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+	for i, id := range []string{"new", "existing", "svc"} {
+		status := list.Items[i]
+		if status.Status != api.StatusSuccess || status.Details == nil || status.Details.ID != id {
+			t.Errorf("%d: unexpected status %#v", i, status)
+		}
+	}
+}
+
+func TestBatchDecodeFailure(t *testing.T) {
+	log, _, _, server := newBatchTest()
+	defer server.Close()
+
+	code, list := postBatch(t, server, "",
+		`{"kind": "Pod", "id": "foo"}`,
+		`{"id": "no-kind"}`,
+		`{"kind": "Minion", "id": "not-stored"}`,
+	)
+	if e, a := http.StatusOK, code; e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+	if e, a := []int{http.StatusCreated, http.StatusBadRequest, http.StatusBadRequest}, codesOf(list); !reflect.DeepEqual(e, a) {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+	if e, a := []string{"create foo pod"}, log.get(); !reflect.DeepEqual(e, a) {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+
+	// With allOrNothing, nothing is applied.
+	code, list = postBatch(t, server, "?allOrNothing=true",
+		`{"kind": "Pod", "id": "bar"}`,
+		`{"id": "no-kind"}`,
+	)
+	if e, a := http.StatusBadRequest, code; e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+	if e, a := []int{http.StatusConflict, http.StatusBadRequest}, codesOf(list); !reflect.DeepEqual(e, a) {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+	if e, a := []string{"create foo pod"}, log.get(); !reflect.DeepEqual(e, a) {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+}
+
+func TestBatchAllOrNothingRollback(t *testing.T) {
+	log, pods, services, server := newBatchTest()
+	defer server.Close()
+	pods.put(&api.Pod{JSONBase: api.JSONBase{ID: "existing"}, DesiredState: api.PodState{Host: "old"}})
+	pods.fail["create broken"] = true
+
+	code, list := postBatch(t, server, "?allOrNothing=true",
+		`{"kind": "Pod", "id": "existing", "desiredState": {"host": "new"}}`,
+		`{"kind": "Service", "id": "svc"}`,
+		`{"kind": "Pod", "id": "broken"}`,
+		`{"kind": "Pod", "id": "skipped"}`,
+	)
+	if e, a := http.StatusInternalServerError, code; e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+	if e, a := []int{http.StatusConflict, http.StatusConflict, http.StatusInternalServerError, http.StatusConflict}, codesOf(list); !reflect.DeepEqual(e, a) {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+	expected := []string{
+		"create svc service", "update existing pod",
+		"update existing pod", "delete svc service",
+	}
+	if e, a := expected, log.get(); !reflect.DeepEqual(e, a) {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+	if _, err := services.Get("svc"); !errors.IsNotFound(err) {
+		t.Errorf("expected the service to be deleted, got %v", err)
+	}
+	obj, err := pods.Get("existing")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e, a := "old", obj.(*api.Pod).DesiredState.Host; e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+}
+
+func TestBatchFailedUndo(t *testing.T) {
+	_, pods, services, server := newBatchTest()
+	defer server.Close()
+	pods.fail["create broken"] = true
+	services.fail["delete svc"] = true
+
+	code, list := postBatch(t, server, "?allOrNothing=true",
+		`{"kind": "Service", "id": "svc"}`,
+		`{"kind": "Pod", "id": "broken"}`,
+	)
+	if e, a := http.StatusInternalServerError, code; e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+	status := list.Items[0]
+	if status.Code != http.StatusInternalServerError || status.Message != "applied, but could not be rolled back: delete svc failed" {
+		t.Errorf("unexpected status %#v", status)
+	}
+	if _, err := services.Get("svc"); err != nil {
+		t.Errorf("expected the service to be left, got %v", err)
+	}
+}
+
+func TestBatchUndoKeepsLaterChanges(t *testing.T) {
+	log, pods, _, server := newBatchTest()
+	defer server.Close()
+	pods.put(&api.Pod{JSONBase: api.JSONBase{ID: "existing"}, DesiredState: api.PodState{Host: "old"}})
+	// The pod is changed by someone else before the batch fails.
+	block := make(chan struct{})
+	pods.block["create broken"] = block
+	pods.fail["create broken"] = true
+	go func() {
+		for len(log.get()) == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		pods.lock.Lock()
+		pods.put(&api.Pod{JSONBase: api.JSONBase{ID: "existing"}, DesiredState: api.PodState{Host: "other"}})
+		pods.lock.Unlock()
+		close(block)
+	}()
+
+	_, list := postBatch(t, server, "?allOrNothing=true",
+		`{"kind": "Pod", "id": "existing", "desiredState": {"host": "new"}}`,
+		`{"kind": "Pod", "id": "broken"}`,
+	)
+	if e, a := http.StatusInternalServerError, list.Items[0].Code; e != a {
+		t.Errorf("expected the rollback to fail, got %#v", list.Items[0])
+	}
+	obj, err := pods.Get("existing")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e, a := "other", obj.(*api.Pod).DesiredState.Host; e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+}
+
+func TestBatchLateItemIsRolledBack(t *testing.T) {
+	log, pods, _, server := newBatchTest()
+	defer server.Close()
+	block := make(chan struct{})
+	pods.block["create slow"] = block
+
+	code, list := postBatch(t, server, "?allOrNothing=true&timeout=10ms",
+		`{"kind": "Pod", "id": "slow"}`,
+	)
+	if e, a := http.StatusGatewayTimeout, code; e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+	if e, a := []int{http.StatusGatewayTimeout}, codesOf(list); !reflect.DeepEqual(e, a) {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+
+	close(block)
+	for i := 0; i < 100 && len(log.get()) < 2; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if e, a := []string{"create slow pod", "delete slow pod"}, log.get(); !reflect.DeepEqual(e, a) {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+	if _, err := pods.Get("slow"); !errors.IsNotFound(err) {
+		t.Errorf("expected the late pod to be deleted, got %v", err)
+	}
+}
@@ -0,0 +1,199 @@
+package apiserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/ryutah/kubernetes-transcribe/pkg/runtime"
+)
+
+const (
+	jsonContentType     = "application/json"
+	yamlContentType     = "application/yaml"
+	protobufContentType = "application/vnd.kubernetes.protobuf"
+)
+
+// yamlContentTypes lists the media types accepted as meaning YAML.
+var yamlContentTypes = map[string]bool{
+	"application/yaml":   true,
+	"application/x-yaml": true,
+	"text/yaml":          true,
+	"text/x-yaml":        true,
+}
+
+// yamlCodec wraps a codec so that it produces YAML instead of JSON. Decoding is
+// passed straight through, since codecs read YAML as well as JSON.
+type yamlCodec struct {
+	runtime.Codec
+}
+
+// Encode implements runtime.Encoder.
+func (c yamlCodec) Encode(obj runtime.Object) ([]byte, error) {
+	data, err := c.Codec.Encode(obj)
+	if err != nil {
+		return nil, err
+	}
+	return jsonToYAML(data)
+}
+
+// protobufCodec marks a codec producing protobuf, as opposed to JSON.
+type protobufCodec struct {
+	runtime.Codec
+}
+
+// contentTypeOf returns the media type of the output of codec.
+func contentTypeOf(codec runtime.Codec) string {
+	switch codec.(type) {
+	case yamlCodec:
+		return yamlContentType
+	case protobufCodec:
+		return protobufContentType
+	}
+	return jsonContentType
+}
+
+// negotiateCodec returns the codec to write the response to req with, according to
+// its Accept header. Responses are JSON unless YAML is preferred, or protobuf is
+// and protobuf is not nil.
+func negotiateCodec(req *http.Request, codec, protobuf runtime.Codec) runtime.Codec {
+	for _, mediaType := range acceptedMediaTypes(req.Header.Get("Accept")) {
+		switch {
+		case yamlContentTypes[mediaType]:
+			return yamlCodec{codec}
+		case mediaType == protobufContentType && protobuf != nil:
+			return protobufCodec{protobuf}
+		case mediaType == jsonContentType, mediaType == "application/*", mediaType == "*/*":
+			return codec
+		}
+	}
+	return codec
+}
+
+// acceptedMediaTypes parses an Accept header, and returns the media types it lists
+// from the most to the least preferred. Types with q=0 are left out.
+func acceptedMediaTypes(accept string) []string {
+	type mediaRange struct {
+		mediaType string
+		q         float64
+	}
+	ranges := []mediaRange{}
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if s, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(s, 64); err != nil {
+				continue
+			}
+		}
+		if q > 0 {
+			ranges = append(ranges, mediaRange{mediaType, q})
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].q > ranges[j].q })
+	mediaTypes := make([]string, len(ranges))
+	for i := range ranges {
+		mediaTypes[i] = ranges[i].mediaType
+	}
+	return mediaTypes
+}
+
+// isYAMLRequest returns true if the body of req is YAML, according to its Content-Type.
+func isYAMLRequest(req *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	return err == nil && yamlContentTypes[mediaType]
+}
+
+// isProtobufRequest returns true if the body of req is protobuf, according to its
+// Content-Type.
+func isProtobufRequest(req *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	return err == nil && mediaType == protobufContentType
+}
+
+// jsonToYAML converts a JSON document to YAML.
+func jsonToYAML(data []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	// Keep integers intact; float64 would print large ones in exponent form.
+	decoder.UseNumber()
+	var obj interface{}
+	if err := decoder.Decode(&obj); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(fromJSONNumbers(obj))
+}
+
+func fromJSONNumbers(obj interface{}) interface{} {
+	switch t := obj.(type) {
+	case map[string]interface{}:
+		for k, v := range t {
+			t[k] = fromJSONNumbers(v)
+		}
+	case []interface{}:
+		for i, v := range t {
+			t[i] = fromJSONNumbers(v)
+		}
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i
+		}
+		if f, err := t.Float64(); err == nil {
+			return f
+		}
+		return t.String()
+	}
+	return obj
+}
+
+// yamlToJSON converts a YAML document to JSON.
+func yamlToJSON(data []byte) ([]byte, error) {
+	var obj interface{}
+	if err := yaml.Unmarshal(data, &obj); err != nil {
+		return nil, err
+	}
+	obj, err := toJSONMaps(obj)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(obj)
+}
+
+// toJSONMaps replaces the map[interface{}]interface{} values produced by the yaml
+// package with map[string]interface{}, which encoding/json can handle.
+func toJSONMaps(obj interface{}) (interface{}, error) {
+	switch t := obj.(type) {
+	case map[interface{}]interface{}:
+		out := make(map[string]interface{}, len(t))
+		for k, v := range t {
+			key, ok := k.(string)
+			if !ok {
+				return nil, fmt.Errorf("unsupported map key %#v", k)
+			}
+			value, err := toJSONMaps(v)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = value
+		}
+		return out, nil
+	case []interface{}:
+		for i, v := range t {
+			value, err := toJSONMaps(v)
+			if err != nil {
+				return nil, err
+			}
+			t[i] = value
+		}
+	}
+	return obj, nil
+}
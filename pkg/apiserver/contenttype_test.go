@@ -0,0 +1,243 @@
+package apiserver
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/ryutah/kubernetes-transcribe/pkg/api"
+	"github.com/ryutah/kubernetes-transcribe/pkg/runtime"
+	"github.com/ryutah/kubernetes-transcribe/pkg/watch"
+)
+
+func TestNegotiateCodec(t *testing.T) {
+	protobuf := codec
+	table := map[string]struct {
+		accept   string
+		protobuf bool
+		expected string
+	}{
+		"none":                    {"", false, jsonContentType},
+		"json":                    {"application/json", false, jsonContentType},
+		"yaml":                    {"application/yaml", false, yamlContentType},
+		"yaml alias":              {"text/x-yaml", false, yamlContentType},
+		"unknown":                 {"text/html", false, jsonContentType},
+		"wildcard":                {"*/*", false, jsonContentType},
+		"unknown first":           {"text/html, application/yaml", false, yamlContentType},
+		"preferred by q":          {"application/json;q=0.5, application/yaml", false, yamlContentType},
+		"less preferred yaml":     {"application/yaml;q=0.5, application/json", false, jsonContentType},
+		"refused yaml":            {"application/yaml;q=0", false, jsonContentType},
+		"malformed q":             {"application/yaml;q=high, application/json", false, jsonContentType},
+		"protobuf":                {protobufContentType, true, protobufContentType},
+		"protobuf not served":     {protobufContentType, false, jsonContentType},
+		"protobuf then yaml":      {protobufContentType + ", application/yaml;q=0.9", false, yamlContentType},
+		"yaml over protobuf by q": {protobufContentType + ";q=0.1, application/yaml", true, yamlContentType},
+	}
+	for name, item := range table {
+		req, _ := http.NewRequest("GET", "http://localhost/", nil)
+		if item.accept != "" {
+			req.Header.Set("Accept", item.accept)
+		}
+		var p = protobuf
+		if !item.protobuf {
+			p = nil
+		}
+		if e, a := item.expected, contentTypeOf(negotiateCodec(req, codec, p)); e != a {
+			t.Errorf("%s: expected %v, got %v", name, e, a)
+		}
+	}
+}
+
+func TestYAMLToJSON(t *testing.T) {
+	data, err := yamlToJSON([]byte("id: foo\nresourceVersion: 12345678901\nlabels:\n  a: b\nitems:\n- x: 1\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e, a := `{"id":"foo","items":[{"x":1}],"labels":{"a":"b"},"resourceVersion":12345678901}`, string(data); e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+	if _, err := yamlToJSON([]byte("1: one\n")); err == nil {
+		t.Errorf("expected an error for a non-string key")
+	}
+}
+
+func TestYAMLRequestBody(t *testing.T) {
+	server := httptest.NewServer(Handle(map[string]RESTStorage{"pods": &SimpleRESTStorage{}}, codec, "/prefix/version"))
+	defer server.Close()
+
+	table := map[string]struct {
+		contentType string
+		body        string
+		expectCode  int
+	}{
+		"json":             {"application/json", `{"kind": "Pod", "id": "foo"}`, http.StatusOK},
+		"yaml":             {"application/yaml", "kind: Pod\nid: foo\n", http.StatusOK},
+		"yaml with params": {"text/yaml; charset=utf-8", "kind: Pod\nid: foo\n", http.StatusOK},
+		"yaml as json":     {"application/json", "kind: Pod\nid: foo\n", http.StatusOK},
+		"invalid yaml":     {"application/yaml", "kind: [Pod\n", http.StatusInternalServerError},
+	}
+	for name, item := range table {
+		resp, err := http.Post(server.URL+"/prefix/version/pods?sync=true", item.contentType, bytes.NewBufferString(item.body))
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		data, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if e, a := item.expectCode, resp.StatusCode; e != a {
+			t.Errorf("%s: expected %v, got %v: %s", name, e, a, data)
+			continue
+		}
+		if item.expectCode != http.StatusOK {
+			continue
+		}
+		pod := &api.Pod{}
+		if err := codec.DecodeInto(data, pod); err != nil {
+			t.Errorf("%s: unexpected error: %v", name, err)
+		}
+		if e, a := "foo", pod.ID; e != a {
+			t.Errorf("%s: expected %v, got %v", name, e, a)
+		}
+	}
+}
+
+// getYAML requests url, asking for YAML, and checks that it gets YAML back.
+func getYAML(t *testing.T, url string) *http.Response {
+	req, _ := http.NewRequest("GET", url, nil)
+	req.Header.Set("Accept", "application/yaml")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e, a := yamlContentType, resp.Header.Get("Content-Type"); e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+	return resp
+}
+
+func TestYAMLFromOperationHandler(t *testing.T) {
+	group := NewAPIGroup(map[string]RESTStorage{"pods": &SimpleRESTStorage{}}, codec)
+	mux := http.NewServeMux()
+	group.InstallREST(mux, "/prefix/version")
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	op := group.handler.ops.NewOperation(make(chan runtime.Object))
+
+	for _, path := range []string{"/prefix/version/operations", "/prefix/version/operations/" + op.ID} {
+		resp := getYAML(t, server.URL+path)
+		data, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		var obj map[string]interface{}
+		if err := yaml.Unmarshal(data, &obj); err != nil {
+			t.Errorf("%s: expected YAML, got %v: %s", path, err, data)
+		}
+		if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+			t.Errorf("%s: expected YAML, got JSON: %s", path, data)
+		}
+	}
+}
+
+func TestYAMLFromWatchHandler(t *testing.T) {
+	storage := &WatchingRESTStorage{fakeWatch: watch.NewFake()}
+	server := httptest.NewServer(Handle(map[string]RESTStorage{"pods": storage}, codec, "/prefix/version"))
+	defer server.Close()
+
+	resp := getYAML(t, server.URL+"/prefix/version/watch/pods")
+	defer resp.Body.Close()
+	go func() {
+		storage.fakeWatch.Add(&api.Pod{JSONBase: api.JSONBase{ID: "foo"}})
+		storage.fakeWatch.Delete(&api.Pod{JSONBase: api.JSONBase{ID: "foo"}})
+		storage.fakeWatch.Stop()
+	}()
+
+	stream := &bytes.Buffer{}
+	decoder := yaml.NewDecoder(io.TeeReader(resp.Body, stream))
+	for _, expected := range []watch.EventType{watch.Added, watch.Deleted} {
+		var event struct {
+			Type   watch.EventType        `yaml:"Type"`
+			Object map[string]interface{} `yaml:"Object"`
+		}
+		if err := decoder.Decode(&event); err != nil {
+			t.Fatalf("unexpected error: %v: %s", err, stream)
+		}
+		if e, a := expected, event.Type; e != a {
+			t.Errorf("expected %v, got %v", e, a)
+		}
+		if e, a := "foo", event.Object["id"]; e != a {
+			t.Errorf("expected %v, got %v", e, a)
+		}
+	}
+	// Each event is a YAML document, starting with "---".
+	if e, a := 2, strings.Count("\n"+stream.String(), "\n---\n"); e != a {
+		t.Errorf("expected %v documents, got %v: %s", e, a, stream)
+	}
+}
+
+func TestProtobufThroughHandler(t *testing.T) {
+	protobuf := runtime.ProtobufCodecFor(api.Scheme, "")
+	group := NewAPIGroup(map[string]RESTStorage{"pods": &SimpleRESTStorage{
+		pod: &api.Pod{JSONBase: api.JSONBase{ID: "foo"}, DesiredState: api.PodState{Host: "machine"}},
+	}}, codec)
+	group.SetProtobufCodec(protobuf)
+	mux := http.NewServeMux()
+	group.InstallREST(mux, "/prefix/version")
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	created, err := protobuf.Encode(&api.Pod{JSONBase: api.JSONBase{ID: "bar"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	table := map[string]struct {
+		method      string
+		path        string
+		contentType string
+		body        []byte
+		accept      string
+		expectType  string
+		expectID    string
+	}{
+		"get":             {"GET", "pods/foo", "", nil, protobufContentType, protobufContentType, "foo"},
+		"get json":        {"GET", "pods/foo", "", nil, "", jsonContentType, "foo"},
+		"create":          {"POST", "pods?sync=true", protobufContentType, created, protobufContentType, protobufContentType, "bar"},
+		"create for json": {"POST", "pods?sync=true", protobufContentType, created, "application/json", jsonContentType, "bar"},
+	}
+	for name, item := range table {
+		req, _ := http.NewRequest(item.method, server.URL+"/prefix/version/"+item.path, bytes.NewReader(item.body))
+		if item.contentType != "" {
+			req.Header.Set("Content-Type", item.contentType)
+		}
+		if item.accept != "" {
+			req.Header.Set("Accept", item.accept)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		data, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if e, a := http.StatusOK, resp.StatusCode; e != a {
+			t.Errorf("%s: expected %v, got %v: %s", name, e, a, data)
+			continue
+		}
+		if e, a := item.expectType, resp.Header.Get("Content-Type"); e != a {
+			t.Errorf("%s: expected %v, got %v", name, e, a)
+		}
+		if e, a := item.expectType == protobufContentType, runtime.IsProtobuf(data); e != a {
+			t.Errorf("%s: expected protobuf %v, got %q", name, e, data)
+		}
+		pod := &api.Pod{}
+		if err := protobuf.DecodeInto(data, pod); err != nil {
+			t.Errorf("%s: unexpected error: %v", name, err)
+			continue
+		}
+		if e, a := item.expectID, pod.ID; e != a {
+			t.Errorf("%s: expected %v, got %v", name, e, a)
+		}
+	}
+}
@@ -37,6 +37,15 @@ func errToAPIStatus(err error) *api.Status {
 	}
 }
 
+// badRequest returns the status of a request which can't be carried out as it is.
+func badRequest(format string, args ...interface{}) *api.Status {
+	return &api.Status{
+		Status:  api.StatusFailure,
+		Code:    http.StatusBadRequest,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
 // notFound renders a simple not found error.
 func notFound(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNotFound)
@@ -0,0 +1,90 @@
+package apiserver
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ryutah/kubernetes-transcribe/pkg/api"
+	"github.com/ryutah/kubernetes-transcribe/pkg/api/errors"
+	"github.com/ryutah/kubernetes-transcribe/pkg/runtime"
+	"github.com/ryutah/kubernetes-transcribe/pkg/tools"
+)
+
+// etagFor returns the ETag of obj, which is its quoted resource version, or "" if
+// it has none.
+func etagFor(obj runtime.Object) string {
+	jsonBase, err := runtime.FindJSONBase(obj)
+	if err != nil || jsonBase.ResourceVersion() == 0 {
+		return ""
+	}
+	return versionETag(jsonBase.ResourceVersion())
+}
+
+// versionETag returns the ETag of an object at the given resource version.
+func versionETag(version uint64) string {
+	return strconv.Quote(strconv.FormatUint(version, 10))
+}
+
+// etagMatches returns true if header, the value of an If-Match or If-None-Match
+// header, lists etag or is "*". Weak tags are compared as if they were strong.
+func etagMatches(header, etag string) bool {
+	if etag == "" {
+		return false
+	}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// notModified writes a 304 response and returns true if obj matches the If-None-Match
+// header of req.
+func notModified(req *http.Request, w http.ResponseWriter, obj runtime.Object) bool {
+	etag := etagFor(obj)
+	if !etagMatches(req.Header.Get("If-None-Match"), etag) {
+		return false
+	}
+	w.Header().Set("ETag", etag)
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// ifMatchVersion returns the resource version req is conditional on, from its
+// If-Match header. ok is false if there is no condition, which is also the case
+// for "*". An error is returned if the header names anything but a single
+// resource version, since such a condition can never be met.
+func ifMatchVersion(req *http.Request) (version uint64, ok bool, err error) {
+	header := strings.TrimSpace(req.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return 0, false, nil
+	}
+	tag, err := strconv.Unquote(strings.TrimPrefix(header, "W/"))
+	if err == nil {
+		version, err = strconv.ParseUint(tag, 10, 64)
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("If-Match must be a single resource version, got %s", header)
+	}
+	return version, true, nil
+}
+
+// preconditionFailed writes a 412 status for a request whose If-Match condition
+// doesn't hold.
+func preconditionFailed(message string, codec runtime.Codec, w http.ResponseWriter) {
+	writeJSON(http.StatusPreconditionFailed, codec, &api.Status{
+		Status:  api.StatusFailure,
+		Code:    http.StatusPreconditionFailed,
+		Reason:  api.StatusReasonConflict,
+		Message: message,
+	}, w)
+}
+
+// isConflict returns true if err means that the resource was not at the expected version.
+func isConflict(err error) bool {
+	return tools.IsEtcdTestFailed(err) || errors.IsConflict(err)
+}
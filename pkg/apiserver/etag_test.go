@@ -0,0 +1,81 @@
+package apiserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ryutah/kubernetes-transcribe/pkg/api"
+)
+
+func TestETagGet(t *testing.T) {
+	storage := map[string]RESTStorage{"pods": &SimpleRESTStorage{
+		pod: &api.Pod{JSONBase: api.JSONBase{ID: "foo", ResourceVersion: 5}},
+	}}
+	server := httptest.NewServer(Handle(storage, codec, "/prefix/version"))
+	defer server.Close()
+
+	table := map[string]struct {
+		ifNoneMatch string
+		expectCode  int
+	}{
+		"none":     {"", http.StatusOK},
+		"match":    {`"5"`, http.StatusNotModified},
+		"weak":     {`W/"5"`, http.StatusNotModified},
+		"list":     {`"4", "5"`, http.StatusNotModified},
+		"star":     {"*", http.StatusNotModified},
+		"mismatch": {`"4"`, http.StatusOK},
+	}
+	for name, item := range table {
+		req, _ := http.NewRequest("GET", server.URL+"/prefix/version/pods/foo", nil)
+		if item.ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", item.ifNoneMatch)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		resp.Body.Close()
+		if e, a := item.expectCode, resp.StatusCode; e != a {
+			t.Errorf("%s: expected %v, got %v", name, e, a)
+		}
+		if e, a := `"5"`, resp.Header.Get("ETag"); e != a {
+			t.Errorf("%s: expected ETag %v, got %v", name, e, a)
+		}
+	}
+}
+
+func TestETagIfMatch(t *testing.T) {
+	table := map[string]struct {
+		method     string
+		body       string
+		ifMatch    string
+		expectCode int
+	}{
+		"update matching":      {"PUT", `{"id":"foo"}`, `"5"`, http.StatusOK},
+		"update stale":         {"PUT", `{"id":"foo"}`, `"4"`, http.StatusPreconditionFailed},
+		"update disagreeing":   {"PUT", `{"id":"foo","resourceVersion":4}`, `"5"`, http.StatusPreconditionFailed},
+		"update unconditional": {"PUT", `{"id":"foo"}`, "*", http.StatusOK},
+		"update malformed":     {"PUT", `{"id":"foo"}`, `"4", "5"`, http.StatusPreconditionFailed},
+		"delete matching":      {"DELETE", "", `"5"`, http.StatusOK},
+		"delete stale":         {"DELETE", "", `"4"`, http.StatusPreconditionFailed},
+	}
+	for name, item := range table {
+		storage := map[string]RESTStorage{"pods": &SimpleRESTStorage{
+			pod: &api.Pod{JSONBase: api.JSONBase{ID: "foo", ResourceVersion: 5}},
+		}}
+		server := httptest.NewServer(Handle(storage, codec, "/prefix/version"))
+		req, _ := http.NewRequest(item.method, server.URL+"/prefix/version/pods/foo", strings.NewReader(item.body))
+		req.Header.Set("If-Match", item.ifMatch)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		resp.Body.Close()
+		if e, a := item.expectCode, resp.StatusCode; e != a {
+			t.Errorf("%s: expected %v, got %v", name, e, a)
+		}
+		server.Close()
+	}
+}
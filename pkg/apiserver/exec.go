@@ -0,0 +1,81 @@
+package apiserver
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+
+	"golang.org/x/net/websocket"
+
+	"github.com/ryutah/kubernetes-transcribe/pkg/httplog"
+	"github.com/ryutah/kubernetes-transcribe/pkg/runtime"
+	"github.com/ryutah/kubernetes-transcribe/pkg/util/wsstream"
+)
+
+// PodExecProxy serves GET /pods/<id>/exec and GET /pods/<id>/attach, which must
+// be websocket requests. The websocket is joined to one opened to the kubelet of
+// the minion running the pod, which runs command in the container, or attaches
+// to its main process, and multiplexes its streams as described in package
+// wsstream. The container query parameter names the container, and may be left
+// out if the pod has only one; exec takes the command as repeated command
+// parameters.
+type PodExecProxy struct {
+	// Port is the port on which kubelets serve /exec and /attach.
+	Port int
+}
+
+// ServeExec proxies the exec or attach request req, for the pod with the given ID
+// read from storage, to its kubelet. action is either "exec" or "attach".
+func (p *PodExecProxy) ServeExec(action, id string, storage RESTStorage, w http.ResponseWriter, req *http.Request, codec runtime.Codec) {
+	if !isWebSocketRequest(req) {
+		status := badRequest("%s requires a websocket", action)
+		writeJSON(status.Code, codec, status, w)
+		return
+	}
+	query := req.URL.Query()
+	host, container, status := locatePodContainer(storage, id, query.Get("container"))
+	if status != nil {
+		writeJSON(status.Code, codec, status, w)
+		return
+	}
+	kubeletQuery := url.Values{}
+	if action == "exec" {
+		commands := query["command"]
+		if len(commands) == 0 {
+			status := badRequest("exec requires a command")
+			writeJSON(status.Code, codec, status, w)
+			return
+		}
+		kubeletQuery["command"] = commands
+	}
+	location := &url.URL{
+		Scheme:   "ws",
+		Host:     net.JoinHostPort(host, strconv.Itoa(p.Port)),
+		Path:     path.Join("/", action, id, container),
+		RawQuery: kubeletQuery.Encode(),
+	}
+	origin := &url.URL{Scheme: "http", Host: req.Host}
+	config, err := websocket.NewConfig(location.String(), origin.String())
+	if err != nil {
+		errorJSON(err, codec, w)
+		return
+	}
+	kubelet, err := websocket.DialConfig(config)
+	if err != nil {
+		httplog.LogOf(req, w).Addf("failed to reach kubelet: %v", err)
+		badGatewayError(w, req)
+		return
+	}
+	relayed := false
+	websocket.Handler(func(ws *websocket.Conn) {
+		relayed = true
+		ws.PayloadType = websocket.BinaryFrame
+		wsstream.Relay(ws, kubelet)
+	}).ServeHTTP(httplog.Unlogged(w), req)
+	if !relayed {
+		// The client's handshake failed.
+		kubelet.Close()
+	}
+}
@@ -0,0 +1,147 @@
+package apiserver
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"github.com/ryutah/kubernetes-transcribe/pkg/api"
+	"github.com/ryutah/kubernetes-transcribe/pkg/util/wsstream"
+)
+
+// podOn returns a pod with a single container, c1, exposing port, running on host.
+func podOn(host string, port int) *api.Pod {
+	return &api.Pod{
+		JSONBase: api.JSONBase{ID: "foo"},
+		DesiredState: api.PodState{Manifest: api.ContainerManifest{
+			Containers: []api.Container{{Name: "c1", Ports: []api.Port{{Name: "http", ContainerPort: port}}}},
+		}},
+		CurrentState: api.PodState{Host: host, PodIP: host},
+	}
+}
+
+// execServer serves the pods of storage, with exec through a kubelet on kubeletURL.
+func execServer(t *testing.T, storage RESTStorage, kubeletURL string) *httptest.Server {
+	u, err := url.Parse(kubeletURL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, port, _ := net.SplitHostPort(u.Host)
+	kubeletPort, _ := strconv.Atoi(port)
+	group := NewAPIGroup(map[string]RESTStorage{"pods": storage}, codec)
+	group.SetPodExecProxy(&PodExecProxy{Port: kubeletPort})
+	mux := http.NewServeMux()
+	group.InstallREST(mux, "/prefix/version")
+	return httptest.NewServer(RecoverPanics(mux))
+}
+
+func TestExecRelaysToKubelet(t *testing.T) {
+	requests := make(chan *http.Request, 1)
+	kubelet := httptest.NewServer(websocket.Handler(func(ws *websocket.Conn) {
+		requests <- ws.Request()
+		ws.PayloadType = websocket.BinaryFrame
+		_, data, err := wsstream.Read(ws)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		wsstream.Write(ws, wsstream.StdoutChannel, append([]byte("out: "), data...))
+		ws.Close()
+	}))
+	defer kubelet.Close()
+	server := execServer(t, &SimpleRESTStorage{pod: podOn("127.0.0.1", 0)}, kubelet.URL)
+	defer server.Close()
+
+	ws, err := websocket.Dial("ws://"+server.Listener.Addr().String()+"/prefix/version/pods/foo/exec?command=ls&command=-l", "", "http://localhost/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer ws.Close()
+	ws.PayloadType = websocket.BinaryFrame
+	if err := wsstream.Write(ws, wsstream.StdinChannel, []byte("in")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	channel, data, err := wsstream.Read(ws)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if channel != wsstream.StdoutChannel || string(data) != "out: in" {
+		t.Errorf("unexpected message on channel %d: %q", channel, data)
+	}
+
+	req := <-requests
+	if e, a := "/exec/foo/c1", req.URL.Path; e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+	if e, a := []string{"ls", "-l"}, req.URL.Query()["command"]; !reflect.DeepEqual(e, a) {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+}
+
+func TestExecFailedHandshakeClosesKubelet(t *testing.T) {
+	closed := make(chan struct{})
+	kubelet := httptest.NewServer(websocket.Handler(func(ws *websocket.Conn) {
+		var message []byte
+		// Returns once the apiserver closes its side.
+		websocket.Message.Receive(ws, &message)
+		close(closed)
+	}))
+	defer kubelet.Close()
+	server := execServer(t, &SimpleRESTStorage{pod: podOn("127.0.0.1", 0)}, kubelet.URL)
+	defer server.Close()
+
+	req, _ := http.NewRequest("GET", server.URL+"/prefix/version/pods/foo/attach", nil)
+	// An upgrade, without the rest of a websocket handshake.
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected a bad request, got %v", resp.StatusCode)
+	}
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Errorf("expected the kubelet connection to be closed")
+	}
+}
+
+func TestExecErrors(t *testing.T) {
+	server := execServer(t, &SimpleRESTStorage{pod: podOn("", 0)}, "http://127.0.0.1:1")
+	defer server.Close()
+	table := map[string]struct {
+		path       string
+		websocket  bool
+		expectCode int
+	}{
+		"not a websocket":   {"/pods/foo/exec?command=ls", false, http.StatusBadRequest},
+		"no such pod":       {"/pods/bar/exec?command=ls", true, http.StatusNotFound},
+		"not on a minion":   {"/pods/foo/exec?command=ls", true, http.StatusBadRequest},
+		"no such container": {"/pods/foo/exec?command=ls&container=c2", true, http.StatusBadRequest},
+	}
+	for name, item := range table {
+		req, _ := http.NewRequest("GET", server.URL+"/prefix/version"+item.path, nil)
+		if item.websocket {
+			req.Header.Set("Connection", "Upgrade")
+			req.Header.Set("Upgrade", "websocket")
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		resp.Body.Close()
+		if e, a := item.expectCode, resp.StatusCode; e != a {
+			t.Errorf("%s: expected %v, got %v", name, e, a)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package apiserver
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/ryutah/kubernetes-transcribe/pkg/cloudprovider"
+	"github.com/ryutah/kubernetes-transcribe/pkg/healthz"
+	"github.com/ryutah/kubernetes-transcribe/pkg/tools"
+)
+
+// EtcdHealthCheck returns a healthz check named "etcd" that fails if the etcd
+// servers behind helper can't be reached.
+func EtcdHealthCheck(helper tools.EtcdHelper) healthz.HealthzChecker {
+	return healthz.NamedCheck("etcd", func(req *http.Request) error {
+		// Any answer from etcd, including "key not found", means it is reachable.
+		if _, err := helper.Client.Get("/", false, false); err != nil && !tools.IsEtcdNotFound(err) {
+			return err
+		}
+		return nil
+	})
+}
+
+// CloudHealthCheck returns a healthz check named "cloud" that fails if the cloud
+// provider can't list the instances matching filter.
+func CloudHealthCheck(cloud cloudprovider.Interface, filter string) healthz.HealthzChecker {
+	return healthz.NamedCheck("cloud", func(req *http.Request) error {
+		instances, ok := cloud.Instances()
+		if !ok {
+			return nil
+		}
+		_, err := instances.List(filter)
+		return err
+	})
+}
+
+// OperationsHealthCheck returns a healthz check named "operations" that fails
+// if more than maxPending operations are outstanding across groups.
+func OperationsHealthCheck(maxPending int, groups ...*APIGroup) healthz.HealthzChecker {
+	return healthz.NamedCheck("operations", func(req *http.Request) error {
+		pending := 0
+		for _, group := range groups {
+			pending += group.PendingOperations()
+		}
+		if pending > maxPending {
+			return fmt.Errorf("%d operations pending (limit %d)", pending, maxPending)
+		}
+		return nil
+	})
+}
@@ -1,6 +1,7 @@
 package apiserver
 
 import (
+	"github.com/ryutah/kubernetes-transcribe/pkg/api"
 	"github.com/ryutah/kubernetes-transcribe/pkg/labels"
 	"github.com/ryutah/kubernetes-transcribe/pkg/runtime"
 	"github.com/ryutah/kubernetes-transcribe/pkg/watch"
@@ -30,6 +31,39 @@ type RESTStorage interface {
 	Update(runtime.Object) (<-chan runtime.Object, error)
 }
 
+// DryRunner should be implemented by RESTStorage objects which can check a create, update
+// or delete without carrying it out, to support dryRun=true requests.
+type DryRunner interface {
+	// DryRunCreate runs the same defaulting and validation as Create, and returns the
+	// object that would be stored, or the error Create would return.
+	DryRunCreate(runtime.Object) (runtime.Object, error)
+	// DryRunUpdate is like DryRunCreate for Update.
+	DryRunUpdate(runtime.Object) (runtime.Object, error)
+	// DryRunDelete returns the object Delete would remove, or the error it would return.
+	DryRunDelete(id string) (runtime.Object, error)
+}
+
+// ConditionalDeleter should be implemented by RESTStorage objects which can delete a
+// resource atomically on the condition that it hasn't changed, to honour If-Match on
+// DELETE requests.
+type ConditionalDeleter interface {
+	// DeleteIfVersion is like Delete, but fails with an error for which
+	// tools.IsEtcdTestFailed or errors.IsConflict is true if the resource isn't at
+	// resourceVersion. tools.EtcdHelper.CompareAndDelete does this for etcd.
+	DeleteIfVersion(id string, resourceVersion uint64) (<-chan runtime.Object, error)
+}
+
+// GracefulDeleter should be implemented by RESTStorage objects which accept the
+// api.DeleteOptions of a DELETE request.
+type GracefulDeleter interface {
+	// DeleteWithOptions is like Delete, but the object is first given a
+	// DeletionTimestamp and kept for options.GracePeriodSeconds before it is removed.
+	// If options.Preconditions doesn't hold, it fails with an error for which
+	// tools.IsEtcdTestFailed or errors.IsConflict is true.
+	// tools.EtcdHelper.DeleteObjGracefully does this for etcd.
+	DeleteWithOptions(id string, options *api.DeleteOptions) (<-chan runtime.Object, error)
+}
+
 // ResourceWatcher should be implemented by all RESTStorage object that
 // want to offer the abillity to watch for changes through watch api.
 type ResourceWatcher interface {
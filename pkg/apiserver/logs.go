@@ -0,0 +1,176 @@
+package apiserver
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+
+	"github.com/ryutah/kubernetes-transcribe/pkg/api"
+	"github.com/ryutah/kubernetes-transcribe/pkg/httplog"
+	"github.com/ryutah/kubernetes-transcribe/pkg/runtime"
+)
+
+// PodLogStreamer serves GET /pods/<id>/log, by streaming the logs of a container
+// of the pod from the kubelet of the minion it runs on. The container query
+// parameter names the container, and may be left out if the pod has only one.
+// With follow=true new lines are streamed until the client goes away, and with
+// tailLines=<n> only the last n lines of the log are sent at first.
+type PodLogStreamer struct {
+	// Client is used to reach kubelets.
+	Client *http.Client
+	// Port is the port on which kubelets serve /containerLogs.
+	Port int
+}
+
+// ServeLogs streams the logs of the pod with the given ID, read from storage, to w.
+func (s *PodLogStreamer) ServeLogs(id string, storage RESTStorage, w http.ResponseWriter, req *http.Request, codec runtime.Codec) {
+	query := req.URL.Query()
+	host, container, status := locatePodContainer(storage, id, query.Get("container"))
+	if status != nil {
+		writeJSON(status.Code, codec, status, w)
+		return
+	}
+	kubeletQuery := url.Values{}
+	if query.Get("follow") == "true" {
+		kubeletQuery.Set("follow", "true")
+	}
+	if tailLines := query.Get("tailLines"); tailLines != "" {
+		if _, err := strconv.ParseUint(tailLines, 10, 64); err != nil {
+			status := badRequest("tailLines must be a number, got %q", tailLines)
+			writeJSON(status.Code, codec, status, w)
+			return
+		}
+		kubeletQuery.Set("tail", tailLines)
+	}
+	location := &url.URL{
+		Scheme:   "http",
+		Host:     net.JoinHostPort(host, strconv.Itoa(s.Port)),
+		Path:     path.Join("/containerLogs", id, container),
+		RawQuery: kubeletQuery.Encode(),
+	}
+	s.stream(location.String(), w, req)
+}
+
+// locatePodContainer returns the minion running the pod with the given ID, read
+// from storage, and the name of its container called name; name may be empty if
+// the pod has a single container. A failure status is returned if the pod can't
+// be found, doesn't have such a container or isn't running.
+func locatePodContainer(storage RESTStorage, id, name string) (host, container string, status *api.Status) {
+	pod, status := getPod(storage, id)
+	if status != nil {
+		return "", "", status
+	}
+	containers := pod.DesiredState.Manifest.Containers
+	switch {
+	case name == "" && len(containers) == 1:
+		container = containers[0].Name
+	case name == "":
+		return "", "", badRequest("pod %q has %d containers, a container must be given", id, len(containers))
+	default:
+		for i := range containers {
+			if containers[i].Name == name {
+				container = name
+			}
+		}
+		if container == "" {
+			return "", "", badRequest("pod %q has no container %q", id, name)
+		}
+	}
+	if pod.CurrentState.Host == "" {
+		return "", "", badRequest("pod %q is not running on a minion", id)
+	}
+	return pod.CurrentState.Host, container, nil
+}
+
+// getPod returns the pod with the given ID, read from storage, or a failure status
+// if there is no such pod.
+func getPod(storage RESTStorage, id string) (*api.Pod, *api.Status) {
+	obj, err := storage.Get(id)
+	if err != nil {
+		return nil, errToAPIStatus(err)
+	}
+	pod, ok := obj.(*api.Pod)
+	if !ok {
+		return nil, &api.Status{
+			Status:  api.StatusFailure,
+			Code:    http.StatusNotFound,
+			Reason:  api.StatusReasonNotFound,
+			Message: fmt.Sprintf("%q is not a pod", id),
+		}
+	}
+	return pod, nil
+}
+
+// stream copies the response to a GET of location to w with Transfer-Encoding:
+// chunked, flushing as data arrives, until either side closes the connection.
+func (s *PodLogStreamer) stream(location string, w http.ResponseWriter, req *http.Request) {
+	loggedW := httplog.LogOf(req, w)
+	w = httplog.Unlogged(w)
+
+	cn, ok := w.(http.CloseNotifier)
+	if !ok {
+		loggedW.Addf("unable to get CloseNotifier")
+		http.NotFound(w, req)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		loggedW.Addf("unable to get Flusher")
+		http.NotFound(w, req)
+		return
+	}
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Get(location)
+	if err != nil {
+		loggedW.Addf("failed to reach kubelet: %v", err)
+		badGatewayError(w, req)
+		return
+	}
+	defer resp.Body.Close()
+
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "text/plain"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Transfer-Encoding", "chunked")
+	w.WriteHeader(resp.StatusCode)
+	flusher.Flush()
+
+	closed := cn.CloseNotify()
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-closed:
+			// Unblocks the read below, if the kubelet has nothing to send.
+			resp.Body.Close()
+		case <-done:
+		}
+	}()
+	buf := make([]byte, 4096)
+	for {
+		n, err := resp.Body.Read(buf)
+		if n > 0 {
+			if _, err := w.Write(buf[:n]); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			loggedW.Addf("log stream ended: %v", err)
+			return
+		}
+	}
+}
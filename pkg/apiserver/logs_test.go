@@ -0,0 +1,155 @@
+package apiserver
+
+import (
+	"bufio"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/ryutah/kubernetes-transcribe/pkg/api"
+)
+
+// logsServer serves the pods of storage, with logs read from a kubelet on kubeletURL.
+func logsServer(t *testing.T, storage RESTStorage, kubeletURL string) *httptest.Server {
+	u, err := url.Parse(kubeletURL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, port, _ := net.SplitHostPort(u.Host)
+	kubeletPort, _ := strconv.Atoi(port)
+	group := NewAPIGroup(map[string]RESTStorage{"pods": storage}, codec)
+	group.SetPodLogStreamer(&PodLogStreamer{Port: kubeletPort})
+	mux := http.NewServeMux()
+	group.InstallREST(mux, "/prefix/version")
+	return httptest.NewServer(RecoverPanics(mux))
+}
+
+func TestLogsStream(t *testing.T) {
+	requests := make(chan *http.Request, 1)
+	release := make(chan struct{})
+	kubelet := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests <- req
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("line 1\n"))
+		w.(http.Flusher).Flush()
+		<-release
+		w.Write([]byte("line 2\n"))
+	}))
+	defer kubelet.Close()
+	server := logsServer(t, &SimpleRESTStorage{pod: podOn("127.0.0.1", 0)}, kubelet.URL)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/prefix/version/pods/foo/log?follow=true&tailLines=10")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	if e, a := http.StatusOK, resp.StatusCode; e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+	if e, a := "text/plain", resp.Header.Get("Content-Type"); e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+	// The first line must arrive while the kubelet is still writing.
+	reader := bufio.NewReader(resp.Body)
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e, a := "line 1\n", line; e != a {
+		t.Errorf("expected %q, got %q", e, a)
+	}
+	close(release)
+	rest, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e, a := "line 2\n", string(rest); e != a {
+		t.Errorf("expected %q, got %q", e, a)
+	}
+
+	req := <-requests
+	if e, a := "/containerLogs/foo/c1", req.URL.Path; e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+	if e, a := (url.Values{"follow": {"true"}, "tail": {"10"}}).Encode(), req.URL.RawQuery; e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+}
+
+func TestLogsParams(t *testing.T) {
+	queries := make(chan string, 1)
+	kubelet := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		queries <- req.URL.RawQuery
+	}))
+	defer kubelet.Close()
+	server := logsServer(t, &SimpleRESTStorage{pod: podOn("127.0.0.1", 0)}, kubelet.URL)
+	defer server.Close()
+
+	table := map[string]string{
+		"":                "",
+		"?follow=false":   "",
+		"?follow=true":    "follow=true",
+		"?tailLines=5":    "tail=5",
+		"?container=c1":   "",
+		"?tailLines=&x=1": "",
+	}
+	for query, expected := range table {
+		resp, err := http.Get(server.URL + "/prefix/version/pods/foo/log" + query)
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %v", query, err)
+		}
+		resp.Body.Close()
+		if e, a := http.StatusOK, resp.StatusCode; e != a {
+			t.Errorf("%q: expected %v, got %v", query, e, a)
+			continue
+		}
+		if a := <-queries; expected != a {
+			t.Errorf("%q: expected %q, got %q", query, expected, a)
+		}
+	}
+}
+
+func TestLogsErrors(t *testing.T) {
+	kubelet := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		http.NotFound(w, req)
+	}))
+	defer kubelet.Close()
+	// Nothing listens on the port of a closed server.
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachable.Close()
+
+	twoContainers := podOn("127.0.0.1", 0)
+	twoContainers.DesiredState.Manifest.Containers = append(twoContainers.DesiredState.Manifest.Containers, api.Container{Name: "c2"})
+	table := map[string]struct {
+		pod        *api.Pod
+		kubeletURL string
+		path       string
+		expectCode int
+	}{
+		"no such pod":         {podOn("127.0.0.1", 0), kubelet.URL, "/pods/bar/log", http.StatusNotFound},
+		"no such container":   {podOn("127.0.0.1", 0), kubelet.URL, "/pods/foo/log?container=c2", http.StatusBadRequest},
+		"container not given": {twoContainers, kubelet.URL, "/pods/foo/log", http.StatusBadRequest},
+		"not running":         {podOn("", 0), kubelet.URL, "/pods/foo/log", http.StatusBadRequest},
+		"bad tailLines":       {podOn("127.0.0.1", 0), kubelet.URL, "/pods/foo/log?tailLines=-1", http.StatusBadRequest},
+		"kubelet error":       {podOn("127.0.0.1", 0), kubelet.URL, "/pods/foo/log", http.StatusNotFound},
+		// badGatewayError reports a bad request.
+		"kubelet unreachable": {podOn("127.0.0.1", 0), unreachable.URL, "/pods/foo/log", http.StatusBadRequest},
+	}
+	for name, item := range table {
+		server := logsServer(t, &SimpleRESTStorage{pod: item.pod}, item.kubeletURL)
+		resp, err := http.Get(server.URL + "/prefix/version" + item.path)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		resp.Body.Close()
+		server.Close()
+		if e, a := item.expectCode, resp.StatusCode; e != a {
+			t.Errorf("%s: expected %v, got %v", name, e, a)
+		}
+	}
+}
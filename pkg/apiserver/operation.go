@@ -16,6 +16,8 @@ import (
 type OperationHandler struct {
 	ops   *Operations
 	codec runtime.Codec
+	// protobufCodec, if not nil, is used when the client prefers protobuf.
+	protobufCodec runtime.Codec
 }
 
 func (o *OperationHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
@@ -24,10 +26,12 @@ func (o *OperationHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		notFound(w, req)
 		return
 	}
+	codec := negotiateCodec(req, o.codec, o.protobufCodec)
 	if len(parts) == 0 {
 		// List outstanding operations.
 		list := o.ops.List()
-		writeJSON(http.StatusOK, o.codec, list, w)
+		writeJSON(http.StatusOK, codec, list, w)
+		return
 	}
 
 	op := o.ops.Get(parts[0])
@@ -38,9 +42,9 @@ func (o *OperationHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 
 	obj, complete := op.StatusOrResult()
 	if complete {
-		writeJSON(http.StatusOK, o.codec, obj, w)
+		writeJSON(http.StatusOK, codec, obj, w)
 	} else {
-		writeJSON(http.StatusAccepted, o.codec, obj, w)
+		writeJSON(http.StatusAccepted, codec, obj, w)
 	}
 }
 
@@ -73,7 +77,8 @@ func NewOperations() *Operations {
 	return ops
 }
 
-// NewOperation adds a new operation. It is lock-free.
+// NewOperation adds a new operation. It is registered before it is returned, so
+// that List, Get and WaitForPending see it at once.
 func (ops *Operations) NewOperation(from <-chan runtime.Object) *Operation {
 	id := atomic.AddInt64(&ops.lastID, 1)
 	op := &Operation{
@@ -82,7 +87,7 @@ func (ops *Operations) NewOperation(from <-chan runtime.Object) *Operation {
 		noftify:  make(chan struct{}),
 	}
 	go op.wait()
-	// XXX
+	ops.insert(op)
 	return op
 }
 
@@ -117,6 +122,42 @@ func (ops *Operations) Get(id string) *Operation {
 	return ops.ops[id]
 }
 
+// Pending returns the number of operations that have not finished yet.
+func (ops *Operations) Pending() int {
+	ops.lock.Lock()
+	defer ops.lock.Unlock()
+	pending := 0
+	for _, op := range ops.ops {
+		if !op.done() {
+			pending++
+		}
+	}
+	return pending
+}
+
+// WaitForPending waits until every operation that was still running when it was
+// called has finished, or until timeout elapses. Returns true if all of them
+// finished in time.
+func (ops *Operations) WaitForPending(timeout time.Duration) bool {
+	ops.lock.Lock()
+	all := make([]*Operation, 0, len(ops.ops))
+	for _, op := range ops.ops {
+		all = append(all, op)
+	}
+	ops.lock.Unlock()
+
+	// Finished operations have already closed their notify channel.
+	deadline := time.After(timeout)
+	for _, op := range all {
+		select {
+		case <-op.noftify:
+		case <-deadline:
+			return false
+		}
+	}
+	return true
+}
+
 // expire garbage collect operations that have finished longer than maxAge ago.
 func (ops *Operations) expire(maxAge time.Duration) {
 	ops.lock.Lock()
@@ -157,6 +198,13 @@ func (op *Operation) WaitFor(timeout time.Duration) {
 	}
 }
 
+// done returns true if the operation has finished.
+func (op *Operation) done() bool {
+	op.lock.Lock()
+	defer op.lock.Unlock()
+	return op.finished != nil
+}
+
 // expired returns true if this operation finished before limitTime.
 func (op *Operation) expired(limitTime time.Time) bool {
 	op.lock.Lock()
@@ -0,0 +1,32 @@
+package apiserver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ryutah/kubernetes-transcribe/pkg/api"
+	"github.com/ryutah/kubernetes-transcribe/pkg/runtime"
+)
+
+func TestNewOperationIsRegistered(t *testing.T) {
+	ops := NewOperations()
+	result := make(chan runtime.Object)
+	op := ops.NewOperation(result)
+	if ops.Get(op.ID) != op {
+		t.Errorf("expected operation %s to be registered", op.ID)
+	}
+	if e, a := 1, ops.Pending(); e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+	if ops.WaitForPending(10 * time.Millisecond) {
+		t.Errorf("expected operation %s to be waited for", op.ID)
+	}
+
+	result <- &api.Status{Status: api.StatusSuccess}
+	if !ops.WaitForPending(time.Minute) {
+		t.Errorf("expected operation %s to finish", op.ID)
+	}
+	if e, a := 0, ops.Pending(); e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+}
@@ -0,0 +1,88 @@
+package apiserver
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/websocket"
+
+	"github.com/ryutah/kubernetes-transcribe/pkg/api"
+	"github.com/ryutah/kubernetes-transcribe/pkg/httplog"
+	"github.com/ryutah/kubernetes-transcribe/pkg/runtime"
+	"github.com/ryutah/kubernetes-transcribe/pkg/util/wsstream"
+)
+
+// PodPortForwarder serves GET /pods/<id>/portforward?port=<port>, which must be a
+// websocket request. The websocket is tunneled to a TCP connection to the given
+// port of the pod's IP; see wsstream.Tunnel. port is either the number or the name
+// of a TCP port declared by one of the pod's containers. Each forwarded connection
+// takes a websocket of its own.
+type PodPortForwarder struct {
+	// Dial opens connections to pods. If nil, net.Dial is used.
+	Dial func(network, address string) (net.Conn, error)
+}
+
+// ServePortForward tunnels the websocket request req to the port it asks for, of
+// the pod with the given ID read from storage.
+func (f *PodPortForwarder) ServePortForward(id string, storage RESTStorage, w http.ResponseWriter, req *http.Request, codec runtime.Codec) {
+	if !isWebSocketRequest(req) {
+		status := badRequest("portforward requires a websocket")
+		writeJSON(status.Code, codec, status, w)
+		return
+	}
+	pod, status := getPod(storage, id)
+	if status != nil {
+		writeJSON(status.Code, codec, status, w)
+		return
+	}
+	port, status := podPort(pod, req.URL.Query().Get("port"))
+	if status != nil {
+		writeJSON(status.Code, codec, status, w)
+		return
+	}
+	if pod.CurrentState.PodIP == "" {
+		status := badRequest("pod %q has no IP", id)
+		writeJSON(status.Code, codec, status, w)
+		return
+	}
+	dial := f.Dial
+	if dial == nil {
+		dial = net.Dial
+	}
+	conn, err := dial("tcp", net.JoinHostPort(pod.CurrentState.PodIP, strconv.Itoa(port)))
+	if err != nil {
+		httplog.LogOf(req, w).Addf("failed to reach pod: %v", err)
+		badGatewayError(w, req)
+		return
+	}
+	tunneled := false
+	websocket.Handler(func(ws *websocket.Conn) {
+		tunneled = true
+		wsstream.Tunnel(ws, conn)
+	}).ServeHTTP(httplog.Unlogged(w), req)
+	if !tunneled {
+		// The client's handshake failed.
+		conn.Close()
+	}
+}
+
+// podPort returns the number of the TCP port of pod named by port, which is either
+// a number or a name.
+func podPort(pod *api.Pod, port string) (int, *api.Status) {
+	if port == "" {
+		return 0, badRequest("portforward requires a port")
+	}
+	for _, container := range pod.DesiredState.Manifest.Containers {
+		for _, p := range container.Ports {
+			if p.Protocol != "" && strings.ToUpper(p.Protocol) != "TCP" {
+				continue
+			}
+			if p.Name == port || strconv.Itoa(p.ContainerPort) == port {
+				return p.ContainerPort, nil
+			}
+		}
+	}
+	return 0, badRequest("pod %q has no TCP port %s", pod.ID, port)
+}
@@ -0,0 +1,151 @@
+package apiserver
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"golang.org/x/net/websocket"
+)
+
+// echoServer accepts TCP connections on a local port, and writes back everything
+// read from them.
+func echoServer(t *testing.T) net.Listener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return listener
+}
+
+// closeRecorder is a net.Conn recording whether it was closed.
+type closeRecorder struct {
+	net.Conn
+	lock   sync.Mutex
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.closed = true
+	return c.Conn.Close()
+}
+
+func (c *closeRecorder) isClosed() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.closed
+}
+
+func portForwardServer(storage RESTStorage, forwarder *PodPortForwarder) *httptest.Server {
+	group := NewAPIGroup(map[string]RESTStorage{"pods": storage}, codec)
+	group.SetPodPortForwarder(forwarder)
+	mux := http.NewServeMux()
+	group.InstallREST(mux, "/prefix/version")
+	return httptest.NewServer(RecoverPanics(mux))
+}
+
+func TestPortForwardTunnels(t *testing.T) {
+	echo := echoServer(t)
+	defer echo.Close()
+	port := echo.Addr().(*net.TCPAddr).Port
+	server := portForwardServer(&SimpleRESTStorage{pod: podOn("127.0.0.1", port)}, &PodPortForwarder{})
+	defer server.Close()
+
+	// By name, and by number.
+	for _, query := range []string{"port=http", "port=" + strconv.Itoa(port)} {
+		ws, err := websocket.Dial("ws://"+server.Listener.Addr().String()+"/prefix/version/pods/foo/portforward?"+query, "", "http://localhost/")
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", query, err)
+		}
+		if _, err := ws.Write([]byte("hello")); err != nil {
+			t.Fatalf("%s: unexpected error: %v", query, err)
+		}
+		reply := make([]byte, 5)
+		if _, err := io.ReadFull(ws, reply); err != nil {
+			t.Fatalf("%s: unexpected error: %v", query, err)
+		}
+		if e, a := "hello", string(reply); e != a {
+			t.Errorf("%s: expected %v, got %v", query, e, a)
+		}
+		ws.Close()
+	}
+}
+
+func TestPortForwardFailedHandshakeClosesConn(t *testing.T) {
+	echo := echoServer(t)
+	defer echo.Close()
+	var conns []*closeRecorder
+	forwarder := &PodPortForwarder{Dial: func(network, address string) (net.Conn, error) {
+		conn, err := net.Dial(network, address)
+		if err != nil {
+			return nil, err
+		}
+		recorder := &closeRecorder{Conn: conn}
+		conns = append(conns, recorder)
+		return recorder, nil
+	}}
+	server := portForwardServer(&SimpleRESTStorage{pod: podOn("127.0.0.1", echo.Addr().(*net.TCPAddr).Port)}, forwarder)
+	defer server.Close()
+
+	req, _ := http.NewRequest("GET", server.URL+"/prefix/version/pods/foo/portforward?port=http", nil)
+	// An upgrade, without the rest of a websocket handshake.
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if len(conns) != 1 || !conns[0].isClosed() {
+		t.Errorf("expected the connection to the pod to be closed")
+	}
+}
+
+func TestPortForwardErrors(t *testing.T) {
+	server := portForwardServer(&SimpleRESTStorage{pod: podOn("", 80)}, &PodPortForwarder{})
+	defer server.Close()
+	table := map[string]struct {
+		path       string
+		websocket  bool
+		expectCode int
+	}{
+		"not a websocket": {"/pods/foo/portforward?port=80", false, http.StatusBadRequest},
+		"no such pod":     {"/pods/bar/portforward?port=80", true, http.StatusNotFound},
+		"no port":         {"/pods/foo/portforward", true, http.StatusBadRequest},
+		"unknown port":    {"/pods/foo/portforward?port=81", true, http.StatusBadRequest},
+		"no pod IP":       {"/pods/foo/portforward?port=80", true, http.StatusBadRequest},
+	}
+	for name, item := range table {
+		req, _ := http.NewRequest("GET", server.URL+"/prefix/version"+item.path, nil)
+		if item.websocket {
+			req.Header.Set("Connection", "Upgrade")
+			req.Header.Set("Upgrade", "websocket")
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		resp.Body.Close()
+		if e, a := item.expectCode, resp.StatusCode; e != a {
+			t.Errorf("%s: expected %v, got %v", name, e, a)
+		}
+	}
+}
@@ -2,13 +2,18 @@ package apiserver
 
 import (
 	"bytes"
+	"compress/gzip"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"mime"
+	"net"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
 	"path"
 	"strings"
+	"time"
 
 	"golang.org/x/net/html"
 
@@ -51,12 +56,60 @@ var tagsToAttrs = map[string]util.StringSet{
 	// TODO: css URLs hidden in style elements.
 }
 
+// hopByHopHeaders are meaningful only for a single connection, and are not passed
+// on by proxies. See RFC 2616, section 13.5.1.
+var hopByHopHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailers",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// credentialHeaders carry the credentials of clients of the apiserver, which must
+// not be disclosed to the services it proxies to.
+var credentialHeaders = []string{
+	"Authorization",
+	"Cookie",
+}
+
+// ProxyConfig bounds the requests made by a ProxyHandler.
+type ProxyConfig struct {
+	// Timeout bounds how long connecting to a destination and waiting for its
+	// response headers may take. 0 means no limit.
+	Timeout time.Duration
+	// MaxBodySize is the largest request or response body, in bytes, passed through
+	// the proxy. 0 means no limit.
+	MaxBodySize int64
+}
+
 // ProxyHandler provides a http.Handler which will proxy traffix to locations
 // specified by items implementing Redirector.
 type ProxyHandler struct {
-	prefix  string
-	storage map[string]RESTStorage
-	codec   runtime.Codec
+	prefix    string
+	storage   map[string]RESTStorage
+	codec     runtime.Codec
+	config    ProxyConfig
+	transport http.RoundTripper
+}
+
+// newProxyHandler returns a ProxyHandler serving the resources in storage under prefix.
+func newProxyHandler(prefix string, storage map[string]RESTStorage, codec runtime.Codec, config ProxyConfig) *ProxyHandler {
+	dialer := &net.Dialer{Timeout: config.Timeout}
+	return &ProxyHandler{
+		prefix:  prefix,
+		storage: storage,
+		codec:   codec,
+		config:  config,
+		transport: &http.Transport{
+			Proxy:                 http.ProxyFromEnvironment,
+			Dial:                  dialer.Dial,
+			ResponseHeaderTimeout: config.Timeout,
+		},
+	}
 }
 
 func (p *ProxyHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
@@ -100,11 +153,23 @@ func (p *ProxyHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	}
 	destURL.Path = rest
 	destURL.RawQuery = req.URL.RawQuery
-	newReq, err := http.NewRequest(req.Method, destURL.String(), req.Body)
+	body := req.Body
+	if p.config.MaxBodySize > 0 {
+		body = http.MaxBytesReader(w, req.Body, p.config.MaxBodySize)
+	}
+	newReq, err := http.NewRequest(req.Method, destURL.String(), body)
 	if err != nil {
 		glog.Errorf("Failed to create request: %s", err)
+		errorJSON(err, p.codec, w)
+		return
+	}
+	newReq.Header = proxyHeader(req.Header)
+	newReq.ContentLength = req.ContentLength
+
+	if isUpgradeRequest(req) {
+		p.proxyUpgrade(w, req, newReq)
+		return
 	}
-	newReq.Header = req.Header
 
 	proxy := httputil.NewSingleHostReverseProxy(&url.URL{Scheme: "http", Host: destURL.Host})
 
@@ -112,18 +177,99 @@ func (p *ProxyHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		proxyScheme:      req.URL.Scheme,
 		proxyHost:        req.URL.Host,
 		proxyPathPrepend: path.Join(p.prefix, resourceName, id),
+		transport:        p.transport,
+		maxBodySize:      p.config.MaxBodySize,
 	}
 	proxy.ServeHTTP(w, newReq)
 }
 
+// proxyHeader returns a copy of header without hop-by-hop headers, including those
+// listed in its Connection header, nor the client's credentials.
+func proxyHeader(header http.Header) http.Header {
+	out := http.Header{}
+	for key, values := range header {
+		out[key] = append([]string{}, values...)
+	}
+	for _, connection := range header["Connection"] {
+		for _, key := range strings.Split(connection, ",") {
+			if key = strings.TrimSpace(key); key != "" {
+				out.Del(key)
+			}
+		}
+	}
+	for _, key := range hopByHopHeaders {
+		out.Del(key)
+	}
+	for _, key := range credentialHeaders {
+		out.Del(key)
+	}
+	return out
+}
+
+// isUpgradeRequest returns true if req asks to switch protocols, as websockets do.
+func isUpgradeRequest(req *http.Request) bool {
+	return connectionUpgradeRegex.MatchString(strings.ToLower(req.Header.Get("Connection"))) && req.Header.Get("Upgrade") != ""
+}
+
+// proxyUpgrade passes req, which asks to switch protocols, on to its destination as
+// newReq, then joins the client's connection to the destination's, in both
+// directions, until either is closed.
+func (p *ProxyHandler) proxyUpgrade(w http.ResponseWriter, req, newReq *http.Request) {
+	hijacker, ok := httplog.Unlogged(w).(http.Hijacker)
+	if !ok {
+		httplog.LogOf(req, w).Addf("unable to get Hijacker")
+		notFound(w, req)
+		return
+	}
+	host := newReq.URL.Host
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(host, "80")
+	}
+	backend, err := net.DialTimeout("tcp", host, p.config.Timeout)
+	if err != nil {
+		httplog.LogOf(req, w).Addf("failed to reach %v: %v", host, err)
+		badGatewayError(w, req)
+		return
+	}
+	defer backend.Close()
+	// The upgrade itself is passed on; credentials are not.
+	newReq.Header.Set("Connection", "Upgrade")
+	newReq.Header.Set("Upgrade", req.Header.Get("Upgrade"))
+	if err := newReq.Write(backend); err != nil {
+		httplog.LogOf(req, w).Addf("failed to send upgrade request: %v", err)
+		badGatewayError(w, req)
+		return
+	}
+	client, buffered, err := hijacker.Hijack()
+	if err != nil {
+		glog.Errorf("Failed to hijack connection: %v", err)
+		return
+	}
+	defer client.Close()
+
+	done := make(chan struct{}, 2)
+	go func() {
+		// Anything the client sent past the request was buffered by the server.
+		io.Copy(backend, buffered)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(client, backend)
+		done <- struct{}{}
+	}()
+	<-done
+}
+
 type proxyTransport struct {
 	proxyScheme      string
 	proxyHost        string
 	proxyPathPrepend string
+	transport        http.RoundTripper
+	maxBodySize      int64
 }
 
 func (p *proxyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
-	resp, err := http.DefaultTransport.RoundTrip(req)
+	resp, err := p.transport.RoundTrip(req)
 	if err != nil {
 		message := fmt.Sprintf("Error: '%s'\nTrying to reach: '%v'", err.Error(), req.URL.String())
 		resp = &http.Response{
@@ -132,15 +278,66 @@ func (p *proxyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 		}
 		return resp, nil
 	}
+	if p.maxBodySize > 0 {
+		resp.Body = &limitedBody{resp.Body, p.maxBodySize}
+	}
 
-	if resp.Header.Get("Content-Type") != "text/html" {
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || mediaType != "text/html" {
 		// DO nothing, simply pass through
 		return resp, nil
 	}
+	switch resp.Header.Get("Content-Encoding") {
+	case "":
+	case "gzip":
+		gzipReader, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+		// The rewritten page is sent uncompressed, and must fit the limit as such.
+		resp.Body = &readCloser{gzipReader, resp.Body}
+		if p.maxBodySize > 0 {
+			resp.Body = &limitedBody{resp.Body, p.maxBodySize}
+		}
+		resp.Header.Del("Content-Encoding")
+	default:
+		// Can't be rewritten.
+		return resp, nil
+	}
 
 	return p.fixLinks(req, resp)
 }
 
+// limitedBody is a response body which fails once more than limit bytes are read.
+type limitedBody struct {
+	io.ReadCloser
+	limit int64
+}
+
+func (b *limitedBody) Read(data []byte) (int, error) {
+	if b.limit <= 0 {
+		// A body of exactly limit bytes is fine.
+		var probe [1]byte
+		if n, err := b.ReadCloser.Read(probe[:]); n == 0 {
+			return 0, err
+		}
+		return 0, fmt.Errorf("response body larger than the proxy allows")
+	}
+	if int64(len(data)) > b.limit {
+		data = data[:b.limit]
+	}
+	n, err := b.ReadCloser.Read(data)
+	b.limit -= int64(n)
+	return n, err
+}
+
+// readCloser reads from a Reader, and closes a Closer.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
 // updateURLs checks and updates any of n's attributes that are listed in tagsToAttrs.
 // Any URLs found are, if they're relative, updated with the necessary changes to make
 // a visit to that URL also go through the proxy.
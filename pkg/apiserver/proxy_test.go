@@ -0,0 +1,93 @@
+package apiserver
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/websocket"
+)
+
+func TestProxyUpgrade(t *testing.T) {
+	backend := httptest.NewServer(websocket.Handler(func(ws *websocket.Conn) {
+		defer ws.Close()
+		var message string
+		if err := websocket.Message.Receive(ws, &message); err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		websocket.Message.Send(ws, "echo "+message)
+	}))
+	defer backend.Close()
+
+	storage := map[string]RESTStorage{"pods": &SimpleRESTStorage{location: backend.URL}}
+	server := httptest.NewServer(RecoverPanics(Handle(storage, codec, "/prefix/version")))
+	defer server.Close()
+
+	ws, err := websocket.Dial("ws://"+server.Listener.Addr().String()+"/prefix/version/proxy/pods/foo/", "", "http://localhost/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer ws.Close()
+	if err := websocket.Message.Send(ws, "hello"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var reply string
+	if err := websocket.Message.Receive(ws, &reply); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e, a := "echo hello", reply; e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+}
+
+func TestProxyGzipBodyLimit(t *testing.T) {
+	table := map[string]struct {
+		page       string
+		expectCode int
+	}{
+		"small": {"<html><body><a href=\"/foo\">foo</a></body></html>", http.StatusOK},
+		// Compresses to much less than the limit.
+		"bomb": {"<html><body>" + strings.Repeat("a", 1<<20) + "</body></html>", http.StatusBadGateway},
+	}
+	for name, item := range table {
+		compressed := &bytes.Buffer{}
+		gz := gzip.NewWriter(compressed)
+		gz.Write([]byte(item.page))
+		gz.Close()
+		if compressed.Len() >= 4096 {
+			t.Fatalf("%s: page compressed to %d bytes", name, compressed.Len())
+		}
+		backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("Content-Type", "text/html")
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Write(compressed.Bytes())
+		}))
+
+		storage := map[string]RESTStorage{"pods": &SimpleRESTStorage{location: backend.URL}}
+		group := NewAPIGroup(storage, codec)
+		group.SetProxyConfig(ProxyConfig{MaxBodySize: 4096})
+		mux := http.NewServeMux()
+		group.InstallREST(mux, "/prefix/version")
+		server := httptest.NewServer(mux)
+
+		resp, err := http.Get(server.URL + "/prefix/version/proxy/pods/foo/")
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if e, a := item.expectCode, resp.StatusCode; e != a {
+			t.Errorf("%s: expected %v, got %v", name, e, a)
+		}
+		if item.expectCode == http.StatusOK && !strings.Contains(string(body), "/prefix/version/proxy/pods/foo/foo") {
+			t.Errorf("%s: expected rewritten link, got %s", name, body)
+		}
+		server.Close()
+		backend.Close()
+	}
+}
@@ -1,13 +1,20 @@
 package apiserver
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"reflect"
 	"time"
 
 	"github.com/ryutah/kubernetes-transcribe/pkg/api"
+	"github.com/ryutah/kubernetes-transcribe/pkg/api/errors"
+	"github.com/ryutah/kubernetes-transcribe/pkg/audit"
 	"github.com/ryutah/kubernetes-transcribe/pkg/httplog"
 	"github.com/ryutah/kubernetes-transcribe/pkg/labels"
 	"github.com/ryutah/kubernetes-transcribe/pkg/runtime"
+	"github.com/ryutah/kubernetes-transcribe/pkg/util"
 )
 
 type RESTHandler struct {
@@ -15,6 +22,17 @@ type RESTHandler struct {
 	codec       runtime.Codec
 	ops         *Operations
 	asyncOpWait time.Duration
+
+	// auditor, if set, records every mutating request.
+	auditor *audit.Auditor
+	// protobufCodec, if not nil, reads and writes protobuf for clients asking for it.
+	protobufCodec runtime.Codec
+	// logs, if not nil, serves the logs of pods.
+	logs *PodLogStreamer
+	// exec, if not nil, serves exec and attach requests for pods.
+	exec *PodExecProxy
+	// portForward, if not nil, forwards connections to the ports of pods.
+	portForward *PodPortForwarder
 }
 
 // ServeHTTP handles requests to all RESTStorage objects.
@@ -31,122 +49,340 @@ func (r *RESTHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	// XXX
+	// A diff is a POST, but it changes nothing, so it isn't audited.
+	isDiff := req.Method == "POST" && len(parts) == 3 && parts[2] == "diff"
+	if r.auditor != nil && audit.IsMutating(req.Method) && !isDiff {
+		id := ""
+		if len(parts) > 1 {
+			id = parts[1]
+		}
+		defer r.auditor.NewAudited(req, &w, parts[0], id).Log()
+	}
+	r.handleRESTStorage(parts, req, w, storage, negotiateCodec(req, r.codec, r.protobufCodec))
 }
 
 // handleRESTStorage is the main dispatcher for a storage object.  It switches on the HTTP method, and then
 // on path length, according to the following table:
-//   Method     Path          Action
-//   GET        /foo          list
-//   GET        /foo/bar      get 'bar'
-//   POST       /foo          create
-//   PUT        /foo/bar      update 'bar'
-//   DELETE     /foo/bar      delete 'bar'
+//   Method     Path                   Action
+//   GET        /foo                   list
+//   GET        /foo/bar               get 'bar'
+//   POST       /foo                   create
+//   PUT        /foo/bar               update 'bar'
+//   DELETE     /foo/bar               delete 'bar'
+//   POST       /foo/bar/diff          diff the body against 'bar'
+//   GET        /pods/bar/log          stream the logs of pod 'bar'
+//   GET        /pods/bar/exec         run a command in pod 'bar', over a websocket
+//   GET        /pods/bar/attach       attach to pod 'bar', over a websocket
+//   GET        /pods/bar/portforward  forward a connection to a port of pod 'bar', over a websocket
 // Returns 404 if the method/pattern doesn't match one of these entries
 // The s accepts several query parameters:
 //    sync=[false|true] Synchronous request (only applies to create, update, delete operations)
 //    timeout=<duration> Timeout for Synchronous requests, only applies if sync=true
 //    labels=<label-selector> Used for filtering list operations
-func (r *RESTHandler) handleRESTStorage(parts []string, req *http.Request, w http.ResponseWriter, storage RESTStorage) {
+//    dryRun=[false|true] Check create, update and delete operations, and return the result
+//        they would have, without carrying them out
+// Responses are written with codec, which has been chosen from the Accept header.
+func (r *RESTHandler) handleRESTStorage(parts []string, req *http.Request, w http.ResponseWriter, storage RESTStorage, codec runtime.Codec) {
 	sync := req.URL.Query().Get("sync") == "true"
 	timeout := parseTimeout(req.URL.Query().Get("timeout"))
+	dryRun := req.URL.Query().Get("dryRun") == "true"
 	switch req.Method {
 	case "GET":
+		if len(parts) == 3 && parts[2] == "log" && r.logs != nil {
+			r.logs.ServeLogs(parts[1], storage, w, req, codec)
+			return
+		}
+		if len(parts) == 3 && (parts[2] == "exec" || parts[2] == "attach") && r.exec != nil {
+			r.exec.ServeExec(parts[2], parts[1], storage, w, req, codec)
+			return
+		}
+		if len(parts) == 3 && parts[2] == "portforward" && r.portForward != nil {
+			r.portForward.ServePortForward(parts[1], storage, w, req, codec)
+			return
+		}
 		switch len(parts) {
 		case 1:
 			label, err := labels.ParseSelector(req.URL.Query().Get("labels"))
 			if err != nil {
-				errorJSON(err, r.codec, w)
+				errorJSON(err, codec, w)
 				return
 			}
 			field, err := labels.ParseSelector(req.URL.Query().Get("fields"))
 			if err != nil {
-				errorJSON(err, r.codec, w)
+				errorJSON(err, codec, w)
 				return
 			}
 			list, err := storage.List(label, field)
 			if err != nil {
-				errorJSON(err, r.codec, w)
+				errorJSON(err, codec, w)
+				return
+			}
+			if notModified(req, w, list) {
 				return
 			}
-			writeJSON(http.StatusOK, r.codec, list, w)
+			writeJSON(http.StatusOK, codec, list, w)
 		case 2:
 			item, err := storage.Get(parts[1])
 			if err != nil {
-				errorJSON(err, r.codec, w)
+				errorJSON(err, codec, w)
 				return
 			}
-			writeJSON(http.StatusOK, r.codec, item, w)
+			if notModified(req, w, item) {
+				return
+			}
+			writeJSON(http.StatusOK, codec, item, w)
 		default:
 			notFound(w, req)
 		}
 
 	case "POST":
+		if len(parts) == 3 && parts[2] == "diff" {
+			r.diff(parts[1], req, w, storage, codec)
+			return
+		}
 		if len(parts) != 1 {
 			notFound(w, req)
 			return
 		}
-		body, err := readBody(req)
+		obj := storage.New()
+		err := r.decodeBody(req, obj)
 		if err != nil {
-			errorJSON(err, r.codec, w)
+			errorJSON(err, codec, w)
 			return
 		}
-		obj := storage.New()
-		err = r.codec.DecodeInto(body, obj)
-		if err != nil {
-			errorJSON(err, r.codec, w)
+		if dryRun {
+			r.dryRun(parts[0], storage, w, codec, func(d DryRunner) (runtime.Object, error) { return d.DryRunCreate(obj) })
 			return
 		}
 		out, err := storage.Create(obj)
 		if err != nil {
-			errorJSON(err, r.codec, w)
+			errorJSON(err, codec, w)
 			return
 		}
 		op := r.createOperation(out, sync, timeout)
-		r.finishReq(op, req, w)
+		r.finishReq(op, false, w, codec)
 
 	case "DELETE":
 		if len(parts) != 2 {
 			notFound(w, req)
 			return
 		}
-		out, err := storage.Delete(parts[1])
+		options, err := r.deleteOptions(req)
 		if err != nil {
-			errorJSON(err, r.codec, w)
+			errorJSON(err, codec, w)
 			return
 		}
-		op := r.createOperation(out, sync, timeout)
-		r.finishReq(op, req, w)
+		version, conditional, err := ifMatchVersion(req)
+		if err != nil {
+			preconditionFailed(err.Error(), codec, w)
+			return
+		}
+		if options.Preconditions != nil && options.Preconditions.ResourceVersion != 0 {
+			if conditional && version != options.Preconditions.ResourceVersion {
+				preconditionFailed("If-Match and preconditions.resourceVersion disagree", codec, w)
+				return
+			}
+			// A precondition in the body is honoured just like If-Match.
+			version, conditional = options.Preconditions.ResourceVersion, true
+		}
+		if dryRun {
+			r.dryRun(parts[0], storage, w, codec, func(d DryRunner) (runtime.Object, error) { return d.DryRunDelete(parts[1]) })
+			return
+		}
+		var out <-chan runtime.Object
+		if deleter, ok := storage.(GracefulDeleter); ok {
+			if conditional {
+				options.Preconditions = &api.Preconditions{ResourceVersion: version}
+			}
+			out, err = deleter.DeleteWithOptions(parts[1], options)
+		} else if conditional {
+			out, err = r.deleteIfVersion(parts[0], parts[1], version, storage)
+		} else {
+			out, err = storage.Delete(parts[1])
+		}
+		if err != nil {
+			if conditional && isConflict(err) {
+				preconditionFailed(err.Error(), codec, w)
+				return
+			}
+			errorJSON(err, codec, w)
+			return
+		}
+		// Wait for conditional requests, so that a failed condition can be reported.
+		op := r.createOperation(out, sync || conditional, timeout)
+		r.finishReq(op, conditional, w, codec)
 
 	case "PUT":
 		if len(parts) != 2 {
 			notFound(w, req)
 			return
 		}
-		body, err := readBody(req)
+		obj := storage.New()
+		err := r.decodeBody(req, obj)
 		if err != nil {
-			errorJSON(err, r.codec, w)
+			errorJSON(err, codec, w)
 			return
 		}
-		obj := storage.New()
-		err = r.codec.DecodeInto(body, obj)
+		version, conditional, err := ifMatchVersion(req)
 		if err != nil {
-			errorJSON(err, r.codec, w)
+			preconditionFailed(err.Error(), codec, w)
+			return
+		}
+		if conditional {
+			// The update is made atomically against the given version.
+			jsonBase, err := runtime.FindJSONBase(obj)
+			if err != nil {
+				errorJSON(err, codec, w)
+				return
+			}
+			if current := jsonBase.ResourceVersion(); current != 0 && current != version {
+				preconditionFailed(fmt.Sprintf("resourceVersion %d doesn't match If-Match", current), codec, w)
+				return
+			}
+			jsonBase.SetResourceVersion(version)
+		}
+		if dryRun {
+			r.dryRun(parts[0], storage, w, codec, func(d DryRunner) (runtime.Object, error) { return d.DryRunUpdate(obj) })
 			return
 		}
 		out, err := storage.Update(obj)
 		if err != nil {
-			errorJSON(err, r.codec, w)
+			if conditional && isConflict(err) {
+				preconditionFailed(err.Error(), codec, w)
+				return
+			}
+			errorJSON(err, codec, w)
 			return
 		}
-		op := r.createOperation(out, sync, timeout)
-		r.finishReq(op, req, w)
+		// Wait for conditional requests, so that a failed condition can be reported.
+		op := r.createOperation(out, sync || conditional, timeout)
+		r.finishReq(op, conditional, w, codec)
 
 	default:
 		notFound(w, req)
 	}
 }
 
+// serverSetFields lists fields of JSONBase which candidate objects are not expected to carry.
+var serverSetFields = util.NewStringSet("creationTimestamp", "selfLink", "resourceVersion", "deletionTimestamp")
+
+// diff writes the differences between the object in the body of req and the stored
+// object with the given ID as an api.Diff. Both are compared in the internal version.
+func (r *RESTHandler) diff(id string, req *http.Request, w http.ResponseWriter, storage RESTStorage, codec runtime.Codec) {
+	candidate := storage.New()
+	if err := r.decodeBody(req, candidate); err != nil {
+		errorJSON(err, codec, w)
+		return
+	}
+	if jsonBase, err := runtime.FindJSONBase(candidate); err == nil && jsonBase.ID() == "" {
+		jsonBase.SetID(id)
+	}
+	stored, err := storage.Get(id)
+	if err != nil {
+		errorJSON(err, codec, w)
+		return
+	}
+	diffs, err := runtime.FieldDiffs(stored, candidate)
+	if err != nil {
+		errorJSON(err, codec, w)
+		return
+	}
+	result := &api.Diff{JSONBase: api.JSONBase{ID: id}}
+	for _, diff := range diffs {
+		if serverSetFields.Has(diff.Path) && (diff.B == nil || reflect.ValueOf(diff.B).IsZero()) {
+			continue
+		}
+		change := api.FieldChange{Path: diff.Path}
+		if change.Old, err = diffValue(diff.A); err == nil {
+			change.New, err = diffValue(diff.B)
+		}
+		if err != nil {
+			errorJSON(err, codec, w)
+			return
+		}
+		result.Changes = append(result.Changes, change)
+	}
+	writeJSON(http.StatusOK, codec, result, w)
+}
+
+// diffValue returns the JSON encoding of a value in a runtime.FieldDiff.
+func diffValue(value interface{}) (string, error) {
+	if value == nil {
+		return "", nil
+	}
+	data, err := json.Marshal(value)
+	return string(data), err
+}
+
+// decodeBody decodes the body of req into obj, with the codec matching its Content-Type.
+func (r *RESTHandler) decodeBody(req *http.Request, obj runtime.Object) error {
+	body, err := readBody(req)
+	if err != nil {
+		return err
+	}
+	return r.decode(req, body, obj)
+}
+
+// decode decodes body, read from req, into obj, with the codec matching the
+// Content-Type of req.
+func (r *RESTHandler) decode(req *http.Request, body []byte, obj runtime.Object) error {
+	if isProtobufRequest(req) {
+		if r.protobufCodec == nil {
+			return fmt.Errorf("%s is not supported", protobufContentType)
+		}
+		return r.protobufCodec.DecodeInto(body, obj)
+	}
+	return r.codec.DecodeInto(body, obj)
+}
+
+// deleteOptions returns the api.DeleteOptions in the body of a DELETE request, or
+// empty options if its body is empty. The body is read to find out, since chunked
+// requests don't tell its length.
+func (r *RESTHandler) deleteOptions(req *http.Request) (*api.DeleteOptions, error) {
+	options := &api.DeleteOptions{}
+	body, err := readBody(req)
+	if err != nil || len(bytes.TrimSpace(body)) == 0 {
+		return options, err
+	}
+	return options, r.decode(req, body, options)
+}
+
+// dryRun answers a dryRun=true request with the result of run, if storage supports it.
+func (r *RESTHandler) dryRun(resource string, storage RESTStorage, w http.ResponseWriter, codec runtime.Codec, run func(DryRunner) (runtime.Object, error)) {
+	dryRunner, ok := storage.(DryRunner)
+	if !ok {
+		writeJSON(http.StatusBadRequest, codec, &api.Status{
+			Status:  api.StatusFailure,
+			Code:    http.StatusBadRequest,
+			Message: fmt.Sprintf("%s does not support dryRun", resource),
+		}, w)
+		return
+	}
+	out, err := run(dryRunner)
+	if err != nil {
+		errorJSON(err, codec, w)
+		return
+	}
+	writeJSON(http.StatusOK, codec, out, w)
+}
+
+// deleteIfVersion deletes the resource with the given ID, if it is at version. Storage
+// which doesn't implement ConditionalDeleter is checked with Get first, which unlike
+// ConditionalDeleter doesn't guard against a concurrent update.
+func (r *RESTHandler) deleteIfVersion(resource, id string, version uint64, storage RESTStorage) (<-chan runtime.Object, error) {
+	if deleter, ok := storage.(ConditionalDeleter); ok {
+		return deleter.DeleteIfVersion(id, version)
+	}
+	current, err := storage.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if etag := etagFor(current); etag != versionETag(version) {
+		return nil, errors.NewConflict(resource, id, fmt.Errorf("current ETag is %s", etag))
+	}
+	return storage.Delete(id)
+}
+
 // createOperation creates an operation to process a channel response.
 func (r *RESTHandler) createOperation(out <-chan runtime.Object, sync bool, timeout time.Duration) *Operation {
 	op := r.ops.NewOperation(out)
@@ -159,19 +395,27 @@ func (r *RESTHandler) createOperation(out <-chan runtime.Object, sync bool, time
 }
 
 // finishReq finishes up a request, waiting until the operation finishes or, after a timeout, creating an
-// Operation to receive the result and returning its ID down the writer.
-func (r *RESTHandler) finishReq(op *Operation, req *http.Request, w http.ResponseWriter) {
+// Operation to receive the result and returning its ID down the writer. A conflict is reported as a
+// failed precondition if the request was conditional.
+func (r *RESTHandler) finishReq(op *Operation, conditional bool, w http.ResponseWriter, codec runtime.Codec) {
+	audit.SetOperationID(w, op.ID)
 	obj, complete := op.StatusOrResult()
 	if complete {
 		status := http.StatusOK
 		switch stat := obj.(type) {
 		case *api.Status:
+			if stat.Code == http.StatusConflict && conditional {
+				// The precondition, enforced by the storage, didn't hold.
+				failed := *stat
+				failed.Code = http.StatusPreconditionFailed
+				stat, obj = &failed, &failed
+			}
 			if stat.Code != 0 {
 				status = stat.Code
 			}
 		}
-		writeJSON(status, r.codec, obj, w)
+		writeJSON(status, codec, obj, w)
 	} else {
-		writeJSON(http.StatusAccepted, r.codec, obj, w)
+		writeJSON(http.StatusAccepted, codec, obj, w)
 	}
 }
@@ -0,0 +1,296 @@
+package apiserver
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ryutah/kubernetes-transcribe/pkg/api"
+	"github.com/ryutah/kubernetes-transcribe/pkg/api/errors"
+	"github.com/ryutah/kubernetes-transcribe/pkg/audit"
+	"github.com/ryutah/kubernetes-transcribe/pkg/runtime"
+	"github.com/ryutah/kubernetes-transcribe/pkg/util"
+)
+
+// GracefulRESTStorage is a SimpleRESTStorage which records the options of deletions,
+// and fails them with a conflict, as etcd would, if their precondition doesn't hold.
+type GracefulRESTStorage struct {
+	SimpleRESTStorage
+	options *api.DeleteOptions
+}
+
+func (s *GracefulRESTStorage) DeleteWithOptions(id string, options *api.DeleteOptions) (<-chan runtime.Object, error) {
+	s.options = options
+	return MakeAsync(func() (runtime.Object, error) {
+		if p := options.Preconditions; p != nil && p.ResourceVersion != s.pod.ResourceVersion {
+			return nil, errors.NewConflict("pod", id, fmt.Errorf("resource version %d is stale", p.ResourceVersion))
+		}
+		return &api.Status{Status: api.StatusSuccess}, nil
+	}), nil
+}
+
+// onlyReader hides the type of a reader from http.NewRequest, so that the request
+// is sent chunked.
+type onlyReader struct {
+	io.Reader
+}
+
+func TestDeleteOptions(t *testing.T) {
+	thirty := int64(30)
+	table := map[string]struct {
+		body          io.Reader
+		ifMatch       string
+		expectCode    int
+		expectOptions *api.DeleteOptions
+	}{
+		"no body": {nil, "", http.StatusOK, &api.DeleteOptions{}},
+		"empty chunked body": {
+			onlyReader{strings.NewReader("")}, "", http.StatusOK, &api.DeleteOptions{},
+		},
+		"grace period": {
+			onlyReader{strings.NewReader(`{"gracePeriodSeconds":30}`)}, "", http.StatusOK,
+			&api.DeleteOptions{GracePeriodSeconds: &thirty},
+		},
+		"precondition holds": {
+			strings.NewReader(`{"preconditions":{"resourceVersion":5}}`), "", http.StatusOK,
+			&api.DeleteOptions{Preconditions: &api.Preconditions{ResourceVersion: 5}},
+		},
+		"precondition fails": {
+			strings.NewReader(`{"preconditions":{"resourceVersion":4}}`), "", http.StatusPreconditionFailed,
+			&api.DeleteOptions{Preconditions: &api.Preconditions{ResourceVersion: 4}},
+		},
+		"If-Match fails": {
+			nil, `"4"`, http.StatusPreconditionFailed,
+			&api.DeleteOptions{Preconditions: &api.Preconditions{ResourceVersion: 4}},
+		},
+		"If-Match disagrees": {
+			strings.NewReader(`{"preconditions":{"resourceVersion":5}}`), `"4"`, http.StatusPreconditionFailed, nil,
+		},
+	}
+	for name, item := range table {
+		storage := &GracefulRESTStorage{SimpleRESTStorage: SimpleRESTStorage{
+			pod: &api.Pod{JSONBase: api.JSONBase{ID: "foo", ResourceVersion: 5}},
+		}}
+		server := httptest.NewServer(Handle(map[string]RESTStorage{"pods": storage}, codec, "/prefix/version"))
+		req, _ := http.NewRequest("DELETE", server.URL+"/prefix/version/pods/foo?sync=true", item.body)
+		if item.ifMatch != "" {
+			req.Header.Set("If-Match", item.ifMatch)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		resp.Body.Close()
+		server.Close()
+		if e, a := item.expectCode, resp.StatusCode; e != a {
+			t.Errorf("%s: expected %v, got %v", name, e, a)
+		}
+		if e, a := item.expectOptions, storage.options; !reflect.DeepEqual(e, a) {
+			t.Errorf("%s: expected %#v, got %#v", name, e, a)
+		}
+	}
+}
+
+// DryRunRESTStorage is a SimpleRESTStorage which records the changes made through it,
+// and checks them in dry runs: a pod without an ID is invalid.
+type DryRunRESTStorage struct {
+	SimpleRESTStorage
+	changes []string
+}
+
+func (s *DryRunRESTStorage) Create(obj runtime.Object) (<-chan runtime.Object, error) {
+	s.changes = append(s.changes, "create")
+	return s.SimpleRESTStorage.Create(obj)
+}
+
+func (s *DryRunRESTStorage) Update(obj runtime.Object) (<-chan runtime.Object, error) {
+	s.changes = append(s.changes, "update")
+	return s.SimpleRESTStorage.Update(obj)
+}
+
+func (s *DryRunRESTStorage) Delete(id string) (<-chan runtime.Object, error) {
+	s.changes = append(s.changes, "delete")
+	return s.SimpleRESTStorage.Delete(id)
+}
+
+func (s *DryRunRESTStorage) DryRunCreate(obj runtime.Object) (runtime.Object, error) {
+	if pod := obj.(*api.Pod); pod.ID == "" {
+		return nil, errors.NewInvalid("pod", "", errors.ErrorList{errors.NewFieldRequired("id", "")})
+	}
+	return obj, nil
+}
+
+func (s *DryRunRESTStorage) DryRunUpdate(obj runtime.Object) (runtime.Object, error) {
+	return s.DryRunCreate(obj)
+}
+
+func (s *DryRunRESTStorage) DryRunDelete(id string) (runtime.Object, error) {
+	return s.Get(id)
+}
+
+func TestDryRun(t *testing.T) {
+	table := map[string]struct {
+		method       string
+		path         string
+		body         string
+		dryRunner    bool
+		expectCode   int
+		expectID     string
+		expectReason api.StatusReason
+	}{
+		"create":               {"POST", "pods", `{"id":"bar"}`, true, http.StatusOK, "bar", ""},
+		"update":               {"PUT", "pods/foo", `{"id":"foo"}`, true, http.StatusOK, "foo", ""},
+		"delete":               {"DELETE", "pods/foo", "", true, http.StatusOK, "foo", ""},
+		"invalid create":       {"POST", "pods", `{}`, true, http.StatusUnprocessableEntity, "", api.StatusReasonInvalid},
+		"delete missing":       {"DELETE", "pods/bar", "", true, http.StatusNotFound, "", api.StatusReasonNotFound},
+		"not supported":        {"POST", "pods", `{"id":"bar"}`, false, http.StatusBadRequest, "", ""},
+		"delete not supported": {"DELETE", "pods/foo", "", false, http.StatusBadRequest, "", ""},
+	}
+	for name, item := range table {
+		dryRunStorage := &DryRunRESTStorage{SimpleRESTStorage: SimpleRESTStorage{
+			pod: &api.Pod{JSONBase: api.JSONBase{ID: "foo"}},
+		}}
+		var storage RESTStorage = dryRunStorage
+		if !item.dryRunner {
+			// Hide the DryRunner methods.
+			storage = struct{ RESTStorage }{dryRunStorage}
+		}
+		server := httptest.NewServer(Handle(map[string]RESTStorage{"pods": storage}, codec, "/prefix/version"))
+		req, _ := http.NewRequest(item.method, server.URL+"/prefix/version/"+item.path+"?dryRun=true&sync=true", strings.NewReader(item.body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		data, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		server.Close()
+
+		if e, a := item.expectCode, resp.StatusCode; e != a {
+			t.Errorf("%s: expected %v, got %v: %s", name, e, a, data)
+		}
+		if len(dryRunStorage.changes) != 0 {
+			t.Errorf("%s: expected no changes, got %v", name, dryRunStorage.changes)
+		}
+		obj, err := codec.Decode(data)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", name, err)
+			continue
+		}
+		if item.expectCode == http.StatusOK {
+			if pod, ok := obj.(*api.Pod); !ok || pod.ID != item.expectID {
+				t.Errorf("%s: expected pod %v, got %#v", name, item.expectID, obj)
+			}
+			continue
+		}
+		status, ok := obj.(*api.Status)
+		if !ok {
+			t.Errorf("%s: expected a status, got %#v", name, obj)
+			continue
+		}
+		if e, a := item.expectReason, status.Reason; e != a {
+			t.Errorf("%s: expected %v, got %v", name, e, a)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	stored := &api.Pod{
+		JSONBase: api.JSONBase{
+			ID:                "foo",
+			ResourceVersion:   5,
+			CreationTimestamp: util.Now(),
+			SelfLink:          "/prefix/version/pods/foo",
+		},
+		DesiredState: api.PodState{Host: "a"},
+	}
+	table := map[string]struct {
+		path          string
+		body          string
+		expectCode    int
+		expectChanges []api.FieldChange
+	}{
+		"unchanged":             {"pods/foo/diff", `{"desiredState":{"host":"a"}}`, http.StatusOK, nil},
+		"server-set fields":     {"pods/foo/diff", `{"id":"foo","desiredState":{"host":"a"},"resourceVersion":0,"selfLink":""}`, http.StatusOK, nil},
+		"changed field":         {"pods/foo/diff", `{"desiredState":{"host":"b"}}`, http.StatusOK, []api.FieldChange{{Path: "desiredState.host", Old: `"a"`, New: `"b"`}}},
+		"removed field":         {"pods/foo/diff", `{}`, http.StatusOK, []api.FieldChange{{Path: "desiredState.host", Old: `"a"`, New: `""`}}},
+		"stale resourceVersion": {"pods/foo/diff", `{"desiredState":{"host":"a"},"resourceVersion":4}`, http.StatusOK, []api.FieldChange{{Path: "resourceVersion", Old: "5", New: "4"}}},
+		"missing object":        {"pods/bar/diff", `{}`, http.StatusNotFound, nil},
+	}
+	for name, item := range table {
+		storage := &SimpleRESTStorage{pod: stored}
+		server := httptest.NewServer(Handle(map[string]RESTStorage{"pods": storage}, codec, "/prefix/version"))
+		resp, err := http.Post(server.URL+"/prefix/version/"+item.path, "application/json", strings.NewReader(item.body))
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		data, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		server.Close()
+
+		if e, a := item.expectCode, resp.StatusCode; e != a {
+			t.Errorf("%s: expected %v, got %v: %s", name, e, a, data)
+			continue
+		}
+		obj, err := codec.Decode(data)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", name, err)
+			continue
+		}
+		if item.expectCode != http.StatusOK {
+			if status, ok := obj.(*api.Status); !ok || status.Reason != api.StatusReasonNotFound {
+				t.Errorf("%s: expected a not found status, got %#v", name, obj)
+			}
+			continue
+		}
+		diff, ok := obj.(*api.Diff)
+		if !ok {
+			t.Errorf("%s: expected a diff, got %#v", name, obj)
+			continue
+		}
+		if e, a := item.expectChanges, diff.Changes; !reflect.DeepEqual(e, a) {
+			t.Errorf("%s: expected %#v, got %#v", name, e, a)
+		}
+	}
+}
+
+// recordingSink is an audit.Sink which keeps the events it is given.
+type recordingSink struct {
+	events []*audit.Event
+}
+
+func (s *recordingSink) Record(event *audit.Event) error {
+	s.events = append(s.events, event)
+	return nil
+}
+
+func TestDiffIsNotAudited(t *testing.T) {
+	sink := &recordingSink{}
+	group := NewAPIGroup(map[string]RESTStorage{"pods": &SimpleRESTStorage{
+		pod: &api.Pod{JSONBase: api.JSONBase{ID: "foo"}},
+	}}, codec)
+	group.SetAuditor(audit.New(sink, 0))
+	mux := http.NewServeMux()
+	group.InstallREST(mux, "/prefix/version")
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	for path, expected := range map[string]int{
+		"/prefix/version/pods/foo/diff":  0,
+		"/prefix/version/pods?sync=true": 1,
+	} {
+		sink.events = nil
+		resp, err := http.Post(server.URL+path, "application/json", strings.NewReader(`{"id":"foo"}`))
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", path, err)
+		}
+		resp.Body.Close()
+		if e, a := expected, len(sink.events); e != a {
+			t.Errorf("%s: expected %v audit events, got %v", path, e, a)
+		}
+	}
+}
@@ -1,15 +1,19 @@
 package apiserver
 
 import (
-	"encoding/json"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"net"
 	"net/http"
+	"sort"
 	"strconv"
+	"time"
 
+	"github.com/ryutah/kubernetes-transcribe/pkg/api"
 	"github.com/ryutah/kubernetes-transcribe/pkg/health"
+	"github.com/ryutah/kubernetes-transcribe/pkg/healthz"
+	"github.com/ryutah/kubernetes-transcribe/pkg/labels"
+	"github.com/ryutah/kubernetes-transcribe/pkg/runtime"
 )
 
 // TODO: this basic interface is duplicated in N places.  consolidate?
@@ -17,75 +21,147 @@ type httpGet interface {
 	Get(url string) (*http.Response, error)
 }
 
+// ValidatorConfig configures the /validate endpoint.
+type ValidatorConfig struct {
+	// Servers maps the name of each component to check to the host:port
+	// on which it serves /healthz.
+	Servers map[string]string
+	// KubeletPort is the port on which kubelets serve /healthz. If it is not
+	// zero, the kubelet of every registered minion is checked as well.
+	KubeletPort int
+	// Timeout bounds how long a single check may take. 0 means no limit.
+	Timeout time.Duration
+}
+
+// server is a healthz.HealthzChecker for a component which serves /healthz over HTTP.
 type server struct {
-	addr string
-	port int
+	name   string
+	addr   string
+	port   int
+	client httpGet
 }
 
 // validator is responsible for validating the cluster and serving
 type validator struct {
-	servers map[string]server
-	client  httpGet
+	servers     []healthz.HealthzChecker
+	kubeletPort int
+	timeout     time.Duration
+	client      httpGet
+	codec       runtime.Codec
+	// minions returns the IDs of the registered minions.
+	minions func() ([]string, error)
+}
+
+// Name implements healthz.HealthzChecker.
+func (s *server) Name() string {
+	return s.name
 }
 
-func (s *server) check(client httpGet) (health.Status, string, error) {
-	resp, err := client.Get("http://" + net.JoinHostPort(s.addr, strconv.Itoa(s.port)) + "/healthz")
+// Check implements healthz.HealthzChecker.
+func (s *server) Check(req *http.Request) error {
+	resp, err := s.client.Get("http://" + net.JoinHostPort(s.addr, strconv.Itoa(s.port)) + "/healthz")
 	if err != nil {
-		return health.Unknown, "", err
+		return err
 	}
 	defer resp.Body.Close()
 	data, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return health.Unknown, string(data), err
+		return err
 	}
 	if resp.StatusCode != http.StatusOK {
-		return health.Unhealthy, string(data),
-			fmt.Errorf("unhealthy http status code: %d (%s)", resp.StatusCode, resp.Status)
+		return fmt.Errorf("unhealthy http status code: %d (%s): %s", resp.StatusCode, resp.Status, string(data))
 	}
-	return health.Healthy, string(data), nil
+	return nil
 }
 
-type ServerStatus struct {
-	Component  string        `json:"component,omitempty"`
-	Health     string        `json:"health,omitempty"`
-	HealthCode health.Status `json:"healthCode,omitempty"`
-	Msg        string        `json:"msg,omitempty"`
-	Err        string        `json:"err,omitempty"`
+// checks returns the configured component checks followed by a check for
+// the kubelet on each registered minion.
+func (v *validator) checks() ([]healthz.HealthzChecker, error) {
+	checks := append([]healthz.HealthzChecker{}, v.servers...)
+	if v.kubeletPort == 0 || v.minions == nil {
+		return checks, nil
+	}
+	minions, err := v.minions()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(minions)
+	for _, minion := range minions {
+		checks = append(checks, &server{
+			name:   "minion-" + minion,
+			addr:   minion,
+			port:   v.kubeletPort,
+			client: v.client,
+		})
+	}
+	return checks, nil
 }
 
-func (v *validator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	reply := []ServerStatus{}
-	for name, server := range v.servers {
-		status, msg, err := server.check(v.client)
-		var errorMsg string
+// runCheck runs check, giving up after v.timeout if it isn't 0.
+func (v *validator) runCheck(check healthz.HealthzChecker, req *http.Request) api.ComponentStatus {
+	result := make(chan error, 1)
+	go func() {
+		result <- check.Check(req)
+	}()
+	var timeout <-chan time.Time
+	if v.timeout > 0 {
+		timer := time.NewTimer(v.timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	status := api.ComponentStatus{JSONBase: api.JSONBase{ID: check.Name()}}
+	select {
+	case err := <-result:
 		if err != nil {
-			errorMsg = err.Error()
+			status.Health = health.Unhealthy.String()
+			status.Error = err.Error()
 		} else {
-			errorMsg = "nil"
+			status.Health = health.Healthy.String()
 		}
-		reply = append(reply, ServerStatus{
-			Component:  name,
-			Health:     status.String(),
-			HealthCode: status,
-			Msg:        msg,
-			Err:        errorMsg,
-		})
+	case <-timeout:
+		status.Health = health.Unknown.String()
+		status.Error = fmt.Sprintf("no response within %v", v.timeout)
 	}
-	data, err := json.Marshal(reply)
-	log.Printf("FOO: %s", string(data))
+	return status
+}
+
+func (v *validator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	checks, err := v.checks()
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(err.Error()))
+		errorJSON(err, v.codec, w)
 		return
 	}
-	w.WriteHeader(http.StatusOK)
-	w.Write(data)
+	results := make(chan api.ComponentStatus, len(checks))
+	for _, check := range checks {
+		go func(check healthz.HealthzChecker) {
+			results <- v.runCheck(check, r)
+		}(check)
+	}
+	list := &api.ComponentStatusList{}
+	for range checks {
+		list.Items = append(list.Items, <-results)
+	}
+	sort.Sort(componentStatusByID(list.Items))
+	writeJSON(http.StatusOK, v.codec, list, w)
+}
+
+// componentStatusByID sorts component statuses by ID.
+type componentStatusByID []api.ComponentStatus
+
+func (s componentStatusByID) Len() int           { return len(s) }
+func (s componentStatusByID) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s componentStatusByID) Less(i, j int) bool { return s[i].ID < s[j].ID }
+
+// NewValidator creates a validator for the components in config. minions is
+// used to find the minions whose kubelets should be checked; it may be nil.
+func NewValidator(config ValidatorConfig, codec runtime.Codec, minions func() ([]string, error)) (http.Handler, error) {
+	return newValidator(config, codec, minions, &http.Client{Timeout: config.Timeout})
 }
 
-// NewValidator creates a validator for a set of servers.
-func NewValidator(servers map[string]string) (http.Handler, error) {
-	result := map[string]server{}
-	for name, value := range servers {
+// newValidator creates a validator whose server checks use client.
+func newValidator(config ValidatorConfig, codec runtime.Codec, minions func() ([]string, error), client httpGet) (*validator, error) {
+	servers := []healthz.HealthzChecker{}
+	for name, value := range config.Servers {
 		host, port, err := net.SplitHostPort(value)
 		if err != nil {
 			return nil, fmt.Errorf("invalid server spec: %s (%v)", value, err)
@@ -94,18 +170,31 @@ func NewValidator(servers map[string]string) (http.Handler, error) {
 		if err != nil {
 			return nil, fmt.Errorf("invalid server spec: %s (%v)", port, err)
 		}
-		result[name] = server{addr: host, port: val}
+		servers = append(servers, &server{name: name, addr: host, port: val, client: client})
 	}
 	return &validator{
-		servers: result,
-		client:  &http.Client{},
+		servers:     servers,
+		kubeletPort: config.KubeletPort,
+		timeout:     config.Timeout,
+		client:      client,
+		codec:       codec,
+		minions:     minions,
 	}, nil
 }
 
-func makeTestValidator(servers map[string]string, get httpGet) (http.Handler, error) {
-	v, e := NewValidator(servers)
-	if e == nil {
-		v.(*validator).client = get
+// listMinions returns the IDs of the minions in storage, which must hold api.Minion objects.
+func listMinions(storage RESTStorage) ([]string, error) {
+	obj, err := storage.List(labels.Everything(), labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	list, ok := obj.(*api.MinionList)
+	if !ok {
+		return nil, fmt.Errorf("unexpected minion list type %T", obj)
+	}
+	ids := make([]string, 0, len(list.Items))
+	for _, minion := range list.Items {
+		ids = append(ids, minion.ID)
 	}
-	return v, e
+	return ids, nil
 }
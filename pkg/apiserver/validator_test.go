@@ -0,0 +1,54 @@
+package apiserver
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ryutah/kubernetes-transcribe/pkg/api"
+	"github.com/ryutah/kubernetes-transcribe/pkg/health"
+)
+
+// fakeHTTPGet answers every GET with 200 OK, after delay.
+type fakeHTTPGet struct {
+	delay time.Duration
+}
+
+func (f fakeHTTPGet) Get(url string) (*http.Response, error) {
+	time.Sleep(f.delay)
+	return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(bytes.NewBufferString("ok"))}, nil
+}
+
+func TestValidatorTimeout(t *testing.T) {
+	table := map[string]struct {
+		timeout time.Duration
+		delay   time.Duration
+		expect  health.Status
+	}{
+		"no limit":  {0, 10 * time.Millisecond, health.Healthy},
+		"in time":   {time.Minute, 0, health.Healthy},
+		"timed out": {time.Millisecond, time.Second, health.Unknown},
+	}
+	for name, item := range table {
+		config := ValidatorConfig{Servers: map[string]string{"scheduler": "localhost:10251"}, Timeout: item.timeout}
+		validator, err := newValidator(config, codec, nil, fakeHTTPGet{item.delay})
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		w := httptest.NewRecorder()
+		validator.ServeHTTP(w, &http.Request{})
+		list := &api.ComponentStatusList{}
+		if err := codec.DecodeInto(w.Body.Bytes(), list); err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if len(list.Items) != 1 {
+			t.Fatalf("%s: unexpected statuses: %#v", name, list.Items)
+		}
+		if e, a := item.expect.String(), list.Items[0].Health; e != a {
+			t.Errorf("%s: expected %v, got %v", name, e, a)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package apiserver
 
 import (
 	"encoding/json"
+	"io"
 	"net/http"
 	"net/url"
 	"regexp"
@@ -20,6 +21,9 @@ import (
 type WatchHandler struct {
 	storage map[string]RESTStorage
 	codec   runtime.Codec
+	// closing is closed when the server is shutting down; open watches are
+	// ended and new ones are refused.
+	closing <-chan struct{}
 }
 
 func getWatchParams(query url.Values) (label, field labels.Selector, resourceVersion uint64) {
@@ -58,15 +62,27 @@ func (h *WatchHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 	if watcher, ok := storage.(ResourceWatcher); ok {
+		// Watches are only served as JSON or YAML.
+		codec := negotiateCodec(req, h.codec, nil)
+		select {
+		case <-h.closing:
+			writeJSON(http.StatusServiceUnavailable, codec, &api.Status{
+				Status:  api.StatusFailure,
+				Code:    http.StatusServiceUnavailable,
+				Message: "the server is shutting down",
+			}, w)
+			return
+		default:
+		}
 		label, field, resourceVersion := getWatchParams(req.URL.Query())
 		watching, err := watcher.Watch(label, field, resourceVersion)
 		if err != nil {
-			errorJSON(err, h.codec, w)
+			errorJSON(err, codec, w)
 			return
 		}
 		// TODO: This is one watch per connection. We want to multiplex, so that
 		// multiple watches of the same thing don't create two watches downstream.
-		watchServer := &WatchServer{watching: watching, codec: h.codec}
+		watchServer := &WatchServer{watching: watching, codec: h.codec, closing: h.closing}
 		if isWebSocketRequest(req) {
 			websocket.Handler(watchServer.HandleWS).ServeHTTP(httplog.Unlogged(w), req)
 		} else {
@@ -82,6 +98,8 @@ func (h *WatchHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 type WatchServer struct {
 	watching watch.Interface
 	codec    runtime.Codec
+	// closing, if not nil, ends the stream cleanly when it is closed.
+	closing <-chan struct{}
 }
 
 // HandleWS implements a websocket handler.
@@ -99,6 +117,10 @@ func (s *WatchServer) HandleWS(ws *websocket.Conn) {
 		case <-done:
 			s.watching.Stop()
 			return
+		case <-s.closing:
+			// Returning closes the websocket.
+			s.watching.Stop()
+			return
 		case event, ok := <-s.watching.ResultChan():
 			if !ok {
 				// End of results.
@@ -120,7 +142,8 @@ func (s *WatchServer) HandleWS(ws *websocket.Conn) {
 }
 
 // ServeHTTP serves a series of JSON encoded events via straight HTTP with
-// Transfer-Encoding: chunked.
+// Transfer-Encoding: chunked. If the Accept header prefers YAML, each event is
+// instead sent as a YAML document, starting with "---".
 func (s *WatchServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	loggedW := httplog.LogOf(req, w)
 	w = httplog.Unlogged(w)
@@ -138,6 +161,8 @@ func (s *WatchServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	contentType := contentTypeOf(negotiateCodec(req, s.codec, nil))
+	w.Header().Set("Content-Type", contentType)
 	w.Header().Set("Transfer-Encoding", "chunked")
 	w.WriteHeader(http.StatusOK)
 	flusher.Flush()
@@ -148,6 +173,10 @@ func (s *WatchServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		case <-cn.CloseNotify():
 			s.watching.Stop()
 			return
+		case <-s.closing:
+			// Returning ends the chunked response.
+			s.watching.Stop()
+			return
 		case event, ok := <-s.watching.ResultChan():
 			if !ok {
 				// End of results.
@@ -159,7 +188,12 @@ func (s *WatchServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 				s.watching.Stop()
 				return
 			}
-			if err := encoder.Encode(obj); err != nil {
+			if contentType == yamlContentType {
+				err = writeYAMLDocument(w, obj)
+			} else {
+				err = encoder.Encode(obj)
+			}
+			if err != nil {
 				s.watching.Stop()
 				return
 			}
@@ -167,3 +201,16 @@ func (s *WatchServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		}
 	}
 }
+
+// writeYAMLDocument writes obj to w as a YAML document in a stream.
+func writeYAMLDocument(w io.Writer, obj interface{}) error {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	if data, err = jsonToYAML(data); err != nil {
+		return err
+	}
+	_, err = w.Write(append([]byte("---\n"), data...))
+	return err
+}
@@ -0,0 +1,128 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"github.com/ryutah/kubernetes-transcribe/pkg/api"
+	"github.com/ryutah/kubernetes-transcribe/pkg/watch"
+)
+
+// readAllWithin reads resp.Body to its end, failing the test unless it ends within a second.
+func readAllWithin(t *testing.T, resp *http.Response) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ioutil.ReadAll(resp.Body)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Errorf("expected the stream to end")
+		resp.Body.Close()
+		<-done
+	}
+}
+
+func isStopped(w *watch.FakeWatcher) bool {
+	w.Lock()
+	defer w.Unlock()
+	return w.Stopped
+}
+
+func TestStopWatches(t *testing.T) {
+	storage := &WatchingRESTStorage{fakeWatch: watch.NewFake()}
+	group := NewAPIGroup(map[string]RESTStorage{"pods": storage}, codec)
+	mux := http.NewServeMux()
+	group.InstallREST(mux, "/prefix/version")
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/prefix/version/watch/pods")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	storage.fakeWatch.Add(&api.Pod{JSONBase: api.JSONBase{ID: "foo"}})
+	var event struct{ Type watch.EventType }
+	if err := json.NewDecoder(resp.Body).Decode(&event); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e, a := watch.Added, event.Type; e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+
+	group.StopWatches()
+	readAllWithin(t, resp)
+	if !isStopped(storage.fakeWatch) {
+		t.Errorf("expected the watch to be stopped")
+	}
+
+	// New watches are refused, and stopping again is harmless.
+	group.StopWatches()
+	resp, err = http.Get(server.URL + "/prefix/version/watch/pods")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if e, a := http.StatusServiceUnavailable, resp.StatusCode; e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+	obj, err := codec.Decode(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status, ok := obj.(*api.Status); !ok || status.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected an unavailable status, got %#v", obj)
+	}
+}
+
+func TestWatchServerClosing(t *testing.T) {
+	closing := make(chan struct{})
+	fakeWatch := watch.NewFake()
+	server := httptest.NewServer(&WatchServer{watching: fakeWatch, codec: codec, closing: closing})
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	close(closing)
+	readAllWithin(t, resp)
+	if !isStopped(fakeWatch) {
+		t.Errorf("expected the watch to be stopped")
+	}
+}
+
+func TestWatchServerClosingWebSocket(t *testing.T) {
+	closing := make(chan struct{})
+	fakeWatch := watch.NewFake()
+	watchServer := &WatchServer{watching: fakeWatch, codec: codec, closing: closing}
+	server := httptest.NewServer(websocket.Handler(watchServer.HandleWS))
+	defer server.Close()
+
+	ws, err := websocket.Dial("ws://"+server.Listener.Addr().String(), "", "http://localhost/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer ws.Close()
+	close(closing)
+	ws.SetReadDeadline(time.Now().Add(time.Second))
+	var unused interface{}
+	if err := websocket.JSON.Receive(ws, &unused); err == nil {
+		t.Errorf("expected the websocket to be closed, got %v", unused)
+	} else if netErr, ok := err.(interface{ Timeout() bool }); ok && netErr.Timeout() {
+		t.Errorf("expected the websocket to be closed, timed out")
+	}
+	if !isStopped(fakeWatch) {
+		t.Errorf("expected the watch to be stopped")
+	}
+}
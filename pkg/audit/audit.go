@@ -0,0 +1,180 @@
+package audit
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/ryutah/kubernetes-transcribe/pkg/httplog"
+)
+
+// Event is a single audit record. It is written as one line of JSON.
+type Event struct {
+	Timestamp time.Time `json:"timestamp"`
+	// ClaimedUser is the user name sent in the basic auth header of the request.
+	// The apiserver doesn't authenticate requests, so nothing vouches for it.
+	ClaimedUser string `json:"claimedUser,omitempty"`
+	SourceIP    string `json:"sourceIP,omitempty"`
+	Verb        string `json:"verb"`
+	Resource    string `json:"resource"`
+	ID          string `json:"id,omitempty"`
+	Code        int    `json:"code"`
+	OperationID string `json:"operationID,omitempty"`
+	// RequestBody and ResponseBody are only recorded if the Auditor was
+	// configured with a body size limit, and are truncated to that limit.
+	RequestBody  string `json:"requestBody,omitempty"`
+	ResponseBody string `json:"responseBody,omitempty"`
+}
+
+// Sink stores audit events.
+type Sink interface {
+	Record(event *Event) error
+}
+
+// Auditor creates audit events for requests and hands them to a Sink.
+type Auditor struct {
+	sink        Sink
+	maxBodySize int
+}
+
+// New creates an Auditor which writes to sink. If maxBodySize is positive, up to
+// that many bytes of each request and response body are recorded too.
+func New(sink Sink, maxBodySize int) *Auditor {
+	return &Auditor{
+		sink:        sink,
+		maxBodySize: maxBodySize,
+	}
+}
+
+// IsMutating returns true if requests with the given method change state, and
+// should therefore be audited.
+func IsMutating(method string) bool {
+	switch method {
+	case "POST", "PUT", "PATCH", "DELETE":
+		return true
+	}
+	return false
+}
+
+// Add a layer on top of ResponseWriter, so we can record the response code and
+// body along with the request.
+type respAuditor struct {
+	auditor  *Auditor
+	event    Event
+	w        http.ResponseWriter
+	reqBody  *limitedBuffer
+	respBody *limitedBuffer
+}
+
+// NewAudited turns a normal response writer into an audited response writer for a
+// request on the given resource and ID.
+//
+// Usage:
+//
+// defer auditor.NewAudited(req, &w, resource, id).Log()
+//
+// Note that this *changes* your writer, to route response writing actions
+// through the auditor, and, if bodies are recorded, replaces req.Body.
+//
+// Use SetOperationID(w, ...) to record the operation that serves the request.
+func (a *Auditor) NewAudited(req *http.Request, w *http.ResponseWriter, resource, id string) *respAuditor {
+	claimedUser, _, _ := req.BasicAuth()
+	sourceIP, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		sourceIP = req.RemoteAddr
+	}
+	ra := &respAuditor{
+		auditor: a,
+		event: Event{
+			Timestamp:   time.Now(),
+			ClaimedUser: claimedUser,
+			SourceIP:    sourceIP,
+			Verb:        req.Method,
+			Resource:    resource,
+			ID:          id,
+		},
+		w: *w,
+	}
+	if a.maxBodySize > 0 {
+		ra.reqBody = &limitedBuffer{limit: a.maxBodySize}
+		ra.respBody = &limitedBuffer{limit: a.maxBodySize}
+		if req.Body != nil {
+			req.Body = &teeReadCloser{io.TeeReader(req.Body, ra.reqBody), req.Body}
+		}
+	}
+	*w = ra // hijack caller's writer!
+	return ra
+}
+
+// SetOperationID records the ID of the operation serving the request, if w is
+// an audited writer. Otherwise it does nothing.
+func SetOperationID(w http.ResponseWriter, id string) {
+	if ra, ok := w.(*respAuditor); ok {
+		ra.event.OperationID = id
+	}
+}
+
+// Log hands the finished event to the sink.
+func (ra *respAuditor) Log() {
+	if ra.event.Code == 0 {
+		ra.event.Code = http.StatusOK
+	}
+	if ra.reqBody != nil {
+		ra.event.RequestBody = ra.reqBody.String()
+		ra.event.ResponseBody = ra.respBody.String()
+	}
+	if err := ra.auditor.sink.Record(&ra.event); err != nil {
+		glog.Errorf("Failed to record audit event %#v: %v", ra.event, err)
+	}
+}
+
+// Addf adds info to the log of the wrapped writer, so that httplog.LogOf finds
+// it through the auditor.
+func (ra *respAuditor) Addf(format string, data ...interface{}) {
+	httplog.LogOf(nil, ra.w).Addf(format, data...)
+}
+
+// Header implements http.ResponseWriter.
+func (ra *respAuditor) Header() http.Header {
+	return ra.w.Header()
+}
+
+// Write implements http.ResponseWriter.
+func (ra *respAuditor) Write(b []byte) (int, error) {
+	if ra.respBody != nil {
+		ra.respBody.Write(b)
+	}
+	return ra.w.Write(b)
+}
+
+// WriteHeader implements http.ResponseWriter.
+func (ra *respAuditor) WriteHeader(status int) {
+	ra.event.Code = status
+	ra.w.WriteHeader(status)
+}
+
+// limitedBuffer keeps the first limit bytes written to it and discards the rest.
+type limitedBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.Len(); room > 0 {
+		if len(p) > room {
+			b.Buffer.Write(p[:room])
+		} else {
+			b.Buffer.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+// teeReadCloser reads through a tee but closes the original body.
+type teeReadCloser struct {
+	io.Reader
+	io.Closer
+}
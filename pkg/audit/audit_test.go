@@ -0,0 +1,162 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ryutah/kubernetes-transcribe/pkg/httplog"
+)
+
+type fakeSink struct {
+	events []Event
+}
+
+func (f *fakeSink) Record(event *Event) error {
+	f.events = append(f.events, *event)
+	return nil
+}
+
+func TestNewAudited(t *testing.T) {
+	sink := &fakeSink{}
+	auditor := New(sink, 5)
+	handler := func(w http.ResponseWriter, req *http.Request) {
+		defer auditor.NewAudited(req, &w, "pods", "foo").Log()
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil || string(body) != "request body" {
+			t.Errorf("Unexpected body %q: %v", body, err)
+		}
+		SetOperationID(w, "42")
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("response body"))
+	}
+	req, _ := http.NewRequest("PUT", "http://localhost/pods/foo", bytes.NewBufferString("request body"))
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.SetBasicAuth("alice", "secret")
+	w := httptest.NewRecorder()
+	handler(w, req)
+
+	if w.Code != http.StatusAccepted || w.Body.String() != "response body" {
+		t.Errorf("Response was not passed through: %d %q", w.Code, w.Body.String())
+	}
+	if len(sink.events) != 1 {
+		t.Fatalf("Expected 1 event, got %#v", sink.events)
+	}
+	event := sink.events[0]
+	expected := Event{
+		Timestamp:    event.Timestamp,
+		ClaimedUser:  "alice",
+		SourceIP:     "10.0.0.1",
+		Verb:         "PUT",
+		Resource:     "pods",
+		ID:           "foo",
+		Code:         http.StatusAccepted,
+		OperationID:  "42",
+		RequestBody:  "reque",
+		ResponseBody: "respo",
+	}
+	if event != expected {
+		t.Errorf("Expected %#v, got %#v", expected, event)
+	}
+}
+
+func TestFileSinkRotates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+
+	// Every event is a little under 100 bytes, so each one forces a rotation.
+	sink, err := NewFileSink(path, 100, 2)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, id := range []string{"a", "b", "c", "d"} {
+		if err := sink.Record(&Event{Verb: "DELETE", Resource: "pods", ID: id}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	sink.Close()
+
+	for file, id := range map[string]string{path: "d", path + ".1": "c", path + ".2": "b"} {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		if len(lines) != 1 {
+			t.Fatalf("Expected 1 line in %s, got %q", file, data)
+		}
+		var event Event
+		if err := json.Unmarshal([]byte(lines[0]), &event); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if event.ID != id {
+			t.Errorf("Expected %s to hold %q, got %#v", file, id, event)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("Expected only 2 backups to be kept, got %v", err)
+	}
+}
+
+func TestFileSinkRecoversFromFailedRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+
+	sink, err := NewFileSink(path, 100, 1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer sink.Close()
+	// A file can't be renamed over a directory which isn't empty.
+	if err := os.MkdirAll(filepath.Join(path+".1", "blocker"), 0700); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, id := range []string{"a", "b"} {
+		if err := sink.Record(&Event{Verb: "DELETE", Resource: "pods", ID: id}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(string(data)), "\n"); len(lines) != 2 {
+		t.Errorf("Expected both events to be kept in %s, got %q", path, data)
+	}
+
+	// Once the rotation can be made, it is.
+	os.RemoveAll(path + ".1")
+	if err := sink.Record(&Event{Verb: "DELETE", Resource: "pods", ID: "c"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := os.Stat(path + ".1"); err != nil {
+		t.Errorf("Expected the log to be rotated: %v", err)
+	}
+}
+
+func TestNewAuditedKeepsLogger(t *testing.T) {
+	auditor := New(&fakeSink{}, 0)
+	handler := httplog.Handler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		defer auditor.NewAudited(req, &w, "pods", "foo").Log()
+		if _, ok := httplog.LogOf(req, w).(*respAuditor); !ok {
+			t.Errorf("Expected the logger to be found through the auditor, got %#v", httplog.LogOf(req, w))
+		}
+		httplog.LogOf(req, w).Addf("some info")
+	}), httplog.DefaultStacktracePred)
+	req, _ := http.NewRequest("DELETE", "http://localhost/pods/foo", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+}
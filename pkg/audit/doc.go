@@ -0,0 +1,3 @@
+// Package audit records who made which mutating request to the apiserver, and
+// what the outcome was, as a JSON-lines log.
+package audit
@@ -0,0 +1,121 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/golang/glog"
+)
+
+// FileSink writes events as JSON lines to a file, rotating it once it grows
+// beyond a maximum size. Rotated files are named path.1 (the most recent),
+// path.2 and so on.
+type FileSink struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	// 'lock' guards everything below.
+	lock sync.Mutex
+	// file is nil if reopening it after a rotation failed.
+	file *os.File
+	size int64
+}
+
+// NewFileSink opens, or creates, the audit log at path. Once the file exceeds
+// maxSize bytes it is rotated, keeping at most maxBackups old files. A maxSize
+// of 0 disables rotation.
+func NewFileSink(path string, maxSize int64, maxBackups int) (*FileSink, error) {
+	f := &FileSink{
+		path:       path,
+		maxSize:    maxSize,
+		maxBackups: maxBackups,
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// open opens f.path for appending. Must be called with f.lock held.
+func (f *FileSink) open() error {
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file = file
+	f.size = info.Size()
+	return nil
+}
+
+// rotate shifts the backups along by one, and starts a new file. The file is
+// reopened even if the rotation fails, so that later events are still recorded;
+// if that fails too, f.file is left nil. Must be called with f.lock held.
+func (f *FileSink) rotate() error {
+	err := f.file.Close()
+	f.file = nil
+	if err == nil {
+		err = f.shift()
+	}
+	if openErr := f.open(); openErr != nil {
+		return openErr
+	}
+	return err
+}
+
+// shift moves f.path to path.1, and the backups along by one, or removes f.path
+// if no backups are kept.
+func (f *FileSink) shift() error {
+	if f.maxBackups == 0 {
+		return os.Remove(f.path)
+	}
+	for i := f.maxBackups - 1; i > 0; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", f.path, i), fmt.Sprintf("%s.%d", f.path, i+1))
+	}
+	return os.Rename(f.path, f.path+".1")
+}
+
+// Record implements Sink.
+func (f *FileSink) Record(event *Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.file == nil {
+		if err := f.open(); err != nil {
+			return err
+		}
+	}
+	if f.maxSize > 0 && f.size > 0 && f.size+int64(len(data)) > f.maxSize {
+		if err := f.rotate(); err != nil {
+			glog.Errorf("Failed to rotate the audit log %s: %v", f.path, err)
+			if f.file == nil {
+				return err
+			}
+		}
+	}
+	n, err := f.file.Write(data)
+	f.size += int64(n)
+	return err
+}
+
+// Close closes the underlying file.
+func (f *FileSink) Close() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.file == nil {
+		return nil
+	}
+	return f.file.Close()
+}
@@ -4,5 +4,6 @@
 // one that simply caches objects (for example, to allow a scheduler to
 // list currently available minions), and one that additionally acts as
 // a FIFO queue (for example, to allow a scheduler to process incoming
-// pods).
+// pods). SharedInformer runs a Reflector for several consumers, and
+// notifies each of them of the changes it makes.
 package cache
@@ -0,0 +1,296 @@
+package cache
+
+import (
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/ryutah/kubernetes-transcribe/pkg/util/wait"
+)
+
+// ResourceEventHandler is notified of the changes to the objects of a SharedInformer.
+// OnUpdate is also called on periodic resyncs, with the same object as old and new.
+// OnDelete gets the last known state of the object.
+type ResourceEventHandler interface {
+	OnAdd(obj interface{})
+	OnUpdate(oldObj, newObj interface{})
+	OnDelete(obj interface{})
+}
+
+// ResourceEventHandlerFuncs implements ResourceEventHandler with functions, any of
+// which may be nil, to ignore its notifications.
+type ResourceEventHandlerFuncs struct {
+	AddFunc    func(obj interface{})
+	UpdateFunc func(oldObj, newObj interface{})
+	DeleteFunc func(obj interface{})
+}
+
+// OnAdd calls AddFunc, if it's set.
+func (f ResourceEventHandlerFuncs) OnAdd(obj interface{}) {
+	if f.AddFunc != nil {
+		f.AddFunc(obj)
+	}
+}
+
+// OnUpdate calls UpdateFunc, if it's set.
+func (f ResourceEventHandlerFuncs) OnUpdate(oldObj, newObj interface{}) {
+	if f.UpdateFunc != nil {
+		f.UpdateFunc(oldObj, newObj)
+	}
+}
+
+// OnDelete calls DeleteFunc, if it's set.
+func (f ResourceEventHandlerFuncs) OnDelete(obj interface{}) {
+	if f.DeleteFunc != nil {
+		f.DeleteFunc(obj)
+	}
+}
+
+// SharedInformer keeps a Store of the objects of one resource up to date with a
+// Reflector, and notifies any number of handlers of the changes to it, so that
+// controllers can share a single list and watch of the resource. Handlers are
+// called one at a time, in the order of the changes, and shouldn't block. They
+// may call HasSynced and read the store, but must not call AddEventHandler or
+// Run, which would deadlock.
+type SharedInformer struct {
+	store        Store
+	reflector    *Reflector
+	resyncPeriod time.Duration
+
+	// lock serializes the changes to store, and so the notifications of handlers.
+	lock     sync.Mutex
+	handlers []ResourceEventHandler
+	started  bool
+
+	// syncedLock guards synced, apart from lock, so that handlers can check it.
+	syncedLock sync.Mutex
+	synced     bool
+}
+
+// NewSharedInformer returns a SharedInformer for the objects listed and watched
+// through lw, which must be of the type of expectedType. If resyncPeriod isn't 0,
+// handlers are notified of an update of every object with that period.
+func NewSharedInformer(lw ListerWatcher, expectedType interface{}, resyncPeriod time.Duration) *SharedInformer {
+	s := &SharedInformer{
+		store:        NewStore(),
+		resyncPeriod: resyncPeriod,
+	}
+	s.reflector = NewReflector(lw, expectedType, &informerStore{s.store, s})
+	return s
+}
+
+// AddEventHandler registers handler. If the informer already holds objects,
+// handler is first notified of their addition. It must not be called by a handler.
+func (s *SharedInformer) AddEventHandler(handler ResourceEventHandler) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, obj := range s.store.List() {
+		handler.OnAdd(obj)
+	}
+	s.handlers = append(s.handlers, handler)
+}
+
+// GetStore returns the store of the informer, which must be treated as read-only.
+func (s *SharedInformer) GetStore() Store {
+	return s.store
+}
+
+// HasSynced returns true once the store holds the result of the first list.
+func (s *SharedInformer) HasSynced() bool {
+	s.syncedLock.Lock()
+	defer s.syncedLock.Unlock()
+	return s.synced
+}
+
+// Run starts the informer, until stopCh is closed. Calls after the first do nothing.
+// It must not be called by a handler.
+// Run starts goroutines and returns immediately.
+func (s *SharedInformer) Run(stopCh <-chan struct{}) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.started {
+		return
+	}
+	s.started = true
+	s.reflector.RunUntil(stopCh)
+	if s.resyncPeriod > 0 {
+		go s.resyncUntil(stopCh)
+	}
+}
+
+// resyncUntil notifies the handlers of an update of every object each resync
+// period, until stopCh is closed.
+func (s *SharedInformer) resyncUntil(stopCh <-chan struct{}) {
+	ticker := time.NewTicker(s.resyncPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+		}
+		s.lock.Lock()
+		for _, obj := range s.store.List() {
+			s.notifyUpdate(obj, obj)
+		}
+		s.lock.Unlock()
+	}
+}
+
+// The notify functions must be called with s.lock held.
+func (s *SharedInformer) notifyAdd(obj interface{}) {
+	for _, handler := range s.handlers {
+		handler.OnAdd(obj)
+	}
+}
+
+func (s *SharedInformer) notifyUpdate(oldObj, newObj interface{}) {
+	for _, handler := range s.handlers {
+		handler.OnUpdate(oldObj, newObj)
+	}
+}
+
+func (s *SharedInformer) notifyDelete(obj interface{}) {
+	for _, handler := range s.handlers {
+		handler.OnDelete(obj)
+	}
+}
+
+// informerStore is the Store a SharedInformer gives its Reflector. It changes the
+// informer's store, and notifies its handlers of the changes.
+type informerStore struct {
+	Store
+	informer *SharedInformer
+}
+
+func (i *informerStore) Add(id string, obj interface{}) {
+	i.informer.lock.Lock()
+	defer i.informer.lock.Unlock()
+	i.set(id, obj)
+}
+
+func (i *informerStore) Update(id string, obj interface{}) {
+	i.informer.lock.Lock()
+	defer i.informer.lock.Unlock()
+	i.set(id, obj)
+}
+
+// set stores obj, and notifies of its addition, or of its update if an object
+// with the same ID was stored. i.informer.lock must be held.
+func (i *informerStore) set(id string, obj interface{}) {
+	old, exists := i.Store.Get(id)
+	i.Store.Update(id, obj)
+	if exists {
+		i.informer.notifyUpdate(old, obj)
+	} else {
+		i.informer.notifyAdd(obj)
+	}
+}
+
+func (i *informerStore) Delete(id string) {
+	i.informer.lock.Lock()
+	defer i.informer.lock.Unlock()
+	old, exists := i.Store.Get(id)
+	if !exists {
+		return
+	}
+	i.Store.Delete(id)
+	i.informer.notifyDelete(old)
+}
+
+// Replace stores the given objects in place of the stored ones, and notifies of
+// the deletion of those which aren't in idToObj, and of the addition or update
+// of those which are. It marks the informer as synced once the store holds
+// them, before the notifications.
+func (i *informerStore) Replace(idToObj map[string]interface{}) {
+	i.informer.lock.Lock()
+	defer i.informer.lock.Unlock()
+	old := map[string]interface{}{}
+	for id := range i.Store.Contains() {
+		if obj, exists := i.Store.Get(id); exists {
+			old[id] = obj
+		}
+	}
+	// Replace takes ownership of idToObj, so notify from a copy.
+	objs := make(map[string]interface{}, len(idToObj))
+	for id, obj := range idToObj {
+		objs[id] = obj
+	}
+	i.Store.Replace(idToObj)
+	i.informer.syncedLock.Lock()
+	i.informer.synced = true
+	i.informer.syncedLock.Unlock()
+	for id, obj := range old {
+		if _, exists := objs[id]; !exists {
+			i.informer.notifyDelete(obj)
+		}
+	}
+	for id, obj := range objs {
+		if oldObj, exists := old[id]; exists {
+			i.informer.notifyUpdate(oldObj, obj)
+		} else {
+			i.informer.notifyAdd(obj)
+		}
+	}
+}
+
+// SharedInformers holds a SharedInformer per type of object, so that the
+// controllers of a process share one list and watch of each resource.
+type SharedInformers struct {
+	resyncPeriod time.Duration
+
+	lock      sync.Mutex
+	informers map[reflect.Type]*SharedInformer
+	started   bool
+	stopCh    <-chan struct{}
+}
+
+// NewSharedInformers returns a SharedInformers whose informers resync with the
+// given period.
+func NewSharedInformers(resyncPeriod time.Duration) *SharedInformers {
+	return &SharedInformers{
+		resyncPeriod: resyncPeriod,
+		informers:    map[reflect.Type]*SharedInformer{},
+	}
+}
+
+// InformerFor returns the informer for the objects of the type of expectedType,
+// creating it with lw if there is none yet. It is running if Start was called.
+func (f *SharedInformers) InformerFor(expectedType interface{}, lw ListerWatcher) *SharedInformer {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	t := reflect.TypeOf(expectedType)
+	informer, ok := f.informers[t]
+	if !ok {
+		informer = NewSharedInformer(lw, expectedType, f.resyncPeriod)
+		f.informers[t] = informer
+		if f.started {
+			informer.Run(f.stopCh)
+		}
+	}
+	return informer
+}
+
+// Start runs the informers, and those created afterwards, until stopCh is closed.
+func (f *SharedInformers) Start(stopCh <-chan struct{}) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.started, f.stopCh = true, stopCh
+	for _, informer := range f.informers {
+		informer.Run(stopCh)
+	}
+}
+
+// WaitForCacheSync waits until every one of cacheSyncs, such as SharedInformer.HasSynced,
+// returns true, and returns true then, or returns false once stopCh is closed.
+func WaitForCacheSync(stopCh <-chan struct{}, cacheSyncs ...func() bool) bool {
+	err := wait.PollUntil(100*time.Millisecond, func() (bool, error) {
+		for _, synced := range cacheSyncs {
+			if !synced() {
+				return false, nil
+			}
+		}
+		return true, nil
+	}, stopCh)
+	return err == nil
+}
@@ -0,0 +1,165 @@
+package cache
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ryutah/kubernetes-transcribe/pkg/api"
+	"github.com/ryutah/kubernetes-transcribe/pkg/runtime"
+	"github.com/ryutah/kubernetes-transcribe/pkg/watch"
+)
+
+// fakeListerWatcher lists list, and hands out the watches it makes on watches.
+type fakeListerWatcher struct {
+	list    runtime.Object
+	watches chan *watch.FakeWatcher
+}
+
+func (lw *fakeListerWatcher) List() (runtime.Object, error) {
+	return lw.list, nil
+}
+
+func (lw *fakeListerWatcher) Watch(resourceVersion uint64) (watch.Interface, error) {
+	w := watch.NewFake()
+	lw.watches <- w
+	return w, nil
+}
+
+// recorder is a ResourceEventHandler recording its notifications as strings.
+type recorder struct {
+	lock   sync.Mutex
+	events []string
+	// onAdd, if set, is called on every addition.
+	onAdd func()
+}
+
+func (r *recorder) OnAdd(obj interface{}) {
+	if r.onAdd != nil {
+		r.onAdd()
+	}
+	r.record("add %s", obj.(*api.Pod).ID)
+}
+
+func (r *recorder) OnUpdate(oldObj, newObj interface{}) {
+	r.record("update %s %s->%s", newObj.(*api.Pod).ID, oldObj.(*api.Pod).DesiredState.Host, newObj.(*api.Pod).DesiredState.Host)
+}
+
+func (r *recorder) OnDelete(obj interface{}) {
+	r.record("delete %s", obj.(*api.Pod).ID)
+}
+
+func (r *recorder) record(format string, args ...interface{}) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.events = append(r.events, fmt.Sprintf(format, args...))
+}
+
+// wait waits until r has recorded n notifications, and returns them.
+func (r *recorder) wait(t *testing.T, n int) []string {
+	for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(time.Millisecond) {
+		r.lock.Lock()
+		events := append([]string(nil), r.events...)
+		r.lock.Unlock()
+		if len(events) >= n {
+			return events
+		}
+	}
+	t.Fatalf("timed out waiting for %d notifications, got %v", n, r.events)
+	return nil
+}
+
+func podWithHost(id, host string) *api.Pod {
+	return &api.Pod{JSONBase: api.JSONBase{ID: id}, DesiredState: api.PodState{Host: host}}
+}
+
+func TestSharedInformerFanOut(t *testing.T) {
+	lw := &fakeListerWatcher{
+		list:    &api.PodList{Items: []api.Pod{*podWithHost("a", "")}},
+		watches: make(chan *watch.FakeWatcher, 10),
+	}
+	informer := NewSharedInformer(lw, &api.Pod{}, 0)
+	first := &recorder{}
+	informer.AddEventHandler(first)
+	if informer.HasSynced() {
+		t.Errorf("expected the informer not to be synced before running")
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	informer.Run(stop)
+	if !WaitForCacheSync(stop, informer.HasSynced) {
+		t.Fatalf("expected the informer to sync")
+	}
+	w := <-lw.watches
+
+	// Registered late, so first told about the stored pod.
+	second := &recorder{}
+	informer.AddEventHandler(second)
+	w.Add(podWithHost("b", ""))
+	w.Modify(podWithHost("a", "h"))
+	w.Delete(podWithHost("b", ""))
+
+	expected := []string{"add a", "add b", "update a ->h", "delete b"}
+	for name, r := range map[string]*recorder{"first": first, "second": second} {
+		if e, a := expected, r.wait(t, len(expected)); !reflect.DeepEqual(e, a) {
+			t.Errorf("%s: expected %v, got %v", name, e, a)
+		}
+	}
+	if _, exists := informer.GetStore().Get("b"); exists {
+		t.Errorf("expected b to be deleted from the store")
+	}
+}
+
+func TestSharedInformerResync(t *testing.T) {
+	lw := &fakeListerWatcher{
+		list:    &api.PodList{Items: []api.Pod{*podWithHost("a", "h")}},
+		watches: make(chan *watch.FakeWatcher, 10),
+	}
+	informer := NewSharedInformer(lw, &api.Pod{}, 10*time.Millisecond)
+	r := &recorder{}
+	informer.AddEventHandler(r)
+	stop := make(chan struct{})
+	defer close(stop)
+	informer.Run(stop)
+
+	events := r.wait(t, 3)
+	if e, a := []string{"add a", "update a h->h", "update a h->h"}, events[:3]; !reflect.DeepEqual(e, a) {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+}
+
+func TestSharedInformerHandlerCallsHasSynced(t *testing.T) {
+	lw := &fakeListerWatcher{
+		list:    &api.PodList{Items: []api.Pod{*podWithHost("a", "")}},
+		watches: make(chan *watch.FakeWatcher, 10),
+	}
+	informer := NewSharedInformer(lw, &api.Pod{}, 0)
+	var synced []bool
+	r := &recorder{}
+	r.onAdd = func() { synced = append(synced, informer.HasSynced()) }
+	informer.AddEventHandler(r)
+	stop := make(chan struct{})
+	defer close(stop)
+	informer.Run(stop)
+
+	w := <-lw.watches
+	w.Add(podWithHost("b", ""))
+	r.wait(t, 2)
+	if e, a := []bool{true, true}, synced; !reflect.DeepEqual(e, a) {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+}
+
+func TestSharedInformersShareByType(t *testing.T) {
+	informers := NewSharedInformers(0)
+	lw := &fakeListerWatcher{list: &api.PodList{}, watches: make(chan *watch.FakeWatcher, 10)}
+	pods := informers.InformerFor(&api.Pod{}, lw)
+	if informers.InformerFor(&api.Pod{}, nil) != pods {
+		t.Errorf("expected the pod informer to be shared")
+	}
+	if informers.InformerFor(&api.Service{}, lw) == pods {
+		t.Errorf("expected a separate informer for services")
+	}
+}
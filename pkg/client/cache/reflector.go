@@ -33,15 +33,31 @@ type Reflector struct {
 	period time.Duration
 }
 
+// NewReflector creates a Reflector which keeps store up to date with the objects
+// listed and watched through lw, which must be of the type of expectedType.
+func NewReflector(lw ListerWatcher, expectedType interface{}, store Store) *Reflector {
+	return &Reflector{
+		listerWatcher: lw,
+		store:         store,
+		expectedType:  reflect.TypeOf(expectedType),
+		period:        time.Second,
+	}
+}
+
 // Run starts a watch and handles watch events. Will restart the watch if it is closed.
 // Run starts a goroutine and returns immediately.
 func (r *Reflector) Run() {
-	go util.Forever(func() {
-		r.listAndWatch()
-	}, r.period)
+	r.RunUntil(nil)
 }
 
-func (r *Reflector) listAndWatch() {
+// RunUntil is like Run, but stops, along with its watch, once stopCh is closed.
+func (r *Reflector) RunUntil(stopCh <-chan struct{}) {
+	go util.Until(func() {
+		r.listAndWatch(stopCh)
+	}, r.period, stopCh)
+}
+
+func (r *Reflector) listAndWatch(stopCh <-chan struct{}) {
 	var resourceVersion uint64
 
 	list, err := r.listerWatcher.List()
@@ -67,12 +83,20 @@ func (r *Reflector) listAndWatch() {
 	}
 
 	for {
+		select {
+		case <-stopCh:
+			return
+		default:
+		}
 		w, err := r.listerWatcher.Watch(resourceVersion)
 		if err != nil {
 			glog.Errorf("failed to watch %v: %v", r.expectedType, err)
 			return
 		}
-		r.watchHandler(w, &resourceVersion)
+		if err := r.watchHandler(w, &resourceVersion, stopCh); err != nil {
+			glog.Errorf("watch of %v failed, relisting: %v", r.expectedType, err)
+			return
+		}
 	}
 }
 
@@ -91,13 +115,26 @@ func (r *Reflector) syncWith(items []runtime.Object) error {
 	return nil
 }
 
-// watchHandler watches w and keep *resourceVersion up to date.
-func (r *Reflector) watchHandler(w watch.Interface, resourceVersion *uint64) {
+// watchHandler watches w and keep *resourceVersion up to date, until w is closed
+// or stopCh is. It returns an error if the watch fails, such as when its resource
+// version is too old, in which case the store must be relisted.
+func (r *Reflector) watchHandler(w watch.Interface, resourceVersion *uint64, stopCh <-chan struct{}) error {
 	for {
-		event, ok := <-w.ResultChan()
+		var event watch.Event
+		var ok bool
+		select {
+		case <-stopCh:
+			w.Stop()
+			return nil
+		case event, ok = <-w.ResultChan():
+		}
 		if !ok {
 			glog.Errorf("unexpected watch close")
-			return
+			return nil
+		}
+		if event.Type == watch.Error {
+			w.Stop()
+			return fmt.Errorf("watch error: %#v", event.Object)
 		}
 		if e, a := r.expectedType, reflect.TypeOf(event.Object); e != a {
 			glog.Errorf("expected type %v, but watch event object had type %v", e, a)
@@ -25,22 +25,25 @@ type Interface interface {
 	PodInterface
 	ReplicationControllerInterface
 	ServiceInterface
+	EndpointsInterface
 	VersionInterface
 	MinionInterface
+	BindingInterface
 }
 
 // PodInterface has methods to work with Pod resources.
 type PodInterface interface {
-	ListPods(selector labels.Selector) (*api.PodList, error)
+	ListPods(label, field labels.Selector) (*api.PodList, error)
 	GetPod(id string) (*api.Pod, error)
 	DeletePod(id string) error
 	CreatePod(*api.Pod) (*api.Pod, error)
 	UpdatePod(*api.Pod) (*api.Pod, error)
+	WatchPods(label, field labels.Selector, resourceVersion uint64) (watch.Interface, error)
 }
 
 // ReplicationControllerInterface has methods to work with ReplicationController resources.
 type ReplicationControllerInterface interface {
-	ListReplicationControllers(selector labels.Selector) (*api.ReplicationControllerList, error)
+	ListReplicationControllers(label, field labels.Selector) (*api.ReplicationControllerList, error)
 	GetReplicationController(id string) (*api.ReplicationController, error)
 	CreateReplicationController(*api.ReplicationController) (*api.ReplicationController, error)
 	UpdateReplicationController(*api.ReplicationController) (*api.ReplicationController, error)
@@ -50,7 +53,7 @@ type ReplicationControllerInterface interface {
 
 // ServiceInterface has methods to work with Service resources.
 type ServiceInterface interface {
-	ListServices(selector labels.Selector) (*api.ServiceList, error)
+	ListServices(label, field labels.Selector) (*api.ServiceList, error)
 	GetService(id string) (*api.Service, error)
 	CreateService(*api.Service) (*api.Service, error)
 	UpdateService(*api.Service) (*api.Service, error)
@@ -60,17 +63,27 @@ type ServiceInterface interface {
 
 // EndpointsInterface has methods to work with Endpoints resources
 type EndpointsInterface interface {
-	ListEndpoints(selector labels.Selector) (*api.EndpointsList, error)
+	ListEndpoints(label, field labels.Selector) (*api.EndpointsList, error)
+	GetEndpoints(id string) (*api.Endpoints, error)
 	WatchEndpoints(label, field labels.Selector, resourceVersion uint64) (watch.Interface, error)
 }
 
 // VersionInterface has a method to retrieve the server version.
 type VersionInterface interface {
-	ServiceVersion() (*version.Info, error)
+	ServerVersion() (*version.Info, error)
 }
 
+// MinionInterface has methods to work with Minion resources.
 type MinionInterface interface {
-	ListMinions() (*api.MinionList, error)
+	ListMinions(label, field labels.Selector) (*api.MinionList, error)
+	GetMinion(id string) (*api.Minion, error)
+	CreateMinion(*api.Minion) (*api.Minion, error)
+	DeleteMinion(id string) error
+}
+
+// BindingInterface has a method to bind pods to minions.
+type BindingInterface interface {
+	CreateBinding(*api.Binding) error
 }
 
 // Client is the actual implementation of a Kubernetes client.
@@ -121,10 +134,12 @@ func (s *StatusErr) Error() string {
 	return fmt.Sprintf("Status: %v (%#v)", s.Status.Status, s.Status)
 }
 
-// AuthInfo is used to store authorization information.
+// AuthInfo is used to store authorization information. Requests carry the
+// bearer token if there is one, and the user and password otherwise.
 type AuthInfo struct {
-	User     string
-	Password string
+	User        string
+	Password    string
+	BearerToken string
 }
 
 // RESTClient holds common code used to work with API resources that follow the
@@ -134,12 +149,14 @@ type RESTClient struct {
 	host       string
 	prefix     string
 	secure     bool
-	auth       *AuthInfo
 	httpClient *http.Client
+	tlsConfig  *tls.Config
 	Sync       bool
 	PollPeriod time.Duration
 	Timeout    time.Duration
 	Codec      runtime.Codec
+	// Retry, if not nil, makes the client retry requests which fail transiently.
+	Retry *RetryPolicy
 }
 
 // NewRESTClient creates a new RESTClient. This client performs generic REST functions
@@ -153,23 +170,31 @@ func NewRESTClient(host string, auth *AuthInfo, path string, c runtime.Codec) (*
 	base.Path = ""
 	base.RawQuery = ""
 	base.Fragment = ""
-	return &RESTClient{
-		host:   base.String(),
-		prefix: prefix.Path,
-		secure: prefix.Scheme == "https",
-		auth:   auth,
-		httpClient: &http.Client{
-			Transport: &http.Transport{
-				TLSClientConfig: &tls.Config{
-					InsecureSkipVerify: true,
-				},
-			},
-		},
+	client := &RESTClient{
+		host:       base.String(),
+		prefix:     prefix.Path,
+		secure:     prefix.Scheme == "https",
 		Sync:       false,
 		PollPeriod: time.Second * 2,
 		Timeout:    time.Second * 20,
 		Codec:      c,
-	}, nil
+	}
+	if err := client.setTransport(&Config{Auth: auth, Insecure: true}); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// setTransport makes the client send all its requests, whether made through Do,
+// Watch or websockets, through the chain of round trippers for config.
+func (c *RESTClient) setTransport(config *Config) error {
+	transport, tlsConfig, err := transportFor(config)
+	if err != nil {
+		return err
+	}
+	c.httpClient = &http.Client{Transport: transport}
+	c.tlsConfig = tlsConfig
+	return nil
 }
 
 // normalizePrefix ensures the passed initial value is valid.
@@ -202,59 +227,79 @@ func (c *RESTClient) Secure() bool {
 }
 
 // doRequest executes a request, adds authentication (if auth != nil), and HTTPS
-// cert ignoring.
+// cert ignoring. Failed attempts are retried as c.Retry allows.
 func (c *RESTClient) doRequest(request *http.Request) ([]byte, error) {
-	if c.auth != nil {
-		request.SetBasicAuth(c.auth.User, c.auth.Password)
+	for attempt := 1; ; attempt++ {
+		body, code, status, retryAfter, err := c.doRequestOnce(request)
+		if err == nil || !c.Retry.shouldRetry(request, attempt, code, status) {
+			return body, err
+		}
+		if c.Retry.OnFailure != nil {
+			c.Retry.OnFailure(request, attempt, err)
+		}
+		if !c.Retry.wait(request, attempt, retryAfter) {
+			return nil, request.Context().Err()
+		}
+		if request.GetBody != nil {
+			if request.Body, err = request.GetBody(); err != nil {
+				return nil, err
+			}
+		}
 	}
+}
+
+// doRequestOnce makes a single attempt at request. Along with the result, it
+// returns the status code of the response, 0 if there was none, the status the
+// server responded with, if any, and the wait it asked for before a retry, or -1.
+func (c *RESTClient) doRequestOnce(request *http.Request) ([]byte, int, *api.Status, time.Duration, error) {
 	response, err := c.httpClient.Do(request)
 	if err != nil {
-		return nil, err
+		return nil, 0, nil, -1, err
 	}
 	defer response.Body.Close()
+	retryAfter := retryAfter(response)
 	body, err := ioutil.ReadAll(response.Body)
 	if err != nil {
-		return body, err
+		return body, 0, nil, retryAfter, err
 	}
 
 	// Did the server give us a status response?
-	isStatusResponse := false
-	var status api.Status
-	if err := latest.Codec.DecodeInto(body, &status); err == nil && status.Status != "" {
-		isStatusResponse = true
+	var status *api.Status
+	if s := (api.Status{}); latest.Codec.DecodeInto(body, &s) == nil && s.Status != "" {
+		status = &s
 	}
 
 	switch {
 	case response.StatusCode == http.StatusConflict:
 		// Return error given by server, if there was one.
-		if isStatusResponse {
-			return nil, &StatusErr{status}
+		if status != nil {
+			return nil, response.StatusCode, status, retryAfter, &StatusErr{*status}
 		}
 		fallthrough
 	case response.StatusCode < http.StatusOK || response.StatusCode > http.StatusPartialContent:
-		return nil, fmt.Errorf("request [%#v] failed (%d) %s: %s", request, response.StatusCode, response.Status, string(body))
+		return nil, response.StatusCode, status, retryAfter, fmt.Errorf("request [%#v] failed (%d) %s: %s", request, response.StatusCode, response.Status, string(body))
 	}
 
 	// If the server gave us a status back, look at what it was.
-	if isStatusResponse && status.Status != api.StatusSuccess {
+	if status != nil && status.Status != api.StatusSuccess {
 		// "Working" requests need to be handled specially.
 		// "Failed" requests are clearly just an error and it makes sense to return them as such.
-		return nil, &StatusErr{status}
+		return nil, response.StatusCode, status, retryAfter, &StatusErr{*status}
 	}
-	return body, err
+	return body, response.StatusCode, status, retryAfter, err
 }
 
-// ListPods takes a selector, and returns the list of pods that match that selector.
-func (c *Client) ListPods(selector labels.Selector) (result *api.PodList, err error) {
+// ListPods takes label and field selectors, and returns the list of pods that match them.
+func (c *Client) ListPods(label, field labels.Selector) (result *api.PodList, err error) {
 	result = &api.PodList{}
-	err = c.Get().Path("pods").SelectorParam("labels", selector).Do().Into(result)
+	err = c.Get().Path("pods").SelectorParam("labels", label).SelectorParam("fields", field).Do().Into(result)
 	return
 }
 
 // GetPod takes the id of the pod, and returns the corresponding Pod object, and an error if it occurs
 func (c *Client) GetPod(id string) (result *api.Pod, err error) {
 	result = &api.Pod{}
-	err = c.Get().Path("pods").Path(id).Do().Error()
+	err = c.Get().Path("pods").Path(id).Do().Into(result)
 	return
 }
 
@@ -281,10 +326,21 @@ func (c *Client) UpdatePod(pod *api.Pod) (result *api.Pod, err error) {
 	return
 }
 
-// ListReplicationControllers takes a selector, and returns the list of replication controllers that match that selector.
-func (c *Client) ListReplicationControllers(selector labels.Selector) (result *api.ReplicationControllerList, err error) {
+// WatchPods returns a watch.Interface that watches the requested pods.
+func (c *Client) WatchPods(label, field labels.Selector, resourceVersion uint64) (watch.Interface, error) {
+	return c.Get().
+		Path("watch").
+		Path("pods").
+		UintParam("resourceVersion", resourceVersion).
+		SelectorParam("labels", label).
+		SelectorParam("fields", field).
+		Watch()
+}
+
+// ListReplicationControllers takes label and field selectors, and returns the list of replication controllers that match them.
+func (c *Client) ListReplicationControllers(label, field labels.Selector) (result *api.ReplicationControllerList, err error) {
 	result = &api.ReplicationControllerList{}
-	err = c.Get().Path("replicationControllers").SelectorParam("labels", selector).Do().Into(result)
+	err = c.Get().Path("replicationControllers").SelectorParam("labels", label).SelectorParam("fields", field).Do().Into(result)
 	return
 }
 
@@ -329,10 +385,10 @@ func (c *Client) WatchReplicationControllers(label, field labels.Selector, resou
 		Watch()
 }
 
-// ListServices takes a selector, and returns the list of services that match that selector.
-func (c *Client) ListServices(selector labels.Selector) (result *api.ServiceList, err error) {
+// ListServices takes label and field selectors, and returns the list of services that match them.
+func (c *Client) ListServices(label, field labels.Selector) (result *api.ServiceList, err error) {
 	result = &api.ServiceList{}
-	err = c.Get().Path("services").SelectorParam("labels", selector).Do().Into(result)
+	err = c.Get().Path("services").SelectorParam("labels", label).SelectorParam("fields", field).Do().Into(result)
 	return
 }
 
@@ -377,10 +433,17 @@ func (c *Client) WatchServices(label, field labels.Selector, resourceVersion uin
 		Watch()
 }
 
-// ListEndpoints takes a selector, and returns the list of endpoints that match that selector
-func (c *Client) ListEndpoints(selector labels.Selector) (result *api.EndpointsList, err error) {
+// ListEndpoints takes label and field selectors, and returns the list of endpoints that match them.
+func (c *Client) ListEndpoints(label, field labels.Selector) (result *api.EndpointsList, err error) {
 	result = &api.EndpointsList{}
-	err = c.Get().Path("endpoints").SelectorParam("labels", selector).Do().Into(result)
+	err = c.Get().Path("endpoints").SelectorParam("labels", label).SelectorParam("fields", field).Do().Into(result)
+	return
+}
+
+// GetEndpoints returns the endpoints of a particular service.
+func (c *Client) GetEndpoints(id string) (result *api.Endpoints, err error) {
+	result = &api.Endpoints{}
+	err = c.Get().Path("endpoints").Path(id).Do().Into(result)
 	return
 }
 
@@ -409,9 +472,33 @@ func (c *Client) ServerVersion() (*version.Info, error) {
 	return &info, nil
 }
 
-// ListMinions lists all the minions in the cluster.
-func (c *Client) ListMinions() (result *api.MinionList, err error) {
+// ListMinions takes label and field selectors, and returns the list of minions that match them.
+func (c *Client) ListMinions(label, field labels.Selector) (result *api.MinionList, err error) {
 	result = &api.MinionList{}
-	err = c.Get().Path("minions").Do().Into(result)
+	err = c.Get().Path("minions").SelectorParam("labels", label).SelectorParam("fields", field).Do().Into(result)
+	return
+}
+
+// GetMinion returns information about a particular minion.
+func (c *Client) GetMinion(id string) (result *api.Minion, err error) {
+	result = &api.Minion{}
+	err = c.Get().Path("minions").Path(id).Do().Into(result)
+	return
+}
+
+// CreateMinion registers a new minion.
+func (c *Client) CreateMinion(minion *api.Minion) (result *api.Minion, err error) {
+	result = &api.Minion{}
+	err = c.Post().Path("minions").Body(minion).Do().Into(result)
 	return
 }
+
+// DeleteMinion removes an existing minion.
+func (c *Client) DeleteMinion(id string) error {
+	return c.Delete().Path("minions").Path(id).Do().Error()
+}
+
+// CreateBinding binds a pod to a minion.
+func (c *Client) CreateBinding(binding *api.Binding) error {
+	return c.Post().Path("bindings").Body(binding).Do().Error()
+}
@@ -0,0 +1,105 @@
+package client
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/ryutah/kubernetes-transcribe/pkg/api"
+	"github.com/ryutah/kubernetes-transcribe/pkg/labels"
+	"github.com/ryutah/kubernetes-transcribe/pkg/runtime"
+)
+
+// testRequest is a request received by a test server.
+type testRequest struct {
+	method string
+	path   string
+	query  url.Values
+	body   runtime.Object
+}
+
+func TestClientRequests(t *testing.T) {
+	success := &api.Status{Status: api.StatusSuccess}
+	selector := labels.SelectorFromSet(labels.Set{"app": "web"})
+	field := labels.SelectorFromSet(labels.Set{"host": "h"})
+	table := map[string]struct {
+		call     func(c *Client) (runtime.Object, error)
+		response runtime.Object
+		expect   testRequest
+	}{
+		"GetMinion": {
+			func(c *Client) (runtime.Object, error) { return c.GetMinion("m") },
+			&api.Minion{JSONBase: api.JSONBase{ID: "m"}},
+			testRequest{"GET", "/api/v1beta1/minions/m", url.Values{}, nil},
+		},
+		"CreateMinion": {
+			func(c *Client) (runtime.Object, error) {
+				return c.CreateMinion(&api.Minion{JSONBase: api.JSONBase{ID: "m"}})
+			},
+			&api.Minion{JSONBase: api.JSONBase{ID: "m"}},
+			testRequest{"POST", "/api/v1beta1/minions", url.Values{}, &api.Minion{JSONBase: api.JSONBase{ID: "m"}}},
+		},
+		"DeleteMinion": {
+			func(c *Client) (runtime.Object, error) { return nil, c.DeleteMinion("m") },
+			success,
+			testRequest{"DELETE", "/api/v1beta1/minions/m", url.Values{}, nil},
+		},
+		"ListMinions": {
+			func(c *Client) (runtime.Object, error) { return c.ListMinions(selector, field) },
+			&api.MinionList{},
+			testRequest{"GET", "/api/v1beta1/minions", url.Values{"labels": {"app=web"}, "fields": {"host=h"}}, nil},
+		},
+		"GetEndpoints": {
+			func(c *Client) (runtime.Object, error) { return c.GetEndpoints("s") },
+			&api.Endpoints{JSONBase: api.JSONBase{ID: "s"}},
+			testRequest{"GET", "/api/v1beta1/endpoints/s", url.Values{}, nil},
+		},
+		"ListEndpoints": {
+			func(c *Client) (runtime.Object, error) { return c.ListEndpoints(selector, labels.Everything()) },
+			&api.EndpointsList{},
+			testRequest{"GET", "/api/v1beta1/endpoints", url.Values{"labels": {"app=web"}, "fields": {""}}, nil},
+		},
+		"ListPods": {
+			func(c *Client) (runtime.Object, error) { return c.ListPods(labels.Everything(), field) },
+			&api.PodList{},
+			testRequest{"GET", "/api/v1beta1/pods", url.Values{"labels": {""}, "fields": {"host=h"}}, nil},
+		},
+		"CreateBinding": {
+			func(c *Client) (runtime.Object, error) {
+				return nil, c.CreateBinding(&api.Binding{PodID: "p", Host: "h"})
+			},
+			success,
+			testRequest{"POST", "/api/v1beta1/bindings", url.Values{}, &api.Binding{PodID: "p", Host: "h"}},
+		},
+	}
+	for name, item := range table {
+		requests := make(chan testRequest, 1)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			received := testRequest{method: req.Method, path: req.URL.Path, query: req.URL.Query()}
+			if data, _ := ioutil.ReadAll(req.Body); len(data) > 0 {
+				obj, err := testCodec.Decode(data)
+				if err != nil {
+					t.Errorf("%s: unexpected error: %v", name, err)
+				}
+				received.body = obj
+			}
+			requests <- received
+			w.Write([]byte(runtime.EncodeOrDie(testCodec, item.response)))
+		}))
+		obj, err := item.call(&Client{testRESTClient(t, server)})
+		server.Close()
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", name, err)
+			continue
+		}
+		if e, a := item.expect, <-requests; !reflect.DeepEqual(e, a) {
+			t.Errorf("%s: expected %#v, got %#v", name, e, a)
+		}
+		if obj != nil && !reflect.DeepEqual(item.response, obj) {
+			t.Errorf("%s: expected %#v, got %#v", name, item.response, obj)
+		}
+	}
+}
@@ -10,7 +10,7 @@ import (
 // for a controller's ReplicaSelector equals the Replicas count.
 func (c *Client) ControllerHasDesiredReplicas(controller api.ReplicationController) wait.ConditionFunc {
 	return func() (bool, error) {
-		pods, err := c.ListPods(labels.Set(controller.DesiredState.ReplicaSelector).AsSelector())
+		pods, err := c.ListPods(labels.Set(controller.DesiredState.ReplicaSelector).AsSelector(), labels.Everything())
 		if err != nil {
 			return false, err
 		}
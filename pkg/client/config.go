@@ -0,0 +1,81 @@
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+// Config holds the settings for connecting to an apiserver. See NewFromConfig.
+type Config struct {
+	// Host is a host string, a host:port pair, or an http or https URL, as for New.
+	Host string
+	// Version is the API version to use, or empty to use the client preferred version.
+	Version string
+	// Auth, if not nil, authenticates the client's requests.
+	Auth *AuthInfo
+	// BearerTokenFile is a file holding the bearer token to authenticate requests
+	// with, instead of Auth. The file is read again whenever it changes.
+	BearerTokenFile string
+
+	// CAFile is a PEM file of the certificates to verify the server's certificate
+	// with. If empty, the system's certificates are used.
+	CAFile string
+	// CertFile and KeyFile are PEM files of a certificate and key the client
+	// presents to the server, if not empty.
+	CertFile string
+	KeyFile  string
+	// ServerName is the name to verify the server's certificate against, if it
+	// is not the host the client connects to.
+	ServerName string
+	// Insecure skips verifying the server's certificate.
+	Insecure bool
+
+	// Transport, if not nil, is used to make requests instead of a transport
+	// using the TLS settings above. Credentials are still added to requests.
+	Transport http.RoundTripper
+}
+
+// NewFromConfig creates a Kubernetes client, like New, from config. Unlike New, the
+// client verifies the server's certificate, unless config.Insecure is set.
+func NewFromConfig(config *Config) (*Client, error) {
+	client, err := New(config.Host, config.Version, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := client.setTransport(config); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// tlsConfigFor returns the TLS settings described by config.
+func tlsConfigFor(config *Config) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: config.Insecure,
+		ServerName:         config.ServerName,
+	}
+	if config.CAFile != "" {
+		if config.Insecure {
+			return nil, fmt.Errorf("a CA file cannot be used along with an insecure connection")
+		}
+		data, err := ioutil.ReadFile(config.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates found in %s", config.CAFile)
+		}
+	}
+	if config.CertFile != "" || config.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(config.CertFile, config.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
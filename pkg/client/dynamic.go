@@ -0,0 +1,129 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/ryutah/kubernetes-transcribe/pkg/api"
+	cwatch "github.com/ryutah/kubernetes-transcribe/pkg/client/watch"
+	"github.com/ryutah/kubernetes-transcribe/pkg/labels"
+	"github.com/ryutah/kubernetes-transcribe/pkg/runtime"
+	"github.com/ryutah/kubernetes-transcribe/pkg/watch"
+)
+
+// ResourceClient works with the objects of one resource, whatever their kind. See
+// RESTClient.Resource.
+type ResourceClient struct {
+	c        *RESTClient
+	resource string
+	// Scheme holds the kinds which are decoded into their types, with the client's
+	// codec. Objects of other kinds are decoded into a *runtime.Unstructured, or
+	// a *runtime.UnstructuredList for lists, and objects without a kind into a
+	// *runtime.Unknown, which passes their JSON through. Defaults to api.Scheme.
+	Scheme *runtime.Scheme
+}
+
+// Resource returns a client for the resource with the given name, such as "pods",
+// which returns objects as runtime.Objects, so that tools can work across kinds,
+// including kinds they don't know.
+func (c *RESTClient) Resource(name string) *ResourceClient {
+	return &ResourceClient{c: c, resource: name, Scheme: api.Scheme}
+}
+
+// List returns the objects matching the label and field selectors.
+func (r *ResourceClient) List(label, field labels.Selector) (runtime.Object, error) {
+	return r.do(r.c.Get().Path(r.resource).SelectorParam("labels", label).SelectorParam("fields", field))
+}
+
+// Get returns the object with the given ID.
+func (r *ResourceClient) Get(id string) (runtime.Object, error) {
+	return r.do(r.c.Get().Path(r.resource).Path(id))
+}
+
+// Create creates obj, and returns the server's representation of it.
+func (r *ResourceClient) Create(obj runtime.Object) (runtime.Object, error) {
+	data, err := r.encode(obj)
+	if err != nil {
+		return nil, err
+	}
+	return r.do(r.c.Post().Path(r.resource).Body(data))
+}
+
+// Update updates obj, which must carry its ID and resource version, and returns
+// the server's representation of it.
+func (r *ResourceClient) Update(obj runtime.Object) (runtime.Object, error) {
+	jsonBase, err := runtime.FindJSONBase(obj)
+	if err != nil {
+		return nil, err
+	}
+	if jsonBase.ResourceVersion() == 0 {
+		return nil, fmt.Errorf("invalid update object, missing resource version: %v", obj)
+	}
+	data, err := r.encode(obj)
+	if err != nil {
+		return nil, err
+	}
+	return r.do(r.c.Put().Path(r.resource).Path(jsonBase.ID()).Body(data))
+}
+
+// Delete deletes the object with the given ID.
+func (r *ResourceClient) Delete(id string) error {
+	return r.c.Delete().Path(r.resource).Path(id).Do().Error()
+}
+
+// Watch watches the objects matching the label and field selectors, from the
+// given resource version.
+func (r *ResourceClient) Watch(label, field labels.Selector, resourceVersion uint64) (watch.Interface, error) {
+	return r.c.Get().
+		Path("watch").
+		Path(r.resource).
+		UintParam("resourceVersion", resourceVersion).
+		SelectorParam("labels", label).
+		SelectorParam("fields", field).
+		watch(func(stream io.ReadCloser) watch.Decoder {
+			return cwatch.NewObjectEventDecoder(stream, r.decode)
+		})
+}
+
+// do makes request, and decodes the object it returns.
+func (r *ResourceClient) do(request *Request) (runtime.Object, error) {
+	body, err := request.Do().Raw()
+	if err != nil {
+		return nil, err
+	}
+	return r.decode(body)
+}
+
+// decode decodes data with the client's codec if its kind is known to r.Scheme,
+// into an unknown object holding data if it has no kind, and into an unstructured
+// object otherwise.
+func (r *ResourceClient) decode(data []byte) (runtime.Object, error) {
+	version, kind, err := runtime.VersionAndKind(data)
+	if err != nil {
+		return nil, err
+	}
+	if kind == "" {
+		unknown := &runtime.Unknown{RawJSON: data}
+		if err := json.Unmarshal(data, &unknown.JSONBase); err != nil {
+			return nil, err
+		}
+		return unknown, nil
+	}
+	if _, ok := r.Scheme.KnownTypes(version)[kind]; ok {
+		return r.c.Codec.Decode(data)
+	}
+	return runtime.DecodeUnstructured(data)
+}
+
+// encode encodes obj for the server: unstructured objects as they are, unknown
+// objects as the JSON they hold, and others with the client's codec.
+func (r *ResourceClient) encode(obj runtime.Object) ([]byte, error) {
+	switch t := obj.(type) {
+	case *runtime.Unstructured:
+		return json.Marshal(t)
+	case *runtime.Unknown:
+		return t.RawJSON, nil
+	}
+	return r.c.Codec.Encode(obj)
+}
@@ -0,0 +1,169 @@
+package client
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/ryutah/kubernetes-transcribe/pkg/api"
+	"github.com/ryutah/kubernetes-transcribe/pkg/labels"
+	"github.com/ryutah/kubernetes-transcribe/pkg/runtime"
+	"github.com/ryutah/kubernetes-transcribe/pkg/watch"
+)
+
+// resourceServer serves the given body for each "METHOD path", and records the
+// bodies of the requests it gets.
+func resourceServer(t *testing.T, responses map[string]string) (*httptest.Server, map[string]string) {
+	requests := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		key := req.Method + " " + req.URL.Path
+		body, _ := ioutil.ReadAll(req.Body)
+		requests[key] = string(body)
+		response, ok := responses[key]
+		if !ok {
+			t.Errorf("unexpected request: %s", key)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(response))
+	}))
+	return server, requests
+}
+
+func resourceClient(t *testing.T, server *httptest.Server, resource string) *ResourceClient {
+	// The versioned API types are not registered yet, so tests use the internal version.
+	c, err := NewRESTClient(server.URL, nil, "/api/v1beta1", runtime.CodecFor(api.Scheme, ""))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return c.Resource(resource)
+}
+
+func TestResourceClientDecodes(t *testing.T) {
+	server, _ := resourceServer(t, map[string]string{
+		"GET /api/v1beta1/pods/foo":     `{"kind":"Pod","id":"foo","resourceVersion":3}`,
+		"GET /api/v1beta1/widgets/foo":  `{"kind":"Widget","id":"foo","spec":{"size":3}}`,
+		"GET /api/v1beta1/widgets":      `{"kind":"WidgetList","resourceVersion":5}`,
+		"GET /api/v1beta1/widgets/bare": `{"id":"bare","size":3}`,
+	})
+	defer server.Close()
+
+	obj, err := resourceClient(t, server, "pods").Get("foo")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pod, ok := obj.(*api.Pod); !ok || pod.ID != "foo" || pod.ResourceVersion != 3 {
+		t.Errorf("expected pod foo, got %#v", obj)
+	}
+
+	widgets := resourceClient(t, server, "widgets")
+	obj, err = widgets.Get("foo")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if u, ok := obj.(*runtime.Unstructured); !ok || u.ID != "foo" || u.Object["spec"] == nil {
+		t.Errorf("expected unstructured widget foo, got %#v", obj)
+	}
+
+	obj, err = widgets.List(labels.Everything(), labels.Everything())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	items, err := runtime.ExtractList(obj)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 0 {
+		t.Errorf("unexpected items: %#v", items)
+	}
+
+	obj, err = widgets.Get("bare")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	unknown, ok := obj.(*runtime.Unknown)
+	if !ok {
+		t.Fatalf("expected *runtime.Unknown, got %#v", obj)
+	}
+	if e, a := "bare", unknown.ID; e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+	if e, a := `{"id":"bare","size":3}`, string(unknown.RawJSON); e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+}
+
+func TestResourceClientWrites(t *testing.T) {
+	server, requests := resourceServer(t, map[string]string{
+		"POST /api/v1beta1/widgets":       `{"kind":"Widget","id":"foo","resourceVersion":1}`,
+		"PUT /api/v1beta1/widgets/foo":    `{"kind":"Widget","id":"foo","resourceVersion":2}`,
+		"POST /api/v1beta1/things":        `{"id":"bar"}`,
+		"DELETE /api/v1beta1/widgets/foo": `{"kind":"Status","status":"success"}`,
+	})
+	defer server.Close()
+	widgets := resourceClient(t, server, "widgets")
+
+	widget := &runtime.Unstructured{
+		JSONBase: runtime.JSONBase{Kind: "Widget", ID: "foo"},
+		Object:   map[string]interface{}{"spec": map[string]interface{}{"size": 3}},
+	}
+	obj, err := widgets.Create(widget)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e, a := `{"id":"foo","kind":"Widget","spec":{"size":3}}`, requests["POST /api/v1beta1/widgets"]; e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+
+	if _, err := widgets.Update(widget); err == nil {
+		t.Errorf("expected an error updating without a resource version")
+	}
+	if _, err := widgets.Update(obj); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e, a := `{"id":"foo","kind":"Widget","resourceVersion":1}`, requests["PUT /api/v1beta1/widgets/foo"]; e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+
+	// Unknown objects pass through as they are.
+	raw := `{"id":"bar", "size":3}`
+	if _, err := resourceClient(t, server, "things").Create(&runtime.Unknown{RawJSON: []byte(raw)}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e, a := raw, requests["POST /api/v1beta1/things"]; e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+
+	if err := widgets.Delete("foo"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := requests["DELETE /api/v1beta1/widgets/foo"]; !ok {
+		t.Errorf("expected a delete request")
+	}
+}
+
+func TestResourceClientWatch(t *testing.T) {
+	server, _ := resourceServer(t, map[string]string{
+		"GET /api/v1beta1/watch/widgets": `{"type":"ADDED","object":{"kind":"Widget","id":"foo"}}
+{"type":"DELETED","object":{"kind":"Pod","id":"bar"}}
+`,
+	})
+	defer server.Close()
+
+	w, err := resourceClient(t, server, "widgets").Watch(labels.Everything(), labels.Everything(), 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer w.Stop()
+	event := <-w.ResultChan()
+	if u, ok := event.Object.(*runtime.Unstructured); event.Type != watch.Added || !ok || u.ID != "foo" {
+		t.Errorf("unexpected event: %#v", event)
+	}
+	event = <-w.ResultChan()
+	if e, a := (watch.Event{Type: watch.Deleted, Object: &api.Pod{JSONBase: api.JSONBase{ID: "bar"}}}), event; !reflect.DeepEqual(e, a) {
+		t.Errorf("expected %#v, got %#v", e, a)
+	}
+}
@@ -0,0 +1,84 @@
+package client
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+
+	"golang.org/x/net/websocket"
+
+	"github.com/ryutah/kubernetes-transcribe/pkg/util/wsstream"
+)
+
+// Exec runs command in a container of a pod, through the apiserver, and returns
+// once it exits. stdin, if not nil, is sent to the command; its output is written
+// to stdout and stderr, either of which may be nil to discard it. Passing
+// os.Stdin, os.Stdout and os.Stderr wires the command to the local terminal.
+// container may be empty if the pod has a single container.
+func (c *RESTClient) Exec(podID, container string, command []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	query := url.Values{"command": command}
+	return c.stream("exec", podID, container, query, stdin, stdout, stderr)
+}
+
+// Attach is like Exec, but connects to the main process of the container.
+func (c *RESTClient) Attach(podID, container string, stdin io.Reader, stdout, stderr io.Writer) error {
+	return c.stream("attach", podID, container, url.Values{}, stdin, stdout, stderr)
+}
+
+// stream opens a websocket to /pods/<podID>/<action>, and copies the streams of the
+// process at the other end to and from the given readers and writers.
+func (c *RESTClient) stream(action, podID, container string, query url.Values, stdin io.Reader, stdout, stderr io.Writer) error {
+	if container != "" {
+		query.Set("container", container)
+	}
+	ws, err := c.dialPod(action, podID, query)
+	if err != nil {
+		return err
+	}
+	defer ws.Close()
+	ws.PayloadType = websocket.BinaryFrame
+
+	if stdin != nil {
+		go wsstream.CopyIn(ws, stdin)
+	} else {
+		// Tell the process there is no input.
+		if err := wsstream.Write(ws, wsstream.StdinChannel, nil); err != nil {
+			return err
+		}
+	}
+	return wsstream.CopyOut(ws, stdout, stderr)
+}
+
+// dialPod opens a websocket to /pods/<podID>/<action>?<query>.
+func (c *RESTClient) dialPod(action, podID string, query url.Values) (*websocket.Conn, error) {
+	location, err := url.Parse(c.host)
+	if err != nil {
+		return nil, err
+	}
+	origin := *location
+	if c.secure {
+		location.Scheme = "wss"
+	} else {
+		location.Scheme = "ws"
+	}
+	location.Path = path.Join(c.prefix, "pods", podID, action)
+	location.RawQuery = query.Encode()
+
+	config, err := websocket.NewConfig(location.String(), origin.String())
+	if err != nil {
+		return nil, err
+	}
+	// Websockets can't go through the client's transport, so take its credentials.
+	req := &http.Request{Header: http.Header{}}
+	if err := authorize(c.httpClient.Transport, req); err != nil {
+		return nil, err
+	}
+	for key, values := range req.Header {
+		config.Header[key] = values
+	}
+	if c.secure {
+		config.TlsConfig = c.tlsConfig
+	}
+	return websocket.DialConfig(config)
+}
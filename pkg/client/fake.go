@@ -1,7 +1,10 @@
 package client
 
 import (
+	"sync"
+
 	"github.com/ryutah/kubernetes-transcribe/pkg/api"
+	"github.com/ryutah/kubernetes-transcribe/pkg/api/errors"
 	"github.com/ryutah/kubernetes-transcribe/pkg/labels"
 	"github.com/ryutah/kubernetes-transcribe/pkg/version"
 	"github.com/ryutah/kubernetes-transcribe/pkg/watch"
@@ -12,122 +15,210 @@ type FakeAction struct {
 	Value  interface{}
 }
 
+// FakeSelectors is the Value of the list actions of the fakes.
+type FakeSelectors struct {
+	Label string
+	Field string
+}
+
+// selectorsOf returns the FakeSelectors of a list with label and field.
+func selectorsOf(label, field labels.Selector) FakeSelectors {
+	var selectors FakeSelectors
+	if label != nil {
+		selectors.Label = label.String()
+	}
+	if field != nil {
+		selectors.Field = field.String()
+	}
+	return selectors
+}
+
 // Fake implements Interface. Meant to be embedded into a struct to get a default
 // implementation. This makes faking out just the method you want to test easier.
+// For a fake which keeps track of the objects written through it, see SimpleFake.
 type Fake struct {
 	// Fake by default keeps a simple list of the methods that have been called.
+	// Read it with FakeActions when methods may still be called concurrently.
 	Actions       []FakeAction
 	Pods          api.PodList
 	Ctrl          api.ReplicationController
 	ServiceList   api.ServiceList
 	EndpointsList api.EndpointsList
 	Minions       api.MinionList
+	Bindings      []api.Binding
 	Err           error
 	Watch         watch.Interface
+
+	lock sync.Mutex
 }
 
-func (c *Fake) ListPods(selector labels.Selector) (*api.PodList, error) {
-	c.Actions = append(c.Actions, FakeAction{Action: "list-pods"})
+// record appends action to c.Actions.
+func (c *Fake) record(action FakeAction) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.Actions = append(c.Actions, action)
+}
+
+// FakeActions returns a copy of c.Actions.
+func (c *Fake) FakeActions() []FakeAction {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return append([]FakeAction(nil), c.Actions...)
+}
+
+func (c *Fake) ListPods(label, field labels.Selector) (*api.PodList, error) {
+	c.record(FakeAction{Action: "list-pods", Value: selectorsOf(label, field)})
 	return api.Scheme.CopyOrDie(&c.Pods).(*api.PodList), nil
 }
 
 func (c *Fake) GetPod(name string) (*api.Pod, error) {
-	c.Actions = append(c.Actions, FakeAction{Action: "get-pod", Value: name})
+	c.record(FakeAction{Action: "get-pod", Value: name})
 	return &api.Pod{}, nil
 }
 
 func (c *Fake) DeletePod(name string) error {
-	c.Actions = append(c.Actions, FakeAction{Action: "delete-pod", Value: name})
+	c.record(FakeAction{Action: "delete-pod", Value: name})
 	return nil
 }
 
 func (c *Fake) CreatePod(*api.Pod) (*api.Pod, error) {
-	c.Actions = append(c.Actions, FakeAction{Action: "create-pod"})
+	c.record(FakeAction{Action: "create-pod"})
 	return &api.Pod{}, nil
 }
 
 func (c *Fake) UpdatePod(pod *api.Pod) (*api.Pod, error) {
-	c.Actions = append(c.Actions, FakeAction{Action: "update-pod", Value: pod.ID})
+	c.record(FakeAction{Action: "update-pod", Value: pod.ID})
 	return &api.Pod{}, nil
 }
 
-func (c *Fake) ListReplicationControllers(selector labels.Selector) (*api.ReplicationControllerList, error) {
-	c.Actions = append(c.Actions, FakeAction{Action: "list-controllers"})
+func (c *Fake) WatchPods(label, field labels.Selector, resourceVersion uint64) (watch.Interface, error) {
+	c.record(FakeAction{Action: "watch-pods", Value: resourceVersion})
+	return c.Watch, c.Err
+}
+
+func (c *Fake) ListReplicationControllers(label, field labels.Selector) (*api.ReplicationControllerList, error) {
+	c.record(FakeAction{Action: "list-controllers", Value: selectorsOf(label, field)})
 	return &api.ReplicationControllerList{}, nil
 }
 
 func (c *Fake) GetReplicationController(name string) (*api.ReplicationController, error) {
-	c.Actions = append(c.Actions, FakeAction{Action: "get-controller", Value: name})
+	c.record(FakeAction{Action: "get-controller", Value: name})
 	return api.Scheme.CopyOrDie(&c.Ctrl).(*api.ReplicationController), nil
 }
 
 func (c *Fake) CreateReplicationController(controller *api.ReplicationController) (*api.ReplicationController, error) {
-	c.Actions = append(c.Actions, FakeAction{Action: "create-controller", Value: controller})
+	c.record(FakeAction{Action: "create-controller", Value: controller})
 	return &api.ReplicationController{}, nil
 }
 
 func (c *Fake) UpdateReplicationController(controller *api.ReplicationController) (*api.ReplicationController, error) {
-	c.Actions = append(c.Actions, FakeAction{Action: "update-controller", Value: controller})
+	c.record(FakeAction{Action: "update-controller", Value: controller})
 	return &api.ReplicationController{}, nil
 }
 
 func (c *Fake) DeleteReplicationController(controller string) error {
-	c.Actions = append(c.Actions, FakeAction{Action: "delete-controller", Value: controller})
+	c.record(FakeAction{Action: "delete-controller", Value: controller})
 	return nil
 }
 
 func (c *Fake) WatchReplicationControllers(label labels.Selector, field labels.Selector, resourceVersion uint64) (watch.Interface, error) {
-	c.Actions = append(c.Actions, FakeAction{Action: "watch-controllers", Value: resourceVersion})
+	c.record(FakeAction{Action: "watch-controllers", Value: resourceVersion})
 	return c.Watch, nil
 }
 
-func (c *Fake) ListServices(selector labels.Selector) (*api.ServiceList, error) {
-	c.Actions = append(c.Actions, FakeAction{Action: "list-services"})
+func (c *Fake) ListServices(label, field labels.Selector) (*api.ServiceList, error) {
+	c.record(FakeAction{Action: "list-services", Value: selectorsOf(label, field)})
 	return &c.ServiceList, c.Err
 }
 
 func (c *Fake) GetService(name string) (*api.Service, error) {
-	c.Actions = append(c.Actions, FakeAction{Action: "get-service", Value: name})
+	c.record(FakeAction{Action: "get-service", Value: name})
 	return &api.Service{}, nil
 }
 
 func (c *Fake) CreateService(service *api.Service) (*api.Service, error) {
-	c.Actions = append(c.Actions, FakeAction{Action: "create-service", Value: service})
+	c.record(FakeAction{Action: "create-service", Value: service})
 	return &api.Service{}, nil
 }
 
 func (c *Fake) UpdateService(service *api.Service) (*api.Service, error) {
-	c.Actions = append(c.Actions, FakeAction{Action: "update-service", Value: service})
+	c.record(FakeAction{Action: "update-service", Value: service})
 	return &api.Service{}, nil
 }
 
 func (c *Fake) DeleteService(service string) error {
-	c.Actions = append(c.Actions, FakeAction{Action: "delete-service", Value: service})
+	c.record(FakeAction{Action: "delete-service", Value: service})
 	return nil
 }
 
 func (c *Fake) WatchServices(label labels.Selector, field labels.Selector, resourceVersion uint64) (watch.Interface, error) {
-	c.Actions = append(c.Actions, FakeAction{Action: "watch-services", Value: resourceVersion})
+	c.record(FakeAction{Action: "watch-services", Value: resourceVersion})
 	return c.Watch, c.Err
 }
 
-func (c *Fake) ListEndpoints(selector labels.Selector) (*api.EndpointsList, error) {
-	c.Actions = append(c.Actions, FakeAction{Action: "list-endpoints"})
+func (c *Fake) ListEndpoints(label, field labels.Selector) (*api.EndpointsList, error) {
+	c.record(FakeAction{Action: "list-endpoints", Value: selectorsOf(label, field)})
 	return api.Scheme.CopyOrDie(&c.EndpointsList).(*api.EndpointsList), c.Err
 }
 
+func (c *Fake) GetEndpoints(name string) (*api.Endpoints, error) {
+	c.record(FakeAction{Action: "get-endpoints", Value: name})
+	if c.Err != nil {
+		return nil, c.Err
+	}
+	for i := range c.EndpointsList.Items {
+		if c.EndpointsList.Items[i].ID == name {
+			return api.Scheme.CopyOrDie(&c.EndpointsList.Items[i]).(*api.Endpoints), nil
+		}
+	}
+	return nil, errors.NewNotFound("endpoints", name)
+}
+
 func (c *Fake) WatchEndpoints(label, field labels.Selector, resourceVersion uint64) (watch.Interface, error) {
-	c.Actions = append(c.Actions, FakeAction{Action: "watch-endpoints", Value: resourceVersion})
+	c.record(FakeAction{Action: "watch-endpoints", Value: resourceVersion})
 	return c.Watch, c.Err
 }
 
-func (c *Fake) ServiceVersion() (*version.Info, error) {
-	c.Actions = append(c.Actions, FakeAction{Action: "get-version", Value: nil})
+func (c *Fake) ServerVersion() (*version.Info, error) {
+	c.record(FakeAction{Action: "get-version", Value: nil})
 	versionInfo := version.Get()
 	return &versionInfo, nil
 }
 
-func (c *Fake) ListMinions() (*api.MinionList, error) {
-	c.Actions = append(c.Actions, FakeAction{Action: "list-minions", Value: nil})
+func (c *Fake) ListMinions(label, field labels.Selector) (*api.MinionList, error) {
+	c.record(FakeAction{Action: "list-minions", Value: selectorsOf(label, field)})
 	return &c.Minions, nil
 }
+
+func (c *Fake) GetMinion(name string) (*api.Minion, error) {
+	c.record(FakeAction{Action: "get-minion", Value: name})
+	if c.Err != nil {
+		return nil, c.Err
+	}
+	for i := range c.Minions.Items {
+		if c.Minions.Items[i].ID == name {
+			return api.Scheme.CopyOrDie(&c.Minions.Items[i]).(*api.Minion), nil
+		}
+	}
+	return nil, errors.NewNotFound("minion", name)
+}
+
+func (c *Fake) CreateMinion(minion *api.Minion) (*api.Minion, error) {
+	c.record(FakeAction{Action: "create-minion", Value: minion})
+	return &api.Minion{}, c.Err
+}
+
+func (c *Fake) DeleteMinion(name string) error {
+	c.record(FakeAction{Action: "delete-minion", Value: name})
+	return c.Err
+}
+
+func (c *Fake) CreateBinding(binding *api.Binding) error {
+	c.record(FakeAction{Action: "create-binding", Value: binding})
+	if c.Err == nil {
+		c.lock.Lock()
+		defer c.lock.Unlock()
+		c.Bindings = append(c.Bindings, *binding)
+	}
+	return c.Err
+}
@@ -0,0 +1,58 @@
+package client
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ryutah/kubernetes-transcribe/pkg/api"
+	"github.com/ryutah/kubernetes-transcribe/pkg/api/errors"
+	"github.com/ryutah/kubernetes-transcribe/pkg/labels"
+)
+
+var _ Interface = &Fake{}
+
+func TestFakeRecordsSelectors(t *testing.T) {
+	c := &Fake{}
+	label := labels.SelectorFromSet(labels.Set{"app": "web"})
+	field := labels.SelectorFromSet(labels.Set{"host": "h"})
+	c.ListPods(label, labels.Everything())
+	c.ListMinions(labels.Everything(), field)
+	c.ListEndpoints(label, field)
+
+	expected := []FakeAction{
+		{Action: "list-pods", Value: FakeSelectors{Label: "app=web"}},
+		{Action: "list-minions", Value: FakeSelectors{Field: "host=h"}},
+		{Action: "list-endpoints", Value: FakeSelectors{Label: "app=web", Field: "host=h"}},
+	}
+	if e, a := expected, c.FakeActions(); !reflect.DeepEqual(e, a) {
+		t.Errorf("expected %#v, got %#v", e, a)
+	}
+}
+
+func TestFakeGets(t *testing.T) {
+	c := &Fake{
+		Minions:       api.MinionList{Items: []api.Minion{{JSONBase: api.JSONBase{ID: "m"}, HostIP: "1.2.3.4"}}},
+		EndpointsList: api.EndpointsList{Items: []api.Endpoints{{JSONBase: api.JSONBase{ID: "s"}, Endpoints: []string{"1.2.3.4:80"}}}},
+	}
+	minion, err := c.GetMinion("m")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e, a := c.Minions.Items[0], *minion; !reflect.DeepEqual(e, a) {
+		t.Errorf("expected %#v, got %#v", e, a)
+	}
+	endpoints, err := c.GetEndpoints("s")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e, a := c.EndpointsList.Items[0], *endpoints; !reflect.DeepEqual(e, a) {
+		t.Errorf("expected %#v, got %#v", e, a)
+	}
+
+	if _, err := c.GetMinion("other"); !errors.IsNotFound(err) {
+		t.Errorf("expected not found, got %v", err)
+	}
+	if _, err := c.GetEndpoints("other"); !errors.IsNotFound(err) {
+		t.Errorf("expected not found, got %v", err)
+	}
+}
@@ -0,0 +1,199 @@
+package client
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v2"
+)
+
+// KubeConfigEnv names the environment variable which, if set, overrides the
+// default location of the configuration file read by LoadKubeConfig.
+const KubeConfigEnv = "KUBECONFIG"
+
+// KubeConfig is the contents of a client configuration file, which lists named
+// clusters, users to connect to them as, and contexts pairing the two. A file
+// looks like this:
+//
+//	current-context: staging
+//	clusters:
+//	- name: staging
+//	  cluster:
+//	    server: https://10.0.0.1
+//	    certificate-authority: ca.crt
+//	users:
+//	- name: admin
+//	  user:
+//	    token: 31ada4fd-adec-460c-809a-9e56ceb75269
+//	contexts:
+//	- name: staging
+//	  context:
+//	    cluster: staging
+//	    user: admin
+//
+// Relative paths of files are relative to the directory of the configuration file.
+type KubeConfig struct {
+	Clusters       []NamedKubeCluster `yaml:"clusters"`
+	Users          []NamedKubeUser    `yaml:"users"`
+	Contexts       []NamedKubeContext `yaml:"contexts"`
+	CurrentContext string             `yaml:"current-context"`
+}
+
+// KubeCluster describes how to reach an apiserver.
+type KubeCluster struct {
+	Server                string `yaml:"server"`
+	APIVersion            string `yaml:"api-version"`
+	CertificateAuthority  string `yaml:"certificate-authority"`
+	TLSServerName         string `yaml:"tls-server-name"`
+	InsecureSkipTLSVerify bool   `yaml:"insecure-skip-tls-verify"`
+}
+
+// KubeUser holds the credentials of a user: a username and password, a bearer
+// token or a file holding one, a client certificate and key, or nothing at all.
+type KubeUser struct {
+	Username          string `yaml:"username"`
+	Password          string `yaml:"password"`
+	Token             string `yaml:"token"`
+	TokenFile         string `yaml:"token-file"`
+	ClientCertificate string `yaml:"client-certificate"`
+	ClientKey         string `yaml:"client-key"`
+}
+
+// KubeContext names the cluster to connect to, and the user to connect as.
+type KubeContext struct {
+	Cluster string `yaml:"cluster"`
+	User    string `yaml:"user"`
+}
+
+// NamedKubeCluster is a KubeCluster, and its name.
+type NamedKubeCluster struct {
+	Name    string      `yaml:"name"`
+	Cluster KubeCluster `yaml:"cluster"`
+}
+
+// NamedKubeUser is a KubeUser, and its name.
+type NamedKubeUser struct {
+	Name string   `yaml:"name"`
+	User KubeUser `yaml:"user"`
+}
+
+// NamedKubeContext is a KubeContext, and its name.
+type NamedKubeContext struct {
+	Name    string      `yaml:"name"`
+	Context KubeContext `yaml:"context"`
+}
+
+// KubeConfigPath returns the configuration file to read: path, if not empty,
+// else the file named by $KUBECONFIG, else ~/.kube/config.
+func KubeConfigPath(path string) string {
+	if path != "" {
+		return path
+	}
+	if path := os.Getenv(KubeConfigEnv); path != "" {
+		return path
+	}
+	return filepath.Join(os.Getenv("HOME"), ".kube", "config")
+}
+
+// LoadKubeConfig reads the configuration file found by KubeConfigPath(path).
+func LoadKubeConfig(path string) (*KubeConfig, error) {
+	path = KubeConfigPath(path)
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config := &KubeConfig{}
+	if err := yaml.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("invalid configuration file %s: %v", path, err)
+	}
+	config.resolvePaths(filepath.Dir(path))
+	return config, nil
+}
+
+// resolvePaths makes the paths of files in config relative to dir absolute.
+func (config *KubeConfig) resolvePaths(dir string) {
+	resolve := func(path *string) {
+		if *path != "" && !filepath.IsAbs(*path) {
+			*path = filepath.Join(dir, *path)
+		}
+	}
+	for i := range config.Clusters {
+		resolve(&config.Clusters[i].Cluster.CertificateAuthority)
+	}
+	for i := range config.Users {
+		resolve(&config.Users[i].User.TokenFile)
+		resolve(&config.Users[i].User.ClientCertificate)
+		resolve(&config.Users[i].User.ClientKey)
+	}
+}
+
+// ClientConfig returns the settings for connecting to the cluster of the named
+// context, as its user. An empty name means the current context.
+func (config *KubeConfig) ClientConfig(contextName string) (*Config, error) {
+	if contextName == "" {
+		contextName = config.CurrentContext
+	}
+	if contextName == "" {
+		return nil, fmt.Errorf("no context given, and no current context set")
+	}
+	var context *KubeContext
+	for i := range config.Contexts {
+		if config.Contexts[i].Name == contextName {
+			context = &config.Contexts[i].Context
+		}
+	}
+	if context == nil {
+		return nil, fmt.Errorf("context %q not found", contextName)
+	}
+	var cluster *KubeCluster
+	for i := range config.Clusters {
+		if config.Clusters[i].Name == context.Cluster {
+			cluster = &config.Clusters[i].Cluster
+		}
+	}
+	if cluster == nil {
+		return nil, fmt.Errorf("cluster %q of context %q not found", context.Cluster, contextName)
+	}
+	user := &KubeUser{}
+	if context.User != "" {
+		user = nil
+		for i := range config.Users {
+			if config.Users[i].Name == context.User {
+				user = &config.Users[i].User
+			}
+		}
+		if user == nil {
+			return nil, fmt.Errorf("user %q of context %q not found", context.User, contextName)
+		}
+	}
+
+	clientConfig := &Config{
+		Host:            cluster.Server,
+		Version:         cluster.APIVersion,
+		CAFile:          cluster.CertificateAuthority,
+		ServerName:      cluster.TLSServerName,
+		Insecure:        cluster.InsecureSkipTLSVerify,
+		CertFile:        user.ClientCertificate,
+		KeyFile:         user.ClientKey,
+		BearerTokenFile: user.TokenFile,
+	}
+	if user.Username != "" || user.Password != "" || user.Token != "" {
+		clientConfig.Auth = &AuthInfo{
+			User:        user.Username,
+			Password:    user.Password,
+			BearerToken: user.Token,
+		}
+	}
+	return clientConfig, nil
+}
+
+// Client returns a client for the named context; see ClientConfig.
+func (config *KubeConfig) Client(contextName string) (*Client, error) {
+	clientConfig, err := config.ClientConfig(contextName)
+	if err != nil {
+		return nil, err
+	}
+	return NewFromConfig(clientConfig)
+}
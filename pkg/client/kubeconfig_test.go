@@ -0,0 +1,205 @@
+package client
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const testKubeConfig = `current-context: staging
+clusters:
+- name: staging
+  cluster:
+    server: https://10.0.0.1
+    api-version: v1beta1
+    certificate-authority: ca.crt
+    tls-server-name: kubernetes
+- name: local
+  cluster:
+    server: http://localhost:8080
+    insecure-skip-tls-verify: true
+users:
+- name: admin
+  user:
+    token: secret
+    client-certificate: /etc/kube/admin.crt
+    client-key: keys/admin.key
+- name: robot
+  user:
+    token-file: robot.token
+- name: basic
+  user:
+    username: user
+    password: pass
+contexts:
+- name: staging
+  context:
+    cluster: staging
+    user: admin
+- name: robot
+  context:
+    cluster: staging
+    user: robot
+- name: local
+  context:
+    cluster: local
+- name: basic
+  context:
+    cluster: local
+    user: basic
+- name: no-cluster
+  context:
+    cluster: missing
+- name: no-user
+  context:
+    cluster: local
+    user: missing
+`
+
+// writeKubeConfig writes data to a config file in a new directory, and returns
+// its path.
+func writeKubeConfig(t *testing.T, data string) string {
+	dir, err := ioutil.TempDir("", "kubeconfig")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	path := filepath.Join(dir, "config")
+	if err := ioutil.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return path
+}
+
+func TestLoadKubeConfig(t *testing.T) {
+	path := writeKubeConfig(t, testKubeConfig)
+	defer os.RemoveAll(filepath.Dir(path))
+	dir := filepath.Dir(path)
+
+	config, err := LoadKubeConfig(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e, a := "staging", config.CurrentContext; e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+	expectedCluster := KubeCluster{
+		Server:               "https://10.0.0.1",
+		APIVersion:           "v1beta1",
+		CertificateAuthority: filepath.Join(dir, "ca.crt"),
+		TLSServerName:        "kubernetes",
+	}
+	if e, a := expectedCluster, config.Clusters[0].Cluster; e != a {
+		t.Errorf("expected %#v, got %#v", e, a)
+	}
+	// Only relative paths are resolved.
+	expectedUser := KubeUser{
+		Token:             "secret",
+		ClientCertificate: "/etc/kube/admin.crt",
+		ClientKey:         filepath.Join(dir, "keys/admin.key"),
+	}
+	if e, a := expectedUser, config.Users[0].User; e != a {
+		t.Errorf("expected %#v, got %#v", e, a)
+	}
+	if e, a := filepath.Join(dir, "robot.token"), config.Users[1].User.TokenFile; e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+}
+
+func TestLoadKubeConfigErrors(t *testing.T) {
+	path := writeKubeConfig(t, "clusters: [")
+	defer os.RemoveAll(filepath.Dir(path))
+	if _, err := LoadKubeConfig(path); err == nil {
+		t.Errorf("expected an error for invalid YAML")
+	}
+	if _, err := LoadKubeConfig(filepath.Join(filepath.Dir(path), "missing")); err == nil {
+		t.Errorf("expected an error for a missing file")
+	}
+}
+
+func TestKubeConfigPath(t *testing.T) {
+	defer os.Setenv(KubeConfigEnv, os.Getenv(KubeConfigEnv))
+	defer os.Setenv("HOME", os.Getenv("HOME"))
+	os.Setenv("HOME", "/home/user")
+
+	os.Setenv(KubeConfigEnv, "")
+	if e, a := "/home/user/.kube/config", KubeConfigPath(""); e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+	os.Setenv(KubeConfigEnv, "/from/env")
+	if e, a := "/from/env", KubeConfigPath(""); e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+	if e, a := "/given", KubeConfigPath("/given"); e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+
+	path := writeKubeConfig(t, testKubeConfig)
+	defer os.RemoveAll(filepath.Dir(path))
+	os.Setenv(KubeConfigEnv, path)
+	config, err := LoadKubeConfig("")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e, a := "staging", config.CurrentContext; e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+}
+
+func TestKubeConfigClientConfig(t *testing.T) {
+	path := writeKubeConfig(t, testKubeConfig)
+	defer os.RemoveAll(filepath.Dir(path))
+	dir := filepath.Dir(path)
+	config, err := LoadKubeConfig(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	table := map[string]*Config{
+		"": {
+			Host:       "https://10.0.0.1",
+			Version:    "v1beta1",
+			CAFile:     filepath.Join(dir, "ca.crt"),
+			ServerName: "kubernetes",
+			CertFile:   "/etc/kube/admin.crt",
+			KeyFile:    filepath.Join(dir, "keys/admin.key"),
+			Auth:       &AuthInfo{BearerToken: "secret"},
+		},
+		"robot": {
+			Host:            "https://10.0.0.1",
+			Version:         "v1beta1",
+			CAFile:          filepath.Join(dir, "ca.crt"),
+			ServerName:      "kubernetes",
+			BearerTokenFile: filepath.Join(dir, "robot.token"),
+		},
+		"local": {
+			Host:     "http://localhost:8080",
+			Insecure: true,
+		},
+		"basic": {
+			Host:     "http://localhost:8080",
+			Insecure: true,
+			Auth:     &AuthInfo{User: "user", Password: "pass"},
+		},
+	}
+	for name, expected := range table {
+		clientConfig, err := config.ClientConfig(name)
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %v", name, err)
+		}
+		if !reflect.DeepEqual(expected, clientConfig) {
+			t.Errorf("%q: expected %#v, got %#v", name, expected, clientConfig)
+		}
+	}
+
+	for _, name := range []string{"missing", "no-cluster", "no-user"} {
+		if _, err := config.ClientConfig(name); err == nil {
+			t.Errorf("%q: expected an error", name)
+		}
+	}
+	config.CurrentContext = ""
+	if _, err := config.ClientConfig(""); err == nil {
+		t.Errorf("expected an error without a current context")
+	}
+}
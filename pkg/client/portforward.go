@@ -0,0 +1,92 @@
+package client
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/golang/glog"
+	"github.com/ryutah/kubernetes-transcribe/pkg/util/wsstream"
+)
+
+// PortForward listens on local ports, and forwards every connection made to them
+// to a port of a pod, through the apiserver.
+type PortForward struct {
+	c         *RESTClient
+	podID     string
+	listeners []net.Listener
+	remotes   []string
+	wg        sync.WaitGroup
+}
+
+// PortForward starts forwarding local ports to the ports of a pod. Each of ports
+// is either "<local>:<remote>" or "<port>" to use the same port on both ends; a
+// local port of 0 picks a free port, which Addrs reports. The remote port may be
+// given by name. Local ports are only opened on localhost. Call Close to stop.
+func (c *RESTClient) PortForward(podID string, ports []string) (*PortForward, error) {
+	f := &PortForward{c: c, podID: podID}
+	for _, port := range ports {
+		local, remote := port, port
+		if i := strings.Index(port, ":"); i >= 0 {
+			local, remote = port[:i], port[i+1:]
+		}
+		if _, err := strconv.ParseUint(local, 10, 16); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("invalid local port in %q", port)
+		}
+		listener, err := net.Listen("tcp", net.JoinHostPort("localhost", local))
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		f.listeners = append(f.listeners, listener)
+		f.remotes = append(f.remotes, remote)
+	}
+	for i := range f.listeners {
+		f.wg.Add(1)
+		go f.serve(f.listeners[i], f.remotes[i])
+	}
+	return f, nil
+}
+
+// Addrs returns the local addresses being listened on, in the order the ports were given.
+func (f *PortForward) Addrs() []net.Addr {
+	addrs := make([]net.Addr, len(f.listeners))
+	for i, listener := range f.listeners {
+		addrs[i] = listener.Addr()
+	}
+	return addrs
+}
+
+// Close stops listening. Connections already forwarded are left open.
+func (f *PortForward) Close() error {
+	for _, listener := range f.listeners {
+		listener.Close()
+	}
+	f.wg.Wait()
+	return nil
+}
+
+// serve forwards the connections accepted by listener to the remote port, until
+// listener is closed.
+func (f *PortForward) serve(listener net.Listener, remote string) {
+	defer f.wg.Done()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			ws, err := f.c.dialPod("portforward", f.podID, url.Values{"port": {remote}})
+			if err != nil {
+				glog.Errorf("Failed to forward a connection to port %s of pod %s: %v", remote, f.podID, err)
+				conn.Close()
+				return
+			}
+			wsstream.Tunnel(ws, conn)
+		}()
+	}
+}
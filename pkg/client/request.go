@@ -2,6 +2,7 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -9,10 +10,12 @@ import (
 	"net/url"
 	"path"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/golang/glog"
 	"github.com/ryutah/kubernetes-transcribe/pkg/api"
+	"github.com/ryutah/kubernetes-transcribe/pkg/api/latest"
 	cwatch "github.com/ryutah/kubernetes-transcribe/pkg/client/watch"
 	"github.com/ryutah/kubernetes-transcribe/pkg/labels"
 	"github.com/ryutah/kubernetes-transcribe/pkg/runtime"
@@ -22,7 +25,7 @@ import (
 
 // specialParams lists parameters that are handled specially and which users of Request
 // are therefore not allowd to set manually.
-var specialParams = util.NewStringSet("sync", "timeout")
+var specialParams = util.NewStringSet("sync", "timeout", "dryRun")
 
 // Verb begins a request with a verb (GET, POST, PUT, DELETE)
 //
@@ -87,6 +90,9 @@ type Request struct {
 	timeout    time.Duration
 	sync       bool
 	pollPeriod time.Duration
+	yaml       bool
+	dryRun     bool
+	ctx        context.Context
 }
 
 // Path appends an item to the request path. You must call Path at least once.
@@ -201,6 +207,39 @@ func (r *Request) Body(obj interface{}) *Request {
 	return r
 }
 
+// DryRun makes the server check a create, update or delete and return its result,
+// without carrying it out. Sets the "dryRun" parameter.
+func (r *Request) DryRun() *Request {
+	if r.err != nil {
+		return r
+	}
+	r.dryRun = true
+	return r
+}
+
+// YAML asks the server to respond with YAML rather than JSON. Result.Into decodes
+// either, so this is mostly useful along with Result.Raw, for readable output.
+// Watch ignores it.
+func (r *Request) YAML() *Request {
+	if r.err != nil {
+		return r
+	}
+	r.yaml = true
+	return r
+}
+
+// Context makes the request observe ctx: Do, including its polling for the
+// completion of operations, and Watch return or end once ctx is done. If ctx
+// has a deadline, the server is asked to give up on synchronous requests by
+// then too, through the "timeout" parameter.
+func (r *Request) Context(ctx context.Context) *Request {
+	if r.err != nil {
+		return r
+	}
+	r.ctx = ctx
+	return r
+}
+
 // PollPeriod sets the poll period.
 // If the server sends back a "working" status message, then repeatedly poll the server
 // to see if the operation has completed yet, waiting 'd' between each poll.
@@ -224,35 +263,96 @@ func (r *Request) finalURL() string {
 	// in any order.
 	if r.sync {
 		query.Add("sync", "true")
-		if r.timeout != 0 {
-			query.Add("timeout", r.timeout.String())
+		if timeout := r.serverTimeout(); timeout != 0 {
+			query.Add("timeout", timeout.String())
 		}
 	}
+	if r.dryRun {
+		query.Add("dryRun", "true")
+	}
 	finalURL += "?" + query.Encode()
 	return finalURL
 }
 
+// serverTimeout returns the timeout for the server to carry out the request in:
+// the one set with Timeout, or the time left until the deadline of the request's
+// context if that is sooner.
+func (r *Request) serverTimeout() time.Duration {
+	timeout := r.timeout
+	if r.ctx == nil {
+		return timeout
+	}
+	if deadline, ok := r.ctx.Deadline(); ok {
+		left := time.Until(deadline)
+		if left <= 0 {
+			// The request will be canceled anyway; ask for as little as possible.
+			left = time.Millisecond
+		}
+		if timeout == 0 || left < timeout {
+			timeout = left
+		}
+	}
+	return timeout
+}
+
+// newHTTPRequest returns the HTTP request to send, bound to the request's context.
+// If the client may retry it, a body given as an arbitrary io.Reader is read in
+// first, so that it can be sent again.
+func (r *Request) newHTTPRequest() (*http.Request, error) {
+	if r.c.Retry.retries() && r.body != nil {
+		switch r.body.(type) {
+		case *bytes.Buffer, *bytes.Reader, *strings.Reader:
+			// http.NewRequest knows how to replay these.
+		default:
+			data, err := ioutil.ReadAll(r.body)
+			if err != nil {
+				return nil, err
+			}
+			r.body = bytes.NewReader(data)
+		}
+	}
+	req, err := http.NewRequest(r.verb, r.finalURL(), r.body)
+	if err != nil {
+		return nil, err
+	}
+	if r.ctx != nil {
+		req = req.WithContext(r.ctx)
+	}
+	return req, nil
+}
+
 // Watch attempts to begin watching the requested location.
-// Returns a watch.Interface, or an error.
+// Returns a watch.Interface, or an error. If the request has a context, the watch
+// ends once it is done.
 func (r *Request) Watch() (watch.Interface, error) {
+	return r.watch(func(stream io.ReadCloser) watch.Decoder {
+		return cwatch.NewAPIEventDecoder(stream)
+	})
+}
+
+// watch is Watch, decoding the stream with the decoder newDecoder returns.
+func (r *Request) watch(newDecoder func(io.ReadCloser) watch.Decoder) (watch.Interface, error) {
 	if r.err != nil {
 		return nil, r.err
 	}
-	req, err := http.NewRequest(r.verb, r.finalURL(), r.body)
+	req, err := r.newHTTPRequest()
 	if err != nil {
 		return nil, err
 	}
-	if r.c.auth != nil {
-		req.SetBasicAuth(r.c.auth.User, r.c.auth.Password)
-	}
 	response, err := r.c.httpClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	if response.StatusCode != http.StatusOK {
+		defer response.Body.Close()
+		body, _ := ioutil.ReadAll(response.Body)
+		var status api.Status
+		if err := latest.Codec.DecodeInto(body, &status); err == nil && status.Status != "" {
+			return nil, &StatusErr{status}
+		}
 		return nil, fmt.Errorf("Got status: %v", response.StatusCode)
 	}
-	return watch.NewStreamWatcher(cwatch.NewAPIEventDecoder(response.Body)), nil
+	return watch.NewStreamWatcher(newDecoder(response.Body)), nil
 }
 
 // Do formats and executes the request. Returns the API object received, or an error.
@@ -261,10 +361,13 @@ func (r *Request) Do() Result {
 		if r.err != nil {
 			return Result{err: r.err}
 		}
-		req, err := http.NewRequest(r.verb, r.finalURL(), r.body)
+		req, err := r.newHTTPRequest()
 		if err != nil {
 			return Result{err: err}
 		}
+		if r.yaml {
+			req.Header.Set("Accept", "application/yaml")
+		}
 		respBody, err := r.c.doRequest(req)
 		if err != nil {
 			if statusErr, ok := err.(*StatusErr); ok {
@@ -273,9 +376,13 @@ func (r *Request) Do() Result {
 						id := statusErr.Status.Details.ID
 						if len(id) > 0 {
 							glog.Infof("Waiting for completion of /operations/%s", id)
-							time.Sleep(r.pollPeriod)
+							if err := r.sleep(r.pollPeriod); err != nil {
+								return Result{err: err}
+							}
 							// Make a poll request
 							pollOp := r.c.PollFor(id).PollPeriod(r.pollPeriod)
+							pollOp.yaml = r.yaml
+							pollOp.ctx = r.ctx
 							// Could also say "return r.Do()" but this way doesn't grow the callstack.
 							r = pollOp
 							continue
@@ -288,6 +395,23 @@ func (r *Request) Do() Result {
 	}
 }
 
+// sleep waits for d, or until the request's context is done, in which case its
+// error is returned.
+func (r *Request) sleep(d time.Duration) error {
+	if r.ctx == nil {
+		time.Sleep(d)
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-r.ctx.Done():
+		return r.ctx.Err()
+	}
+}
+
 // Result contains the result of calling Request.Do().
 type Result struct {
 	body  []byte
@@ -0,0 +1,158 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ryutah/kubernetes-transcribe/pkg/api"
+	"github.com/ryutah/kubernetes-transcribe/pkg/runtime"
+)
+
+// The versioned API types are not registered yet, so tests use the internal version.
+var testCodec = runtime.CodecFor(api.Scheme, "")
+
+func testRESTClient(t *testing.T, server *httptest.Server) *RESTClient {
+	c, err := NewRESTClient(server.URL, nil, "/api/v1beta1", testCodec)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return c
+}
+
+func TestContextCancelsDo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		<-req.Context().Done()
+	}))
+	defer server.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	start := time.Now()
+	err := testRESTClient(t, server).Get().Path("pods").Context(ctx).Do().Error()
+	if err == nil || ctx.Err() == nil {
+		t.Fatalf("expected the request to be canceled, got %v", err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("expected Do to return once canceled, took %v", d)
+	}
+}
+
+func TestContextCancelsPolling(t *testing.T) {
+	polls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		polls++
+		data, err := testCodec.Encode(&api.Status{
+			Status:  api.StatusWorking,
+			Code:    http.StatusAccepted,
+			Details: &api.StatusDetails{ID: "1"},
+		})
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		w.WriteHeader(http.StatusAccepted)
+		w.Write(data)
+	}))
+	defer server.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	err := testRESTClient(t, server).Post().Path("pods").PollPeriod(10 * time.Millisecond).Context(ctx).Do().Error()
+	if err != context.Canceled {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("expected polling to stop once canceled, took %v", d)
+	}
+	if polls < 2 {
+		t.Errorf("expected the operation to be polled, got %d requests", polls)
+	}
+}
+
+func TestContextClosesWatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		<-req.Context().Done()
+	}))
+	defer server.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	w, err := testRESTClient(t, server).Get().Path("watch").Path("pods").Context(ctx).Watch()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cancel()
+	select {
+	case event, ok := <-w.ResultChan():
+		if ok {
+			t.Errorf("unexpected event %#v", event)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("expected the watch to end once canceled")
+		w.Stop()
+	}
+}
+
+func TestContextDeadlineSetsTimeout(t *testing.T) {
+	timeouts := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		timeouts <- req.URL.Query().Get("timeout")
+		w.Write([]byte(`{"kind": "Status", "status": "success"}`))
+	}))
+	defer server.Close()
+	c := testRESTClient(t, server)
+
+	table := map[string]struct {
+		deadline  time.Duration
+		expectMin time.Duration
+		expectMax time.Duration
+	}{
+		"no deadline":     {0, time.Minute, time.Minute},
+		"sooner deadline": {5 * time.Second, 4 * time.Second, 5 * time.Second},
+		"later deadline":  {time.Hour, time.Minute, time.Minute},
+	}
+	for name, item := range table {
+		request := c.Get().Path("pods").Sync(true).Timeout(time.Minute)
+		if item.deadline != 0 {
+			ctx, cancel := context.WithTimeout(context.Background(), item.deadline)
+			defer cancel()
+			request.Context(ctx)
+		}
+		if err := request.Do().Error(); err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		timeout, err := time.ParseDuration(<-timeouts)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if timeout < item.expectMin || timeout > item.expectMax {
+			t.Errorf("%s: expected a timeout between %v and %v, got %v", name, item.expectMin, item.expectMax, timeout)
+		}
+	}
+}
+
+func TestYAMLSetsAccept(t *testing.T) {
+	accepts := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		accepts <- req.Header.Get("Accept")
+		w.Write([]byte("kind: Status\nstatus: success\n"))
+	}))
+	defer server.Close()
+	c := testRESTClient(t, server)
+
+	for request, expected := range map[*Request]string{
+		c.Get().Path("pods"):        "",
+		c.Get().Path("pods").YAML(): "application/yaml",
+	} {
+		if err := request.Do().Error(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if e, a := expected, <-accepts; e != a {
+			t.Errorf("expected %q, got %q", e, a)
+		}
+	}
+}
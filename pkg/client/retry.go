@@ -0,0 +1,157 @@
+package client
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ryutah/kubernetes-transcribe/pkg/api"
+	"github.com/ryutah/kubernetes-transcribe/pkg/util/wait"
+)
+
+// RetryPolicy decides which failed requests a RESTClient retries, and how long it
+// waits in between. A request is retried if it failed to reach the server, or if
+// the server responded with one of StatusCodes, or with a status whose reason is
+// one of Reasons. Requests which are not idempotent, that is POSTs, are only
+// retried if RetryNonIdempotent is set, as the server may have carried them out
+// before failing. A Retry-After header on a 429 or 503 response sets the wait
+// before the next attempt in place of the backoff.
+type RetryPolicy struct {
+	// MaxAttempts is the number of times a request is made at most, including the
+	// first. Values below 2 disable retries.
+	MaxAttempts int
+	// Backoff is the wait before the first retry. It doubles with every further
+	// retry, up to MaxBackoff if that is not zero.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// Jitter adds a random fraction, up to Jitter, of each wait to it, so that
+	// clients which failed together don't all retry together.
+	Jitter float64
+
+	StatusCodes        []int
+	Reasons            []api.StatusReason
+	RetryNonIdempotent bool
+
+	// OnFailure, if not nil, is called with every failed attempt which is going
+	// to be retried, before waiting.
+	OnFailure func(request *http.Request, attempt int, err error)
+}
+
+// DefaultRetryPolicy returns a policy retrying up to 5 times, starting 100ms
+// apart, on connection errors and on responses which mean the server is
+// temporarily unable to handle the request.
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts: 5,
+		Backoff:     100 * time.Millisecond,
+		MaxBackoff:  5 * time.Second,
+		Jitter:      0.5,
+		StatusCodes: []int{
+			http.StatusTooManyRequests,
+			http.StatusInternalServerError,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+	}
+}
+
+// idempotentVerbs lists the verbs which have the same effect whether a request is
+// made once or several times.
+var idempotentVerbs = map[string]bool{
+	"GET":     true,
+	"HEAD":    true,
+	"OPTIONS": true,
+	"PUT":     true,
+	"DELETE":  true,
+}
+
+// retries reports whether p allows more than one attempt.
+func (p *RetryPolicy) retries() bool {
+	return p != nil && p.MaxAttempts > 1
+}
+
+// shouldRetry reports whether request, which failed on the given attempt, should
+// be made again. code is the status code of the response, or 0 if there was none,
+// and status the status the server responded with, if any.
+func (p *RetryPolicy) shouldRetry(request *http.Request, attempt, code int, status *api.Status) bool {
+	if !p.retries() || attempt >= p.MaxAttempts {
+		return false
+	}
+	if request.Context().Err() != nil {
+		return false
+	}
+	if request.Body != nil && request.GetBody == nil {
+		// The body is gone.
+		return false
+	}
+	if !idempotentVerbs[request.Method] && !p.RetryNonIdempotent {
+		return false
+	}
+	if code == 0 {
+		return true
+	}
+	for _, c := range p.StatusCodes {
+		if c == code {
+			return true
+		}
+	}
+	if status != nil {
+		for _, reason := range p.Reasons {
+			if reason == status.Reason {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// backoff returns how long to wait after the given failed attempt.
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	return wait.Backoff{
+		Duration: p.Backoff,
+		Factor:   2,
+		Jitter:   p.Jitter,
+		Cap:      p.MaxBackoff,
+	}.Step(attempt)
+}
+
+// wait waits before retrying request after the given failed attempt, for
+// retryAfter if it isn't negative, and returns false if the request's context is
+// done first.
+func (p *RetryPolicy) wait(request *http.Request, attempt int, retryAfter time.Duration) bool {
+	d := retryAfter
+	if d < 0 {
+		d = p.backoff(attempt)
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-request.Context().Done():
+		return false
+	}
+}
+
+// retryAfter returns the wait the Retry-After header of a 429 or 503 response asks
+// for, given in seconds or as a date, or -1 if there is none.
+func retryAfter(response *http.Response) time.Duration {
+	if response.StatusCode != http.StatusTooManyRequests && response.StatusCode != http.StatusServiceUnavailable {
+		return -1
+	}
+	value := response.Header.Get("Retry-After")
+	if value == "" {
+		return -1
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if d := time.Until(date); d > 0 {
+			return d
+		}
+		return 0
+	}
+	return -1
+}
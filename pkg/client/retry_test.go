@@ -0,0 +1,214 @@
+package client
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ryutah/kubernetes-transcribe/pkg/api"
+	"github.com/ryutah/kubernetes-transcribe/pkg/runtime"
+)
+
+func TestShouldRetry(t *testing.T) {
+	policy := &RetryPolicy{
+		MaxAttempts: 3,
+		StatusCodes: []int{http.StatusServiceUnavailable},
+		Reasons:     []api.StatusReason{api.StatusReasonConflict},
+	}
+	get, _ := http.NewRequest("GET", "http://localhost", nil)
+	post, _ := http.NewRequest("POST", "http://localhost", nil)
+	replayable, _ := http.NewRequest("PUT", "http://localhost", bytes.NewReader([]byte("body")))
+	consumed, _ := http.NewRequest("PUT", "http://localhost", ioutil.NopCloser(strings.NewReader("body")))
+	conflict := &api.Status{Reason: api.StatusReasonConflict}
+
+	table := map[string]struct {
+		policy  *RetryPolicy
+		request *http.Request
+		attempt int
+		code    int
+		status  *api.Status
+		expect  bool
+	}{
+		"connection error":       {policy, get, 1, 0, nil, true},
+		"retried status code":    {policy, get, 1, http.StatusServiceUnavailable, nil, true},
+		"other status code":      {policy, get, 1, http.StatusNotFound, nil, false},
+		"retried reason":         {policy, get, 1, http.StatusConflict, conflict, true},
+		"last attempt":           {policy, get, 3, 0, nil, false},
+		"no policy":              {nil, get, 1, 0, nil, false},
+		"single attempt":         {&RetryPolicy{MaxAttempts: 1}, get, 1, 0, nil, false},
+		"POST":                   {policy, post, 1, 0, nil, false},
+		"POST allowed":           {&RetryPolicy{MaxAttempts: 3, RetryNonIdempotent: true}, post, 1, 0, nil, true},
+		"replayable body":        {policy, replayable, 1, 0, nil, true},
+		"body can't be replayed": {policy, consumed, 1, 0, nil, false},
+	}
+	for name, item := range table {
+		if e, a := item.expect, item.policy.shouldRetry(item.request, item.attempt, item.code, item.status); e != a {
+			t.Errorf("%s: expected %v, got %v", name, e, a)
+		}
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	policy := &RetryPolicy{Backoff: 100 * time.Millisecond, MaxBackoff: 300 * time.Millisecond}
+	for attempt, expected := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond, 300 * time.Millisecond} {
+		if a := policy.backoff(attempt + 1); expected != a {
+			t.Errorf("attempt %d: expected %v, got %v", attempt+1, expected, a)
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	date := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	table := map[string]struct {
+		code      int
+		header    string
+		expectMin time.Duration
+		expectMax time.Duration
+	}{
+		"seconds":    {http.StatusServiceUnavailable, "3", 3 * time.Second, 3 * time.Second},
+		"too many":   {http.StatusTooManyRequests, "0", 0, 0},
+		"date":       {http.StatusServiceUnavailable, date, 59 * time.Minute, time.Hour},
+		"past date":  {http.StatusServiceUnavailable, "Mon, 02 Jan 2006 15:04:05 GMT", 0, 0},
+		"missing":    {http.StatusServiceUnavailable, "", -1, -1},
+		"invalid":    {http.StatusServiceUnavailable, "soon", -1, -1},
+		"other code": {http.StatusInternalServerError, "3", -1, -1},
+	}
+	for name, item := range table {
+		response := &http.Response{StatusCode: item.code, Header: http.Header{}}
+		if item.header != "" {
+			response.Header.Set("Retry-After", item.header)
+		}
+		if d := retryAfter(response); d < item.expectMin || d > item.expectMax {
+			t.Errorf("%s: expected between %v and %v, got %v", name, item.expectMin, item.expectMax, d)
+		}
+	}
+}
+
+// flakyServer fails the first failures requests with code, and records the bodies
+// of all of them.
+type flakyServer struct {
+	failures int
+	code     int
+	header   http.Header
+
+	lock   sync.Mutex
+	bodies []string
+}
+
+func (f *flakyServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := ioutil.ReadAll(req.Body)
+	f.lock.Lock()
+	f.bodies = append(f.bodies, string(body))
+	attempt := len(f.bodies)
+	f.lock.Unlock()
+	if attempt <= f.failures {
+		for key, values := range f.header {
+			w.Header()[key] = values
+		}
+		w.WriteHeader(f.code)
+		return
+	}
+	w.Write([]byte(`{"kind":"Pod","id":"foo"}`))
+}
+
+func (f *flakyServer) attempts() []string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return append([]string{}, f.bodies...)
+}
+
+// retryingClient returns a client for server, retrying as policy says.
+func retryingClient(t *testing.T, server *httptest.Server, policy *RetryPolicy) *RESTClient {
+	// The versioned API types are not registered yet, so tests use the internal version.
+	c, err := NewRESTClient(server.URL, nil, "/api/v1beta1", runtime.CodecFor(api.Scheme, ""))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.Retry = policy
+	return c
+}
+
+func TestRetryReplaysBody(t *testing.T) {
+	flaky := &flakyServer{failures: 2, code: http.StatusBadGateway}
+	server := httptest.NewServer(flaky)
+	defer server.Close()
+	failures := 0
+	policy := DefaultRetryPolicy()
+	policy.Backoff = time.Millisecond
+	policy.OnFailure = func(request *http.Request, attempt int, err error) { failures++ }
+	c := retryingClient(t, server, policy)
+
+	if _, err := c.Put().Path("pods").Path("foo").Body([]byte(`{"id":"foo"}`)).Do().Raw(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	attempts := flaky.attempts()
+	if len(attempts) != 3 {
+		t.Fatalf("expected 3 attempts, got %q", attempts)
+	}
+	for i, body := range attempts {
+		if e, a := `{"id":"foo"}`, body; e != a {
+			t.Errorf("attempt %d: expected %q, got %q", i+1, e, a)
+		}
+	}
+	if e, a := 2, failures; e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	flaky := &flakyServer{failures: 10, code: http.StatusServiceUnavailable}
+	server := httptest.NewServer(flaky)
+	defer server.Close()
+	policy := DefaultRetryPolicy()
+	policy.MaxAttempts, policy.Backoff = 3, time.Millisecond
+	c := retryingClient(t, server, policy)
+
+	if _, err := c.Get().Path("pods").Do().Raw(); err == nil {
+		t.Errorf("expected an error")
+	}
+	if e, a := 3, len(flaky.attempts()); e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+}
+
+func TestRetrySkipsPost(t *testing.T) {
+	flaky := &flakyServer{failures: 1, code: http.StatusServiceUnavailable}
+	server := httptest.NewServer(flaky)
+	defer server.Close()
+	policy := DefaultRetryPolicy()
+	policy.Backoff = time.Millisecond
+	c := retryingClient(t, server, policy)
+
+	if _, err := c.Post().Path("pods").Body([]byte(`{"id":"foo"}`)).Do().Raw(); err == nil {
+		t.Errorf("expected an error")
+	}
+	if e, a := 1, len(flaky.attempts()); e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+}
+
+func TestRetryHonoursRetryAfter(t *testing.T) {
+	flaky := &flakyServer{
+		failures: 1,
+		code:     http.StatusTooManyRequests,
+		header:   http.Header{"Retry-After": {"0"}},
+	}
+	server := httptest.NewServer(flaky)
+	defer server.Close()
+	policy := DefaultRetryPolicy()
+	// The test times out if the backoff is used.
+	policy.Backoff, policy.MaxBackoff, policy.Jitter = time.Hour, 0, 0
+	c := retryingClient(t, server, policy)
+
+	if _, err := c.Get().Path("pods").Path("foo").Do().Raw(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e, a := 2, len(flaky.attempts()); e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+}
@@ -0,0 +1,164 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/ryutah/kubernetes-transcribe/pkg/api"
+	"github.com/ryutah/kubernetes-transcribe/pkg/runtime"
+	"github.com/ryutah/kubernetes-transcribe/pkg/util"
+	"github.com/ryutah/kubernetes-transcribe/pkg/util/wait"
+	"github.com/ryutah/kubernetes-transcribe/pkg/watch"
+)
+
+// WatchFunc begins a watch at the given resource version, like the Watch method
+// of cache.ListerWatcher.
+type WatchFunc func(resourceVersion uint64) (watch.Interface, error)
+
+// retryWatchBackoff is how long a RetryWatcher waits before watching again, after
+// failing to watch, or after a watch ended without any events.
+var retryWatchBackoff = wait.Backoff{
+	Duration: 100 * time.Millisecond,
+	Factor:   2,
+	Jitter:   0.5,
+	Cap:      30 * time.Second,
+}
+
+// RetryWatcher is a watch.Interface which, unlike the one returned by Request.Watch,
+// doesn't end when the watch it makes does: it watches again, from the version
+// after the last one seen, backing off as long as that fails. Events for versions
+// already seen are dropped, so each change is seen once.
+//
+// If the version to watch from is too old, the RetryWatcher sends an event of
+// type watch.Error, with an *api.Status whose reason is api.StatusReasonExpired,
+// and ends. The caller should then list again, and watch from the version listed.
+type RetryWatcher struct {
+	watchFunc       WatchFunc
+	resourceVersion uint64
+
+	result   chan watch.Event
+	stopCh   chan struct{}
+	stopOnce sync.Once
+}
+
+// NewRetryWatcher begins watching with watchFunc at resourceVersion.
+func NewRetryWatcher(resourceVersion uint64, watchFunc WatchFunc) *RetryWatcher {
+	rw := &RetryWatcher{
+		watchFunc:       watchFunc,
+		resourceVersion: resourceVersion,
+		result:          make(chan watch.Event),
+		stopCh:          make(chan struct{}),
+	}
+	go rw.run()
+	return rw
+}
+
+// ResultChan implements watch.Interface.
+func (rw *RetryWatcher) ResultChan() <-chan watch.Event {
+	return rw.result
+}
+
+// Stop implements watch.Interface.
+func (rw *RetryWatcher) Stop() {
+	rw.stopOnce.Do(func() { close(rw.stopCh) })
+}
+
+func (rw *RetryWatcher) run() {
+	defer util.HandleCrash()
+	defer close(rw.result)
+	failures := 0
+	for {
+		w, err := rw.watchFunc(rw.resourceVersion)
+		if err != nil {
+			if statusErr, ok := err.(*StatusErr); ok && isExpired(&statusErr.Status) {
+				rw.send(watch.Event{Type: watch.Error, Object: &statusErr.Status})
+				return
+			}
+			glog.Errorf("Failed to watch from version %d: %v", rw.resourceVersion, err)
+		} else {
+			received, done := rw.receive(w)
+			if done {
+				return
+			}
+			if received {
+				failures = 0
+				continue
+			}
+		}
+		failures++
+		select {
+		case <-time.After(retryWatchBackoff.Step(failures)):
+		case <-rw.stopCh:
+			return
+		}
+	}
+}
+
+// receive passes on the events of w until it ends, and reports whether there were
+// any other than errors, and whether the RetryWatcher is done.
+func (rw *RetryWatcher) receive(w watch.Interface) (received, done bool) {
+	defer w.Stop()
+	for {
+		select {
+		case event, ok := <-w.ResultChan():
+			if !ok {
+				return received, false
+			}
+			if event.Type == watch.Error {
+				if status, ok := event.Object.(*api.Status); ok && isExpired(status) {
+					rw.send(event)
+					return received, true
+				}
+				glog.Errorf("Watch from version %d failed: %#v", rw.resourceVersion, event.Object)
+				// Watching again deals with errors other than missed changes. The error
+				// doesn't count as an event, so that a watch which only fails backs off.
+				return received, false
+			}
+			received = true
+			jsonBase, err := runtime.FindJSONBase(event.Object)
+			if err != nil {
+				glog.Errorf("Unable to understand watch event %#v", event)
+				continue
+			}
+			if version := jsonBase.ResourceVersion(); version != 0 {
+				if version < rw.resourceVersion {
+					// Seen before the watch was made again.
+					continue
+				}
+				rw.resourceVersion = version + 1
+			}
+			if !rw.send(event) {
+				return received, true
+			}
+		case <-rw.stopCh:
+			return received, true
+		}
+	}
+}
+
+// send passes event on, and returns false if the RetryWatcher was stopped first.
+func (rw *RetryWatcher) send(event watch.Event) bool {
+	select {
+	case rw.result <- event:
+		return true
+	case <-rw.stopCh:
+		return false
+	}
+}
+
+// isExpired reports whether status says a watch was made from too old a version.
+func isExpired(status *api.Status) bool {
+	return status.Reason == api.StatusReasonExpired || status.Code == http.StatusGone
+}
+
+// statusError returns the error described by the object of an event of type
+// watch.Error.
+func statusError(obj runtime.Object) error {
+	if status, ok := obj.(*api.Status); ok {
+		return &StatusErr{*status}
+	}
+	return fmt.Errorf("watch failed: %#v", obj)
+}
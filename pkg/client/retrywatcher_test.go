@@ -0,0 +1,166 @@
+package client
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ryutah/kubernetes-transcribe/pkg/api"
+	"github.com/ryutah/kubernetes-transcribe/pkg/util/wait"
+	"github.com/ryutah/kubernetes-transcribe/pkg/watch"
+)
+
+func init() {
+	retryWatchBackoff = wait.Backoff{Duration: time.Millisecond, Factor: 1}
+}
+
+// fakeWatches is a WatchFunc returning the results in watches in turn, and
+// recording the versions it was called with.
+type fakeWatches struct {
+	lock     sync.Mutex
+	watches  []watch.Interface
+	errs     []error
+	versions []uint64
+}
+
+func (f *fakeWatches) watch(resourceVersion uint64) (watch.Interface, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	n := len(f.versions)
+	f.versions = append(f.versions, resourceVersion)
+	if n < len(f.errs) && f.errs[n] != nil {
+		return nil, f.errs[n]
+	}
+	if n < len(f.watches) {
+		return f.watches[n], nil
+	}
+	// Nothing more to send.
+	return watch.NewFake(), nil
+}
+
+func (f *fakeWatches) calls() []uint64 {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return append([]uint64(nil), f.versions...)
+}
+
+func podAt(id string, version uint64) *api.Pod {
+	return &api.Pod{JSONBase: api.JSONBase{ID: id, ResourceVersion: version}}
+}
+
+func TestRetryWatcherReconnectsAndDedupes(t *testing.T) {
+	first := watch.NewFakeWithChanSize(10)
+	first.Add(podAt("a", 5))
+	first.Modify(podAt("a", 6))
+	first.Stop()
+	second := watch.NewFakeWithChanSize(10)
+	// Replayed by the server after reconnecting.
+	second.Modify(podAt("a", 6))
+	second.Delete(podAt("a", 7))
+	fake := &fakeWatches{
+		watches: []watch.Interface{first, nil, second},
+		errs:    []error{nil, errors.New("connection refused")},
+	}
+
+	rw := NewRetryWatcher(1, fake.watch)
+	defer rw.Stop()
+	expected := []watch.Event{
+		{Type: watch.Added, Object: podAt("a", 5)},
+		{Type: watch.Modified, Object: podAt("a", 6)},
+		{Type: watch.Deleted, Object: podAt("a", 7)},
+	}
+	for i, e := range expected {
+		select {
+		case a := <-rw.ResultChan():
+			if !reflect.DeepEqual(e, a) {
+				t.Errorf("%d: expected %#v, got %#v", i, e, a)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%d: timed out waiting for %#v", i, e)
+		}
+	}
+	if e, a := []uint64{1, 7, 7}, fake.calls()[:3]; !reflect.DeepEqual(e, a) {
+		t.Errorf("expected watches from %v, got %v", e, a)
+	}
+}
+
+func TestRetryWatcherExpiredEvent(t *testing.T) {
+	w := watch.NewFakeWithChanSize(10)
+	w.Add(podAt("a", 5))
+	expired := &api.Status{Status: api.StatusFailure, Code: 410, Reason: api.StatusReasonExpired}
+	w.Action(watch.Error, expired)
+	fake := &fakeWatches{watches: []watch.Interface{w}}
+
+	rw := NewRetryWatcher(1, fake.watch)
+	defer rw.Stop()
+	if event := <-rw.ResultChan(); event.Type != watch.Added {
+		t.Errorf("unexpected event %#v", event)
+	}
+	event := <-rw.ResultChan()
+	if event.Type != watch.Error || event.Object != expired {
+		t.Errorf("expected the expired status, got %#v", event)
+	}
+	if _, ok := <-rw.ResultChan(); ok {
+		t.Errorf("expected the watcher to end")
+	}
+	if e, a := 1, len(fake.calls()); e != a {
+		t.Errorf("expected %d watch, got %d", e, a)
+	}
+}
+
+func TestRetryWatcherExpiredResponse(t *testing.T) {
+	fake := &fakeWatches{errs: []error{&StatusErr{api.Status{Status: api.StatusFailure, Code: 410}}}}
+	rw := NewRetryWatcher(1, fake.watch)
+	defer rw.Stop()
+	event := <-rw.ResultChan()
+	if status, ok := event.Object.(*api.Status); event.Type != watch.Error || !ok || !isExpired(status) {
+		t.Errorf("expected the expired status, got %#v", event)
+	}
+	if _, ok := <-rw.ResultChan(); ok {
+		t.Errorf("expected the watcher to end")
+	}
+}
+
+func TestRetryWatcherStop(t *testing.T) {
+	w := watch.NewFake()
+	rw := NewRetryWatcher(1, (&fakeWatches{watches: []watch.Interface{w}}).watch)
+	rw.Stop()
+	if _, ok := <-rw.ResultChan(); ok {
+		t.Errorf("expected the watcher to end")
+	}
+	w.Lock()
+	defer w.Unlock()
+	if !w.Stopped {
+		t.Errorf("expected the underlying watch to be stopped")
+	}
+}
+
+func TestRetryWatcherBacksOffOnErrorEvents(t *testing.T) {
+	lock := sync.Mutex{}
+	calls := 0
+	watchFunc := func(uint64) (watch.Interface, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		calls++
+		w := watch.NewFakeWithChanSize(1)
+		w.Action(watch.Error, &api.Status{Status: api.StatusFailure, Code: 500, Message: "etcd is down"})
+		return w, nil
+	}
+	retryWatchBackoff = wait.Backoff{Duration: 10 * time.Millisecond, Factor: 1}
+	defer func() { retryWatchBackoff = wait.Backoff{Duration: time.Millisecond, Factor: 1} }()
+
+	rw := NewRetryWatcher(1, watchFunc)
+	time.Sleep(100 * time.Millisecond)
+	rw.Stop()
+	for range rw.ResultChan() {
+		t.Errorf("unexpected event")
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	// Without backing off, the watch would be made thousands of times.
+	if calls > 20 {
+		t.Errorf("expected the RetryWatcher to back off, but it watched %d times", calls)
+	}
+}
@@ -0,0 +1,331 @@
+package client
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/ryutah/kubernetes-transcribe/pkg/api"
+	"github.com/ryutah/kubernetes-transcribe/pkg/labels"
+	"github.com/ryutah/kubernetes-transcribe/pkg/runtime"
+	"github.com/ryutah/kubernetes-transcribe/pkg/version"
+	"github.com/ryutah/kubernetes-transcribe/pkg/watch"
+)
+
+// ReactionFunc handles an action made through a SimpleFake, instead of its
+// tracker, if it returns handled. ret is the object to return, if any.
+type ReactionFunc func(action FakeAction) (handled bool, ret runtime.Object, err error)
+
+// reactor is a ReactionFunc, and the actions it is for.
+type reactor struct {
+	action   string
+	reaction ReactionFunc
+}
+
+// matches reports whether the reactor is for action. "*" matches every action, and
+// "<verb>-*" every action with that verb, such as "create-*".
+func (r reactor) matches(action string) bool {
+	if r.action == "*" || r.action == action {
+		return true
+	}
+	return strings.HasSuffix(r.action, "-*") && strings.HasPrefix(action, strings.TrimSuffix(r.action, "*"))
+}
+
+// SimpleFake implements Interface on top of an ObjectTracker, so that tests of
+// code using a client see the results of their writes, in later reads and in
+// watches, as they would with an apiserver. Like Fake, it records the actions
+// made through it, named the same way. Reactors can take over actions, to make
+// them fail for example. Watches only get the changes made after they start,
+// whatever resource version they are given, and field selectors are ignored.
+// SimpleFake is safe for concurrent use.
+type SimpleFake struct {
+	Tracker *ObjectTracker
+
+	lock     sync.Mutex
+	actions  []FakeAction
+	reactors []reactor
+}
+
+// NewSimpleFake returns a SimpleFake whose tracker holds objects.
+func NewSimpleFake(objects ...runtime.Object) (*SimpleFake, error) {
+	tracker, err := NewObjectTracker(objects...)
+	if err != nil {
+		return nil, err
+	}
+	return &SimpleFake{Tracker: tracker}, nil
+}
+
+// AddReactor makes reaction handle the actions matching action, which is either
+// the name of an action, such as "create-pod", "<verb>-*", or "*". Reactors added
+// later are tried first.
+func (c *SimpleFake) AddReactor(action string, reaction ReactionFunc) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.reactors = append([]reactor{{action, reaction}}, c.reactors...)
+}
+
+// Actions returns the actions made so far.
+func (c *SimpleFake) Actions() []FakeAction {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return append([]FakeAction(nil), c.actions...)
+}
+
+// ClearActions forgets the actions made so far.
+func (c *SimpleFake) ClearActions() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.actions = nil
+}
+
+// invoke records action, and hands it to the first reactor for it which handles
+// it, or to defaultReaction if none does.
+func (c *SimpleFake) invoke(action FakeAction, defaultReaction func() (runtime.Object, error)) (runtime.Object, error) {
+	c.lock.Lock()
+	c.actions = append(c.actions, action)
+	reactors := c.reactors
+	c.lock.Unlock()
+	for _, r := range reactors {
+		if !r.matches(action.Action) {
+			continue
+		}
+		if handled, ret, err := r.reaction(action); handled {
+			return ret, err
+		}
+	}
+	return defaultReaction()
+}
+
+// list fills list, a pointer to a list type, with the tracked objects of kind
+// matching label.
+func (c *SimpleFake) list(kind string, label labels.Selector, list runtime.Object) (runtime.Object, error) {
+	objects, resourceVersion, err := c.Tracker.List(kind, label)
+	if err != nil {
+		return nil, err
+	}
+	jsonBase, err := runtime.FindJSONBase(list)
+	if err != nil {
+		return nil, err
+	}
+	jsonBase.SetResourceVersion(resourceVersion)
+	items := reflect.ValueOf(list).Elem().FieldByName("Items")
+	slice := reflect.MakeSlice(items.Type(), len(objects), len(objects))
+	for i, obj := range objects {
+		slice.Index(i).Set(reflect.ValueOf(obj).Elem())
+	}
+	items.Set(slice)
+	return list, nil
+}
+
+// watch records action, and returns a watch of the tracked objects of kind
+// matching label from resourceVersion, unless a reactor fails it.
+func (c *SimpleFake) watch(action FakeAction, kind string, label labels.Selector, resourceVersion uint64) (watch.Interface, error) {
+	_, err := c.invoke(action, func() (runtime.Object, error) { return nil, nil })
+	if err != nil {
+		return nil, err
+	}
+	return c.Tracker.Watch(kind, label, resourceVersion)
+}
+
+func (c *SimpleFake) ListPods(label, field labels.Selector) (*api.PodList, error) {
+	obj, err := c.invoke(FakeAction{Action: "list-pods", Value: selectorsOf(label, field)}, func() (runtime.Object, error) {
+		return c.list("Pod", label, &api.PodList{})
+	})
+	list, _ := obj.(*api.PodList)
+	return list, err
+}
+
+func (c *SimpleFake) GetPod(id string) (*api.Pod, error) {
+	obj, err := c.invoke(FakeAction{Action: "get-pod", Value: id}, func() (runtime.Object, error) {
+		return c.Tracker.Get("Pod", id)
+	})
+	pod, _ := obj.(*api.Pod)
+	return pod, err
+}
+
+func (c *SimpleFake) DeletePod(id string) error {
+	_, err := c.invoke(FakeAction{Action: "delete-pod", Value: id}, func() (runtime.Object, error) {
+		return nil, c.Tracker.Delete("Pod", id)
+	})
+	return err
+}
+
+func (c *SimpleFake) CreatePod(pod *api.Pod) (*api.Pod, error) {
+	obj, err := c.invoke(FakeAction{Action: "create-pod", Value: pod}, func() (runtime.Object, error) {
+		return c.Tracker.Create(pod)
+	})
+	created, _ := obj.(*api.Pod)
+	return created, err
+}
+
+func (c *SimpleFake) UpdatePod(pod *api.Pod) (*api.Pod, error) {
+	obj, err := c.invoke(FakeAction{Action: "update-pod", Value: pod}, func() (runtime.Object, error) {
+		return c.Tracker.Update(pod)
+	})
+	updated, _ := obj.(*api.Pod)
+	return updated, err
+}
+
+func (c *SimpleFake) WatchPods(label, field labels.Selector, resourceVersion uint64) (watch.Interface, error) {
+	return c.watch(FakeAction{Action: "watch-pods", Value: resourceVersion}, "Pod", label, resourceVersion)
+}
+
+func (c *SimpleFake) ListReplicationControllers(label, field labels.Selector) (*api.ReplicationControllerList, error) {
+	obj, err := c.invoke(FakeAction{Action: "list-controllers", Value: selectorsOf(label, field)}, func() (runtime.Object, error) {
+		return c.list("ReplicationController", label, &api.ReplicationControllerList{})
+	})
+	list, _ := obj.(*api.ReplicationControllerList)
+	return list, err
+}
+
+func (c *SimpleFake) GetReplicationController(id string) (*api.ReplicationController, error) {
+	obj, err := c.invoke(FakeAction{Action: "get-controller", Value: id}, func() (runtime.Object, error) {
+		return c.Tracker.Get("ReplicationController", id)
+	})
+	controller, _ := obj.(*api.ReplicationController)
+	return controller, err
+}
+
+func (c *SimpleFake) CreateReplicationController(controller *api.ReplicationController) (*api.ReplicationController, error) {
+	obj, err := c.invoke(FakeAction{Action: "create-controller", Value: controller}, func() (runtime.Object, error) {
+		return c.Tracker.Create(controller)
+	})
+	created, _ := obj.(*api.ReplicationController)
+	return created, err
+}
+
+func (c *SimpleFake) UpdateReplicationController(controller *api.ReplicationController) (*api.ReplicationController, error) {
+	obj, err := c.invoke(FakeAction{Action: "update-controller", Value: controller}, func() (runtime.Object, error) {
+		return c.Tracker.Update(controller)
+	})
+	updated, _ := obj.(*api.ReplicationController)
+	return updated, err
+}
+
+func (c *SimpleFake) DeleteReplicationController(id string) error {
+	_, err := c.invoke(FakeAction{Action: "delete-controller", Value: id}, func() (runtime.Object, error) {
+		return nil, c.Tracker.Delete("ReplicationController", id)
+	})
+	return err
+}
+
+func (c *SimpleFake) WatchReplicationControllers(label, field labels.Selector, resourceVersion uint64) (watch.Interface, error) {
+	return c.watch(FakeAction{Action: "watch-controllers", Value: resourceVersion}, "ReplicationController", label, resourceVersion)
+}
+
+func (c *SimpleFake) ListServices(label, field labels.Selector) (*api.ServiceList, error) {
+	obj, err := c.invoke(FakeAction{Action: "list-services", Value: selectorsOf(label, field)}, func() (runtime.Object, error) {
+		return c.list("Service", label, &api.ServiceList{})
+	})
+	list, _ := obj.(*api.ServiceList)
+	return list, err
+}
+
+func (c *SimpleFake) GetService(id string) (*api.Service, error) {
+	obj, err := c.invoke(FakeAction{Action: "get-service", Value: id}, func() (runtime.Object, error) {
+		return c.Tracker.Get("Service", id)
+	})
+	service, _ := obj.(*api.Service)
+	return service, err
+}
+
+func (c *SimpleFake) CreateService(service *api.Service) (*api.Service, error) {
+	obj, err := c.invoke(FakeAction{Action: "create-service", Value: service}, func() (runtime.Object, error) {
+		return c.Tracker.Create(service)
+	})
+	created, _ := obj.(*api.Service)
+	return created, err
+}
+
+func (c *SimpleFake) UpdateService(service *api.Service) (*api.Service, error) {
+	obj, err := c.invoke(FakeAction{Action: "update-service", Value: service}, func() (runtime.Object, error) {
+		return c.Tracker.Update(service)
+	})
+	updated, _ := obj.(*api.Service)
+	return updated, err
+}
+
+func (c *SimpleFake) DeleteService(id string) error {
+	_, err := c.invoke(FakeAction{Action: "delete-service", Value: id}, func() (runtime.Object, error) {
+		return nil, c.Tracker.Delete("Service", id)
+	})
+	return err
+}
+
+func (c *SimpleFake) WatchServices(label, field labels.Selector, resourceVersion uint64) (watch.Interface, error) {
+	return c.watch(FakeAction{Action: "watch-services", Value: resourceVersion}, "Service", label, resourceVersion)
+}
+
+func (c *SimpleFake) ListEndpoints(label, field labels.Selector) (*api.EndpointsList, error) {
+	obj, err := c.invoke(FakeAction{Action: "list-endpoints", Value: selectorsOf(label, field)}, func() (runtime.Object, error) {
+		return c.list("Endpoints", label, &api.EndpointsList{})
+	})
+	list, _ := obj.(*api.EndpointsList)
+	return list, err
+}
+
+func (c *SimpleFake) GetEndpoints(id string) (*api.Endpoints, error) {
+	obj, err := c.invoke(FakeAction{Action: "get-endpoints", Value: id}, func() (runtime.Object, error) {
+		return c.Tracker.Get("Endpoints", id)
+	})
+	endpoints, _ := obj.(*api.Endpoints)
+	return endpoints, err
+}
+
+func (c *SimpleFake) WatchEndpoints(label, field labels.Selector, resourceVersion uint64) (watch.Interface, error) {
+	return c.watch(FakeAction{Action: "watch-endpoints", Value: resourceVersion}, "Endpoints", label, resourceVersion)
+}
+
+func (c *SimpleFake) ServerVersion() (*version.Info, error) {
+	_, err := c.invoke(FakeAction{Action: "get-version"}, func() (runtime.Object, error) { return nil, nil })
+	if err != nil {
+		return nil, err
+	}
+	versionInfo := version.Get()
+	return &versionInfo, nil
+}
+
+func (c *SimpleFake) ListMinions(label, field labels.Selector) (*api.MinionList, error) {
+	obj, err := c.invoke(FakeAction{Action: "list-minions", Value: selectorsOf(label, field)}, func() (runtime.Object, error) {
+		return c.list("Minion", label, &api.MinionList{})
+	})
+	list, _ := obj.(*api.MinionList)
+	return list, err
+}
+
+func (c *SimpleFake) GetMinion(id string) (*api.Minion, error) {
+	obj, err := c.invoke(FakeAction{Action: "get-minion", Value: id}, func() (runtime.Object, error) {
+		return c.Tracker.Get("Minion", id)
+	})
+	minion, _ := obj.(*api.Minion)
+	return minion, err
+}
+
+func (c *SimpleFake) CreateMinion(minion *api.Minion) (*api.Minion, error) {
+	obj, err := c.invoke(FakeAction{Action: "create-minion", Value: minion}, func() (runtime.Object, error) {
+		return c.Tracker.Create(minion)
+	})
+	created, _ := obj.(*api.Minion)
+	return created, err
+}
+
+func (c *SimpleFake) DeleteMinion(id string) error {
+	_, err := c.invoke(FakeAction{Action: "delete-minion", Value: id}, func() (runtime.Object, error) {
+		return nil, c.Tracker.Delete("Minion", id)
+	})
+	return err
+}
+
+// CreateBinding assigns the pod of binding to its host, as the apiserver does.
+func (c *SimpleFake) CreateBinding(binding *api.Binding) error {
+	_, err := c.invoke(FakeAction{Action: "create-binding", Value: binding}, func() (runtime.Object, error) {
+		obj, err := c.Tracker.Get("Pod", binding.PodID)
+		if err != nil {
+			return nil, err
+		}
+		pod := obj.(*api.Pod)
+		pod.DesiredState.Host = binding.Host
+		return c.Tracker.Update(pod)
+	})
+	return err
+}
@@ -0,0 +1,129 @@
+package client
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/ryutah/kubernetes-transcribe/pkg/api"
+	"github.com/ryutah/kubernetes-transcribe/pkg/labels"
+	"github.com/ryutah/kubernetes-transcribe/pkg/runtime"
+	"github.com/ryutah/kubernetes-transcribe/pkg/watch"
+)
+
+var _ Interface = &SimpleFake{}
+
+func TestSimpleFakeWritesAreVisible(t *testing.T) {
+	c, err := NewSimpleFake(labeledPod("a", map[string]string{"app": "web"}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	w, err := c.WatchPods(labels.SelectorFromSet(labels.Set{"app": "web"}), labels.Everything(), 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer w.Stop()
+	if _, err := c.CreatePod(labeledPod("b", map[string]string{"app": "web"})); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := c.CreateBinding(&api.Binding{PodID: "b", Host: "h"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := c.DeletePod("a"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	list, err := c.ListPods(labels.Everything(), labels.Everything())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(list.Items) != 1 || list.Items[0].DesiredState.Host != "h" || list.ResourceVersion != 3 {
+		t.Errorf("unexpected list %#v", list)
+	}
+	for _, e := range []watch.EventType{watch.Added, watch.Modified, watch.Deleted} {
+		if event := <-w.ResultChan(); event.Type != e {
+			t.Errorf("expected %v, got %#v", e, event)
+		}
+	}
+	expected := []string{"watch-pods", "create-pod", "create-binding", "delete-pod", "list-pods"}
+	var actions []string
+	for _, action := range c.Actions() {
+		actions = append(actions, action.Action)
+	}
+	if !reflect.DeepEqual(expected, actions) {
+		t.Errorf("expected %v, got %v", expected, actions)
+	}
+}
+
+func TestSimpleFakeReactors(t *testing.T) {
+	c, _ := NewSimpleFake(labeledPod("a", nil))
+	failure := errors.New("injected")
+	c.AddReactor("delete-*", func(action FakeAction) (bool, runtime.Object, error) {
+		return true, nil, failure
+	})
+	c.AddReactor("get-pod", func(action FakeAction) (bool, runtime.Object, error) {
+		// Only handles "b".
+		if action.Value != "b" {
+			return false, nil, nil
+		}
+		return true, labeledPod("b", nil), nil
+	})
+	if err := c.DeletePod("a"); err != failure {
+		t.Errorf("expected the injected error, got %v", err)
+	}
+	if err := c.DeleteService("a"); err != failure {
+		t.Errorf("expected the injected error, got %v", err)
+	}
+	if pod, err := c.GetPod("a"); err != nil || pod.ID != "a" {
+		t.Errorf("expected the tracked pod, got %#v, %v", pod, err)
+	}
+	if pod, err := c.GetPod("b"); err != nil || pod.ID != "b" {
+		t.Errorf("expected the reactor's pod, got %#v, %v", pod, err)
+	}
+
+	// Reactors added later are tried first.
+	c.AddReactor("*", func(action FakeAction) (bool, runtime.Object, error) {
+		return true, nil, failure
+	})
+	if _, err := c.WatchPods(labels.Everything(), labels.Everything(), 0); err != failure {
+		t.Errorf("expected the injected error, got %v", err)
+	}
+}
+
+func TestReactorMatches(t *testing.T) {
+	table := []struct {
+		pattern, action string
+		matches         bool
+	}{
+		{"*", "get-pod", true},
+		{"get-pod", "get-pod", true},
+		{"get-pod", "get-pods", false},
+		{"get-*", "get-pod", true},
+		{"get-*", "list-pods", false},
+		{"create-*", "create-binding", true},
+	}
+	for _, item := range table {
+		if e, a := item.matches, (reactor{action: item.pattern}).matches(item.action); e != a {
+			t.Errorf("%s on %s: expected %v, got %v", item.pattern, item.action, e, a)
+		}
+	}
+}
+
+func TestSimpleFakeWatchFromVersion(t *testing.T) {
+	c, _ := NewSimpleFake()
+	c.CreatePod(labeledPod("a", nil))
+	list, err := c.ListPods(labels.Everything(), labels.Everything())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.CreatePod(labeledPod("b", nil))
+
+	w, err := c.WatchPods(labels.Everything(), labels.Everything(), list.ResourceVersion+1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer w.Stop()
+	if event := <-w.ResultChan(); event.Type != watch.Added || event.Object.(*api.Pod).ID != "b" {
+		t.Errorf("expected the pod created after the list, got %#v", event)
+	}
+}
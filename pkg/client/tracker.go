@@ -0,0 +1,325 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang/glog"
+	"github.com/ryutah/kubernetes-transcribe/pkg/api"
+	"github.com/ryutah/kubernetes-transcribe/pkg/api/errors"
+	"github.com/ryutah/kubernetes-transcribe/pkg/labels"
+	"github.com/ryutah/kubernetes-transcribe/pkg/runtime"
+	"github.com/ryutah/kubernetes-transcribe/pkg/watch"
+)
+
+// trackerWatchChanSize is how many events a watch of an ObjectTracker holds before
+// they are received. A watch which would hold more is closed, the way the apiserver
+// drops watchers too slow to keep up, so that writes to the tracker never block.
+const trackerWatchChanSize = 100
+
+// trackerHistorySize is how many of its latest changes an ObjectTracker keeps, to
+// replay to watches from past resource versions. It is no more than
+// trackerWatchChanSize, so that a replay never fills a new watch.
+const trackerHistorySize = trackerWatchChanSize
+
+// ObjectTracker holds API objects by kind and ID in memory, and changes them the
+// way the apiserver would: every write gets a new resource version, creates fail
+// if the object exists, updates fail if it doesn't or if the resource versions
+// don't match, and each change is sent to the watches of its kind. The latest
+// changes are kept, so that a watch can start from a past resource version. Objects are
+// copied in and out, so callers can't change the tracked objects. The kind of an
+// object is the name of its type, such as "Pod". ObjectTracker is safe for
+// concurrent use.
+type ObjectTracker struct {
+	lock     sync.Mutex
+	objects  map[string]map[string]runtime.Object
+	version  uint64
+	watchers map[string][]*trackerWatcher
+	// history holds the latest changes, oldest first, and expired is the version
+	// of the latest change dropped from it.
+	history []trackerChange
+	expired uint64
+}
+
+// trackerChange is a change made to an ObjectTracker, at version.
+type trackerChange struct {
+	kind    string
+	version uint64
+	event   watch.Event
+}
+
+// trackerWatcher is a watch of an ObjectTracker, for the changes from version from
+// on to the objects with labels matching selector.
+type trackerWatcher struct {
+	selector labels.Selector
+	from     uint64
+	result   chan watch.Event
+
+	// lock guards stopped, and the closing of result.
+	lock    sync.Mutex
+	stopped bool
+}
+
+// ResultChan implements watch.Interface.
+func (w *trackerWatcher) ResultChan() <-chan watch.Event {
+	return w.result
+}
+
+// Stop implements watch.Interface.
+func (w *trackerWatcher) Stop() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.stop()
+}
+
+// stop closes result, if it isn't already. w.lock must be held.
+func (w *trackerWatcher) stop() {
+	if !w.stopped {
+		w.stopped = true
+		close(w.result)
+	}
+}
+
+// isStopped returns true if w is stopped.
+func (w *trackerWatcher) isStopped() bool {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.stopped
+}
+
+// sendCopy sends a copy of event, a change made at version, if w watches it.
+// It returns true if the event was sent.
+func (w *trackerWatcher) sendCopy(version uint64, event watch.Event) bool {
+	if version < w.from || !w.selector.Matches(labels.Set(objectLabels(event.Object))) {
+		return false
+	}
+	copied, err := api.Scheme.Copy(event.Object)
+	if err != nil {
+		glog.Errorf("Failed to copy %#v: %v", event.Object, err)
+		return false
+	}
+	return w.send(watch.Event{Type: event.Type, Object: copied})
+}
+
+// send passes event on without blocking, and returns false if w is stopped. A
+// watch without room for event is stopped.
+func (w *trackerWatcher) send(event watch.Event) bool {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.stopped {
+		return false
+	}
+	select {
+	case w.result <- event:
+		return true
+	default:
+		glog.Errorf("Closing a watch holding %d unreceived events", len(w.result))
+		w.stop()
+		return false
+	}
+}
+
+// NewObjectTracker returns an ObjectTracker holding objects, which are taken as
+// they are, with their resource versions.
+func NewObjectTracker(objects ...runtime.Object) (*ObjectTracker, error) {
+	t := &ObjectTracker{
+		objects:  map[string]map[string]runtime.Object{},
+		watchers: map[string][]*trackerWatcher{},
+	}
+	for _, obj := range objects {
+		kind, id, err := kindAndID(obj)
+		if err != nil {
+			return nil, err
+		}
+		t.store(kind, id, obj)
+	}
+	return t, nil
+}
+
+// Get returns the object of kind with the given ID.
+func (t *ObjectTracker) Get(kind, id string) (runtime.Object, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	obj, ok := t.objects[kind][id]
+	if !ok {
+		return nil, errors.NewNotFound(strings.ToLower(kind), id)
+	}
+	return api.Scheme.Copy(obj)
+}
+
+// List returns the objects of kind with labels matching selector, ordered by ID,
+// and the current resource version.
+func (t *ObjectTracker) List(kind string, selector labels.Selector) ([]runtime.Object, uint64, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	ids := []string{}
+	for id, obj := range t.objects[kind] {
+		if selector.Matches(labels.Set(objectLabels(obj))) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	list := make([]runtime.Object, 0, len(ids))
+	for _, id := range ids {
+		obj, err := api.Scheme.Copy(t.objects[kind][id])
+		if err != nil {
+			return nil, 0, err
+		}
+		list = append(list, obj)
+	}
+	return list, t.version, nil
+}
+
+// Create adds obj, and returns it as stored.
+func (t *ObjectTracker) Create(obj runtime.Object) (runtime.Object, error) {
+	kind, id, err := kindAndID(obj)
+	if err != nil {
+		return nil, err
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if _, ok := t.objects[kind][id]; ok {
+		return nil, errors.NewAlreadyExists(strings.ToLower(kind), id)
+	}
+	return t.write(watch.Added, kind, id, obj)
+}
+
+// Update replaces the object with the ID of obj by obj, and returns it as stored.
+// A resource version of 0 in obj matches any.
+func (t *ObjectTracker) Update(obj runtime.Object) (runtime.Object, error) {
+	kind, id, err := kindAndID(obj)
+	if err != nil {
+		return nil, err
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	old, ok := t.objects[kind][id]
+	if !ok {
+		return nil, errors.NewNotFound(strings.ToLower(kind), id)
+	}
+	jsonBase, _ := runtime.FindJSONBase(obj)
+	oldJSONBase, _ := runtime.FindJSONBase(old)
+	if version := jsonBase.ResourceVersion(); version != 0 && version != oldJSONBase.ResourceVersion() {
+		return nil, errors.NewConflict(strings.ToLower(kind), id, fmt.Errorf("resource version %d does not match %d", version, oldJSONBase.ResourceVersion()))
+	}
+	return t.write(watch.Modified, kind, id, obj)
+}
+
+// Delete removes the object of kind with the given ID.
+func (t *ObjectTracker) Delete(kind, id string) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	obj, ok := t.objects[kind][id]
+	if !ok {
+		return errors.NewNotFound(strings.ToLower(kind), id)
+	}
+	delete(t.objects[kind], id)
+	t.version++
+	t.send(watch.Deleted, kind, obj)
+	return nil
+}
+
+// Watch returns a watch of the changes to the objects of kind with labels
+// matching selector, starting with the change made at resourceVersion, or from
+// now on if resourceVersion is 0. As from the apiserver, a StatusErr with
+// api.StatusReasonExpired is returned if the changes since resourceVersion are no
+// longer kept. The watch is closed if more than trackerWatchChanSize of its
+// events are waiting to be received.
+func (t *ObjectTracker) Watch(kind string, selector labels.Selector, resourceVersion uint64) (watch.Interface, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if resourceVersion != 0 && resourceVersion <= t.expired {
+		return nil, &StatusErr{api.Status{
+			Status:  api.StatusFailure,
+			Code:    http.StatusGone,
+			Reason:  api.StatusReasonExpired,
+			Message: fmt.Sprintf("resource version %d is too old to watch from", resourceVersion),
+		}}
+	}
+	w := &trackerWatcher{
+		selector: selector,
+		from:     resourceVersion,
+		result:   make(chan watch.Event, trackerWatchChanSize),
+	}
+	for _, change := range t.history {
+		if change.kind == kind {
+			w.sendCopy(change.version, change.event)
+		}
+	}
+	t.watchers[kind] = append(t.watchers[kind], w)
+	return w, nil
+}
+
+// write stores a copy of obj, with a new resource version, and sends it to the
+// watches of kind in an event of type eventType. t.lock must be held.
+func (t *ObjectTracker) write(eventType watch.EventType, kind, id string, obj runtime.Object) (runtime.Object, error) {
+	obj, err := api.Scheme.Copy(obj)
+	if err != nil {
+		return nil, err
+	}
+	jsonBase, err := runtime.FindJSONBase(obj)
+	if err != nil {
+		return nil, err
+	}
+	t.version++
+	jsonBase.SetResourceVersion(t.version)
+	t.store(kind, id, obj)
+	t.send(eventType, kind, obj)
+	return api.Scheme.Copy(obj)
+}
+
+// store keeps obj as the object of kind with the given ID.
+func (t *ObjectTracker) store(kind, id string, obj runtime.Object) {
+	if t.objects[kind] == nil {
+		t.objects[kind] = map[string]runtime.Object{}
+	}
+	t.objects[kind][id] = obj
+}
+
+// send records the change of obj at t.version in the history, and sends a copy of
+// obj to the watches of kind, in an event of type eventType. It forgets about
+// stopped watches. t.lock must be held.
+func (t *ObjectTracker) send(eventType watch.EventType, kind string, obj runtime.Object) {
+	event := watch.Event{Type: eventType, Object: obj}
+	if len(t.history) == trackerHistorySize {
+		t.expired = t.history[0].version
+		t.history = append(t.history[:0], t.history[1:]...)
+	}
+	t.history = append(t.history, trackerChange{kind, t.version, event})
+
+	watchers := t.watchers[kind][:0]
+	for _, w := range t.watchers[kind] {
+		if w.sendCopy(t.version, event) || !w.isStopped() {
+			watchers = append(watchers, w)
+		}
+	}
+	t.watchers[kind] = watchers
+}
+
+// kindAndID returns the kind of obj, which is the name of its type, and its ID.
+func kindAndID(obj runtime.Object) (kind, id string, err error) {
+	jsonBase, err := runtime.FindJSONBase(obj)
+	if err != nil {
+		return "", "", err
+	}
+	if jsonBase.ID() == "" {
+		return "", "", fmt.Errorf("object has no ID: %#v", obj)
+	}
+	return reflect.TypeOf(obj).Elem().Name(), jsonBase.ID(), nil
+}
+
+// objectLabels returns the labels of obj, or nil if it has none.
+func objectLabels(obj runtime.Object) map[string]string {
+	v := reflect.ValueOf(obj).Elem().FieldByName("Labels")
+	if !v.IsValid() {
+		return nil
+	}
+	if labels, ok := v.Interface().(map[string]string); ok {
+		return labels
+	}
+	return nil
+}
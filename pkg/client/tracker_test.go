@@ -0,0 +1,166 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/ryutah/kubernetes-transcribe/pkg/api"
+	"github.com/ryutah/kubernetes-transcribe/pkg/api/errors"
+	"github.com/ryutah/kubernetes-transcribe/pkg/labels"
+	"github.com/ryutah/kubernetes-transcribe/pkg/watch"
+)
+
+func labeledPod(id string, podLabels map[string]string) *api.Pod {
+	return &api.Pod{JSONBase: api.JSONBase{ID: id}, Labels: podLabels}
+}
+
+func TestObjectTrackerCreateUpdate(t *testing.T) {
+	tracker, err := NewObjectTracker(labeledPod("a", nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := tracker.Create(labeledPod("a", nil)); !errors.IsAlreadyExists(err) {
+		t.Errorf("expected an already exists error, got %v", err)
+	}
+	created, err := tracker.Create(labeledPod("b", nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pod := created.(*api.Pod)
+	if e, a := uint64(1), pod.ResourceVersion; e != a {
+		t.Errorf("expected version %v, got %v", e, a)
+	}
+
+	pod.DesiredState.Host = "h"
+	updated, err := tracker.Update(pod)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e, a := uint64(2), updated.(*api.Pod).ResourceVersion; e != a {
+		t.Errorf("expected version %v, got %v", e, a)
+	}
+	// pod is now at a stale version.
+	if _, err := tracker.Update(pod); !errors.IsConflict(err) {
+		t.Errorf("expected a conflict, got %v", err)
+	}
+	if _, err := tracker.Update(labeledPod("c", nil)); !errors.IsNotFound(err) {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+
+	got, err := tracker.Get("Pod", "b")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The tracked object can't be changed through the objects handed out.
+	updated.(*api.Pod).DesiredState.Host = "changed"
+	if e, a := "h", got.(*api.Pod).DesiredState.Host; e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+}
+
+func TestObjectTrackerWatch(t *testing.T) {
+	tracker, _ := NewObjectTracker()
+	w, err := tracker.Watch("Pod", labels.SelectorFromSet(labels.Set{"app": "web"}), 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer w.Stop()
+	tracker.Create(labeledPod("db", map[string]string{"app": "db"}))
+	tracker.Create(labeledPod("web", map[string]string{"app": "web"}))
+	tracker.Create(&api.Service{JSONBase: api.JSONBase{ID: "web"}, Labels: map[string]string{"app": "web"}})
+	if err := tracker.Delete("Pod", "web"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := tracker.Delete("Pod", "web"); !errors.IsNotFound(err) {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+
+	for _, e := range []watch.EventType{watch.Added, watch.Deleted} {
+		event := <-w.ResultChan()
+		if event.Type != e || event.Object.(*api.Pod).ID != "web" {
+			t.Errorf("expected %v of web, got %#v", e, event)
+		}
+	}
+	select {
+	case event := <-w.ResultChan():
+		t.Errorf("unexpected event %#v", event)
+	default:
+	}
+}
+
+func TestObjectTrackerSlowWatch(t *testing.T) {
+	tracker, _ := NewObjectTracker()
+	slow, err := tracker.Watch("Pod", labels.Everything(), 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := 0; i <= trackerWatchChanSize; i++ {
+		tracker.Create(labeledPod(string(rune('a'+i%26))+string(rune('a'+i/26)), nil))
+	}
+	received := 0
+	for range slow.ResultChan() {
+		received++
+	}
+	if e, a := trackerWatchChanSize, received; e != a {
+		t.Errorf("expected %d events before the watch was closed, got %d", e, a)
+	}
+	// Stopping a closed watch, and writing afterwards, is fine.
+	slow.Stop()
+	if _, err := tracker.Create(labeledPod("last", nil)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestObjectTrackerWatchFromVersion(t *testing.T) {
+	tracker, _ := NewObjectTracker()
+	tracker.Create(labeledPod("a", map[string]string{"app": "web"}))
+	tracker.Create(labeledPod("b", map[string]string{"app": "db"}))
+	tracker.Create(&api.Service{JSONBase: api.JSONBase{ID: "c"}})
+	tracker.Update(labeledPod("a", map[string]string{"app": "web"}))
+	tracker.Delete("Pod", "a")
+
+	w, err := tracker.Watch("Pod", labels.SelectorFromSet(labels.Set{"app": "web"}), 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer w.Stop()
+	tracker.Create(labeledPod("d", map[string]string{"app": "web"}))
+
+	expected := []struct {
+		eventType watch.EventType
+		id        string
+	}{{watch.Modified, "a"}, {watch.Deleted, "a"}, {watch.Added, "d"}}
+	for _, e := range expected {
+		event := <-w.ResultChan()
+		if event.Type != e.eventType || event.Object.(*api.Pod).ID != e.id {
+			t.Errorf("expected %v of %v, got %#v", e.eventType, e.id, event)
+		}
+	}
+	select {
+	case event := <-w.ResultChan():
+		t.Errorf("unexpected event %#v", event)
+	default:
+	}
+}
+
+func TestObjectTrackerWatchExpired(t *testing.T) {
+	tracker, _ := NewObjectTracker()
+	for i := 0; i <= trackerHistorySize; i++ {
+		tracker.Create(labeledPod(string(rune('a'+i%26))+string(rune('a'+i/26)), nil))
+	}
+	_, err := tracker.Watch("Pod", labels.Everything(), 1)
+	if statusErr, ok := err.(*StatusErr); !ok || !isExpired(&statusErr.Status) {
+		t.Fatalf("expected an expired error, got %v", err)
+	}
+
+	w, err := tracker.Watch("Pod", labels.Everything(), 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer w.Stop()
+	for i := 0; i < trackerHistorySize; i++ {
+		event := <-w.ResultChan()
+		if e, a := uint64(i+2), event.Object.(*api.Pod).ResourceVersion; e != a {
+			t.Fatalf("expected %v, got %v", e, a)
+		}
+	}
+}
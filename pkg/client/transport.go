@@ -0,0 +1,189 @@
+package client
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TransportFor returns the http.RoundTripper making the requests of clients
+// created from config. It is a chain: round trippers adding credentials, if any,
+// wrap the transport, which is config.Transport if set, or one using the TLS
+// settings of config otherwise.
+func TransportFor(config *Config) (http.RoundTripper, error) {
+	transport, _, err := transportFor(config)
+	return transport, err
+}
+
+// transportFor returns the transport for config, along with its TLS settings.
+func transportFor(config *Config) (http.RoundTripper, *tls.Config, error) {
+	tlsConfig, err := tlsConfigFor(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	transport := config.Transport
+	if transport == nil {
+		transport = &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConfig,
+		}
+	}
+
+	auth := config.Auth
+	if auth == nil {
+		auth = &AuthInfo{}
+	}
+	switch {
+	case config.BearerTokenFile != "":
+		if auth.BearerToken != "" {
+			return nil, nil, fmt.Errorf("a bearer token and a bearer token file cannot both be used")
+		}
+		transport = &bearerAuthRoundTripper{
+			token: (&tokenFile{path: config.BearerTokenFile}).Token,
+			rt:    transport,
+		}
+	case auth.BearerToken != "":
+		token := auth.BearerToken
+		transport = &bearerAuthRoundTripper{
+			token: func() (string, error) { return token, nil },
+			rt:    transport,
+		}
+	case auth.User != "" || auth.Password != "":
+		transport = &basicAuthRoundTripper{user: auth.User, password: auth.Password, rt: transport}
+	}
+	return transport, tlsConfig, nil
+}
+
+// authRoundTripper is implemented by the round trippers of a chain which add
+// credentials to requests, so that the credentials can also be added to requests
+// made otherwise, such as websocket handshakes.
+type authRoundTripper interface {
+	http.RoundTripper
+	// authorize adds the credentials to req.
+	authorize(req *http.Request) error
+	// wrapped returns the next round tripper of the chain.
+	wrapped() http.RoundTripper
+}
+
+// authorize adds to req the credentials the round trippers of the chain rt would.
+func authorize(rt http.RoundTripper, req *http.Request) error {
+	for {
+		auth, ok := rt.(authRoundTripper)
+		if !ok {
+			return nil
+		}
+		if err := auth.authorize(req); err != nil {
+			return err
+		}
+		rt = auth.wrapped()
+	}
+}
+
+// roundTripWith sends a copy of req, with credentials added by auth, to the
+// round tripper auth wraps. Round trippers must not change the requests they are
+// given.
+func roundTripWith(auth authRoundTripper, req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	if err := auth.authorize(req); err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+	return auth.wrapped().RoundTrip(req)
+}
+
+// basicAuthRoundTripper authenticates requests with a user and password.
+type basicAuthRoundTripper struct {
+	user     string
+	password string
+	rt       http.RoundTripper
+}
+
+func (b *basicAuthRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	return roundTripWith(b, req)
+}
+
+func (b *basicAuthRoundTripper) authorize(req *http.Request) error {
+	req.SetBasicAuth(b.user, b.password)
+	return nil
+}
+
+func (b *basicAuthRoundTripper) wrapped() http.RoundTripper {
+	return b.rt
+}
+
+// bearerAuthRoundTripper authenticates requests with the bearer token returned
+// by token.
+type bearerAuthRoundTripper struct {
+	token func() (string, error)
+	rt    http.RoundTripper
+}
+
+func (b *bearerAuthRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	return roundTripWith(b, req)
+}
+
+func (b *bearerAuthRoundTripper) authorize(req *http.Request) error {
+	token, err := b.token()
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+func (b *bearerAuthRoundTripper) wrapped() http.RoundTripper {
+	return b.rt
+}
+
+// tokenFile holds the token read from a file, and reads it again whenever the
+// file changes, so that tokens can be rotated without restarting clients.
+type tokenFile struct {
+	path string
+
+	lock    sync.Mutex
+	token   string
+	modTime time.Time
+	size    int64
+}
+
+// Token returns the token in the file.
+func (f *tokenFile) Token() (string, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	info, err := os.Stat(f.path)
+	if err != nil {
+		if f.token != "" {
+			// Keep using the last token while the file is being replaced.
+			return f.token, nil
+		}
+		return "", err
+	}
+	if f.token != "" && info.ModTime().Equal(f.modTime) && info.Size() == f.size {
+		return f.token, nil
+	}
+	// Keep using the last token while the file is being replaced or written, and
+	// read it again next time.
+	data, err := ioutil.ReadFile(f.path)
+	if err != nil {
+		if f.token != "" {
+			return f.token, nil
+		}
+		return "", err
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		if f.token != "" {
+			return f.token, nil
+		}
+		return "", fmt.Errorf("no token in %s", f.path)
+	}
+	f.token, f.modTime, f.size = token, info.ModTime(), info.Size()
+	return f.token, nil
+}
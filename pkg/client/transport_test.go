@@ -0,0 +1,112 @@
+package client
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// recordingRoundTripper records the requests it is given, and answers them with
+// 200 OK.
+type recordingRoundTripper struct {
+	requests []*http.Request
+}
+
+func (r *recordingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	r.requests = append(r.requests, req)
+	return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(nil), Request: req}, nil
+}
+
+func TestTransportForAuth(t *testing.T) {
+	table := map[string]struct {
+		config       Config
+		expectHeader string
+	}{
+		"none":   {Config{}, ""},
+		"basic":  {Config{Auth: &AuthInfo{User: "user", Password: "pass"}}, "Basic dXNlcjpwYXNz"},
+		"bearer": {Config{Auth: &AuthInfo{BearerToken: "token"}}, "Bearer token"},
+	}
+	for name, item := range table {
+		rt := &recordingRoundTripper{}
+		item.config.Transport = rt
+		transport, err := TransportFor(&item.config)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		req, _ := http.NewRequest("GET", "http://localhost/api", nil)
+		if _, err := transport.RoundTrip(req); err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if e, a := item.expectHeader, rt.requests[0].Header.Get("Authorization"); e != a {
+			t.Errorf("%s: expected %q, got %q", name, e, a)
+		}
+		if a := req.Header.Get("Authorization"); a != "" {
+			t.Errorf("%s: expected the original request to be left alone, got %q", name, a)
+		}
+
+		// Websocket handshakes are authorized the same way.
+		handshake, _ := http.NewRequest("GET", "http://localhost/api", nil)
+		if err := authorize(transport, handshake); err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if e, a := item.expectHeader, handshake.Header.Get("Authorization"); e != a {
+			t.Errorf("%s: expected %q, got %q", name, e, a)
+		}
+	}
+}
+
+func TestTransportForTokenAndTokenFile(t *testing.T) {
+	_, err := TransportFor(&Config{Auth: &AuthInfo{BearerToken: "token"}, BearerTokenFile: "/some/file"})
+	if err == nil {
+		t.Errorf("expected an error")
+	}
+}
+
+func TestTokenFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "token")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "token")
+	// write writes data to the token file, and makes it look changed.
+	modTime := time.Now()
+	write := func(data string) {
+		if err := ioutil.WriteFile(path, []byte(data), 0600); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		modTime = modTime.Add(time.Second)
+		os.Chtimes(path, modTime, modTime)
+	}
+	f := &tokenFile{path: path}
+	expectToken := func(step, expected string) {
+		token, err := f.Token()
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", step, err)
+		}
+		if expected != token {
+			t.Errorf("%s: expected %q, got %q", step, expected, token)
+		}
+	}
+
+	if _, err := f.Token(); err == nil {
+		t.Errorf("expected an error for a missing file")
+	}
+	write("")
+	if _, err := f.Token(); err == nil {
+		t.Errorf("expected an error for an empty file")
+	}
+	write("first\n")
+	expectToken("read", "first")
+	write("second")
+	expectToken("reload", "second")
+	write("")
+	expectToken("half written", "second")
+	os.Remove(path)
+	expectToken("replaced", "second")
+	write("third")
+	expectToken("rotated", "third")
+}
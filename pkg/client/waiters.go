@@ -0,0 +1,235 @@
+package client
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/ryutah/kubernetes-transcribe/pkg/api"
+	"github.com/ryutah/kubernetes-transcribe/pkg/labels"
+	"github.com/ryutah/kubernetes-transcribe/pkg/runtime"
+	"github.com/ryutah/kubernetes-transcribe/pkg/util/wait"
+	"github.com/ryutah/kubernetes-transcribe/pkg/watch"
+)
+
+// rewatchPeriod is how long waiters wait before watching again after failing to.
+var rewatchPeriod = time.Second
+
+// WaitForPodRunning waits until the pod with the given ID is running, and returns
+// it. It returns wait.ErrWaitTimeout if the pod isn't running within timeout, and
+// an error if the pod terminates.
+func (c *Client) WaitForPodRunning(id string, timeout time.Duration) (*api.Pod, error) {
+	return waitForPodRunning(c, id, timeout)
+}
+
+func waitForPodRunning(c PodInterface, id string, timeout time.Duration) (*api.Pod, error) {
+	var pod *api.Pod
+	err := waitForPods(c, labels.Everything(), timeout, func(pods map[string]runtime.Object) (bool, error) {
+		obj, ok := pods[id]
+		if !ok {
+			return false, nil
+		}
+		pod = obj.(*api.Pod)
+		switch pod.CurrentState.Status {
+		case api.PodRunning:
+			return true, nil
+		case api.PodTerminated:
+			return false, fmt.Errorf("pod %q terminated", id)
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pod, nil
+}
+
+// WaitForPodsWithLabelRunning waits until there are pods matching selector, and
+// all of them are running. It returns wait.ErrWaitTimeout if that isn't the case
+// within timeout.
+func (c *Client) WaitForPodsWithLabelRunning(selector labels.Selector, timeout time.Duration) error {
+	return waitForPodsWithLabelRunning(c, selector, timeout)
+}
+
+func waitForPodsWithLabelRunning(c PodInterface, selector labels.Selector, timeout time.Duration) error {
+	return waitForPods(c, selector, timeout, func(pods map[string]runtime.Object) (bool, error) {
+		if len(pods) == 0 {
+			return false, nil
+		}
+		for _, obj := range pods {
+			if obj.(*api.Pod).CurrentState.Status != api.PodRunning {
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+}
+
+// WaitForServiceEndpoints waits until the service with the given ID has endpoints,
+// and returns them. It returns wait.ErrWaitTimeout if the service has none within
+// timeout.
+func (c *Client) WaitForServiceEndpoints(id string, timeout time.Duration) (*api.Endpoints, error) {
+	return waitForServiceEndpoints(c, id, timeout)
+}
+
+func waitForServiceEndpoints(c EndpointsInterface, id string, timeout time.Duration) (*api.Endpoints, error) {
+	var endpoints *api.Endpoints
+	list := func() (runtime.Object, error) {
+		return c.ListEndpoints(labels.Everything(), labels.Everything())
+	}
+	watchFrom := func(resourceVersion uint64) (watch.Interface, error) {
+		return c.WatchEndpoints(labels.Everything(), labels.Everything(), resourceVersion)
+	}
+	err := waitForObjects(list, watchFrom, timeout, func(objects map[string]runtime.Object) (bool, error) {
+		obj, ok := objects[id]
+		if !ok || len(obj.(*api.Endpoints).Endpoints) == 0 {
+			return false, nil
+		}
+		endpoints = obj.(*api.Endpoints)
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return endpoints, nil
+}
+
+// waitForPods waits until condition holds for the pods matching selector.
+func waitForPods(c PodInterface, selector labels.Selector, timeout time.Duration, condition func(map[string]runtime.Object) (bool, error)) error {
+	list := func() (runtime.Object, error) {
+		return c.ListPods(selector, labels.Everything())
+	}
+	watchFrom := func(resourceVersion uint64) (watch.Interface, error) {
+		return c.WatchPods(selector, labels.Everything(), resourceVersion)
+	}
+	return waitForObjects(list, watchFrom, timeout, condition)
+}
+
+// waitForObjects lists objects, then watches them from the version listed,
+// keeping track of them by ID, until condition holds for them, fails, or timeout
+// elapses, in which case wait.ErrWaitTimeout is returned. The watch is resumed
+// from the last version seen whenever it ends, and if that version is too old to
+// watch from, the objects are listed again.
+func waitForObjects(list func() (runtime.Object, error), watchFrom func(uint64) (watch.Interface, error),
+	timeout time.Duration, condition func(map[string]runtime.Object) (bool, error)) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	deadline := timer.C
+
+	for {
+		objects, resourceVersion, err := listObjects(list, deadline)
+		if err != nil {
+			return err
+		}
+		if done, err := condition(objects); err != nil || done {
+			return err
+		}
+		err = watchUntil(watchFrom, deadline, objects, resourceVersion, condition)
+		if statusErr, ok := err.(*StatusErr); !ok || !isExpired(&statusErr.Status) {
+			return err
+		}
+		glog.Infof("Version %d is too old to watch from, listing again", resourceVersion)
+	}
+}
+
+// listObjects lists objects by ID, with the version they were listed at, unless
+// the deadline comes first.
+func listObjects(list func() (runtime.Object, error), deadline <-chan time.Time) (map[string]runtime.Object, uint64, error) {
+	// The list can't be cancelled, but stop waiting for it at the deadline.
+	type listResult struct {
+		obj runtime.Object
+		err error
+	}
+	listCh := make(chan listResult, 1)
+	go func() {
+		obj, err := list()
+		listCh <- listResult{obj, err}
+	}()
+	var listed runtime.Object
+	select {
+	case result := <-listCh:
+		if result.err != nil {
+			return nil, 0, result.err
+		}
+		listed = result.obj
+	case <-deadline:
+		return nil, 0, wait.ErrWaitTimeout
+	}
+	jsonBase, err := runtime.FindJSONBase(listed)
+	if err != nil {
+		return nil, 0, err
+	}
+	items, err := runtime.ExtractList(listed)
+	if err != nil {
+		return nil, 0, err
+	}
+	objects := map[string]runtime.Object{}
+	for _, item := range items {
+		itemBase, err := runtime.FindJSONBase(item)
+		if err != nil {
+			return nil, 0, err
+		}
+		objects[itemBase.ID()] = item
+	}
+	return objects, jsonBase.ResourceVersion(), nil
+}
+
+// watchUntil watches objects from resourceVersion, watching again whenever the watch
+// ends, until condition holds, fails, or the deadline comes.
+func watchUntil(watchFrom func(uint64) (watch.Interface, error), deadline <-chan time.Time,
+	objects map[string]runtime.Object, resourceVersion uint64, condition func(map[string]runtime.Object) (bool, error)) error {
+	for {
+		w, err := watchFrom(resourceVersion)
+		if err != nil {
+			if statusErr, ok := err.(*StatusErr); ok && isExpired(&statusErr.Status) {
+				return err
+			}
+			glog.Errorf("Failed to watch, retrying: %v", err)
+			select {
+			case <-time.After(rewatchPeriod):
+				continue
+			case <-deadline:
+				return wait.ErrWaitTimeout
+			}
+		}
+		done, err := watchObjects(w, deadline, objects, &resourceVersion, condition)
+		w.Stop()
+		if err != nil || done {
+			return err
+		}
+	}
+}
+
+// watchObjects applies the events of w to objects, and keeps *resourceVersion up
+// to date, until condition holds, fails, or w ends, in which case it returns false.
+func watchObjects(w watch.Interface, deadline <-chan time.Time, objects map[string]runtime.Object,
+	resourceVersion *uint64, condition func(map[string]runtime.Object) (bool, error)) (bool, error) {
+	for {
+		select {
+		case event, ok := <-w.ResultChan():
+			if !ok {
+				return false, nil
+			}
+			if event.Type == watch.Error {
+				return false, statusError(event.Object)
+			}
+			jsonBase, err := runtime.FindJSONBase(event.Object)
+			if err != nil {
+				glog.Errorf("Unable to understand watch event %#v", event)
+				continue
+			}
+			switch event.Type {
+			case watch.Added, watch.Modified:
+				objects[jsonBase.ID()] = event.Object
+			case watch.Deleted:
+				delete(objects, jsonBase.ID())
+			}
+			*resourceVersion = jsonBase.ResourceVersion() + 1
+			if done, err := condition(objects); err != nil || done {
+				return done, err
+			}
+		case <-deadline:
+			return false, wait.ErrWaitTimeout
+		}
+	}
+}
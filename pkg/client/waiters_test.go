@@ -0,0 +1,234 @@
+package client
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ryutah/kubernetes-transcribe/pkg/api"
+	"github.com/ryutah/kubernetes-transcribe/pkg/labels"
+	"github.com/ryutah/kubernetes-transcribe/pkg/runtime"
+	"github.com/ryutah/kubernetes-transcribe/pkg/util/wait"
+	"github.com/ryutah/kubernetes-transcribe/pkg/watch"
+)
+
+func TestWaitForObjectsListTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	list := func() (runtime.Object, error) {
+		<-release
+		return nil, nil
+	}
+	watchFrom := func(uint64) (watch.Interface, error) {
+		t.Errorf("unexpected watch")
+		return watch.NewFake(), nil
+	}
+	condition := func(map[string]runtime.Object) (bool, error) {
+		t.Errorf("unexpected check")
+		return true, nil
+	}
+	if err := waitForObjects(list, watchFrom, 10*time.Millisecond, condition); err != wait.ErrWaitTimeout {
+		t.Errorf("expected %v, got %v", wait.ErrWaitTimeout, err)
+	}
+}
+
+func TestWaitForObjectsRelistsWhenExpired(t *testing.T) {
+	lists := []runtime.Object{
+		&api.PodList{JSONBase: api.JSONBase{ResourceVersion: 1}},
+		&api.PodList{JSONBase: api.JSONBase{ResourceVersion: 10}, Items: []api.Pod{*podAt("a", 9)}},
+	}
+	listed := 0
+	list := func() (runtime.Object, error) {
+		listed++
+		return lists[listed-1], nil
+	}
+	watched := []uint64{}
+	watchFrom := func(resourceVersion uint64) (watch.Interface, error) {
+		watched = append(watched, resourceVersion)
+		w := watch.NewFakeWithChanSize(1)
+		w.Action(watch.Error, &api.Status{Status: api.StatusFailure, Code: 410, Reason: api.StatusReasonExpired})
+		return w, nil
+	}
+	condition := func(objects map[string]runtime.Object) (bool, error) {
+		return len(objects) == 1, nil
+	}
+	if err := waitForObjects(list, watchFrom, time.Second, condition); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e, a := 2, listed; e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+	if e, a := []uint64{1}, watched; !reflect.DeepEqual(e, a) {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+}
+
+// fakeListerWatcher lists Pods or EndpointsList, and hands out watches in order,
+// recording the versions they were made from. Once they run out, it hands out
+// watches which never send anything.
+type fakeListerWatcher struct {
+	Fake
+	watches     []*watch.FakeWatcher
+	watchedFrom []uint64
+}
+
+func (f *fakeListerWatcher) WatchPods(label, field labels.Selector, resourceVersion uint64) (watch.Interface, error) {
+	f.Fake.WatchPods(label, field, resourceVersion)
+	return f.nextWatch(resourceVersion), nil
+}
+
+func (f *fakeListerWatcher) WatchEndpoints(label, field labels.Selector, resourceVersion uint64) (watch.Interface, error) {
+	f.Fake.WatchEndpoints(label, field, resourceVersion)
+	return f.nextWatch(resourceVersion), nil
+}
+
+func (f *fakeListerWatcher) nextWatch(resourceVersion uint64) watch.Interface {
+	f.watchedFrom = append(f.watchedFrom, resourceVersion)
+	if len(f.watches) == 0 {
+		return watch.NewFake()
+	}
+	w := f.watches[0]
+	f.watches = f.watches[1:]
+	return w
+}
+
+// fakeWatch returns a watch holding events.
+func fakeWatch(events ...watch.Event) *watch.FakeWatcher {
+	w := watch.NewFakeWithChanSize(len(events))
+	for _, event := range events {
+		w.Action(event.Type, event.Object)
+	}
+	return w
+}
+
+func podInState(id string, version uint64, status api.PodStatus) *api.Pod {
+	pod := podAt(id, version)
+	pod.CurrentState.Status = status
+	return pod
+}
+
+func TestWaitForPodRunning(t *testing.T) {
+	table := map[string]struct {
+		listed    *api.Pod
+		events    []watch.Event
+		expectErr bool
+		timeout   bool
+	}{
+		"running": {
+			podInState("a", 1, api.PodWaiting),
+			[]watch.Event{{Type: watch.Modified, Object: podInState("a", 2, api.PodRunning)}},
+			false, false,
+		},
+		"created running": {
+			nil,
+			[]watch.Event{{Type: watch.Added, Object: podInState("b", 2, api.PodWaiting)}, {Type: watch.Added, Object: podInState("a", 3, api.PodRunning)}},
+			false, false,
+		},
+		"already running": {podInState("a", 1, api.PodRunning), nil, false, false},
+		"terminated": {
+			podInState("a", 1, api.PodWaiting),
+			[]watch.Event{{Type: watch.Modified, Object: podInState("a", 2, api.PodTerminated)}},
+			true, false,
+		},
+		"deleted": {
+			podInState("a", 1, api.PodWaiting),
+			[]watch.Event{{Type: watch.Deleted, Object: podInState("a", 2, api.PodWaiting)}},
+			true, true,
+		},
+	}
+	for name, item := range table {
+		f := &fakeListerWatcher{watches: []*watch.FakeWatcher{fakeWatch(item.events...)}}
+		f.Pods.ResourceVersion = 1
+		if item.listed != nil {
+			f.Pods.Items = []api.Pod{*item.listed}
+		}
+		pod, err := waitForPodRunning(f, "a", 50*time.Millisecond)
+		if item.expectErr {
+			if err == nil || (err == wait.ErrWaitTimeout) != item.timeout {
+				t.Errorf("%s: unexpected error: %v", name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", name, err)
+			continue
+		}
+		if pod.ID != "a" || pod.CurrentState.Status != api.PodRunning {
+			t.Errorf("%s: expected running pod a, got %#v", name, pod)
+		}
+	}
+}
+
+func TestWaitForPodsWithLabelRunning(t *testing.T) {
+	f := &fakeListerWatcher{watches: []*watch.FakeWatcher{fakeWatch(
+		watch.Event{Type: watch.Modified, Object: podInState("a", 2, api.PodRunning)},
+		watch.Event{Type: watch.Modified, Object: podInState("b", 3, api.PodRunning)},
+	)}}
+	f.Pods = api.PodList{
+		JSONBase: api.JSONBase{ResourceVersion: 1},
+		Items:    []api.Pod{*podInState("a", 1, api.PodWaiting), *podInState("b", 1, api.PodWaiting)},
+	}
+	selector := labels.SelectorFromSet(labels.Set{"app": "web"})
+	if err := waitForPodsWithLabelRunning(f, selector, time.Second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []FakeAction{
+		{Action: "list-pods", Value: FakeSelectors{Label: "app=web"}},
+		{Action: "watch-pods", Value: uint64(1)},
+	}
+	if e, a := expected, f.FakeActions(); !reflect.DeepEqual(e, a) {
+		t.Errorf("expected %#v, got %#v", e, a)
+	}
+
+	// Without pods, there is nothing running.
+	f = &fakeListerWatcher{}
+	if err := waitForPodsWithLabelRunning(f, selector, 10*time.Millisecond); err != wait.ErrWaitTimeout {
+		t.Errorf("expected %v, got %v", wait.ErrWaitTimeout, err)
+	}
+}
+
+func TestWaitForServiceEndpoints(t *testing.T) {
+	f := &fakeListerWatcher{watches: []*watch.FakeWatcher{fakeWatch(
+		watch.Event{Type: watch.Modified, Object: &api.Endpoints{JSONBase: api.JSONBase{ID: "other", ResourceVersion: 2}, Endpoints: []string{"1.2.3.4:80"}}},
+		watch.Event{Type: watch.Modified, Object: &api.Endpoints{JSONBase: api.JSONBase{ID: "s", ResourceVersion: 3}, Endpoints: []string{"1.2.3.5:80"}}},
+	)}}
+	f.EndpointsList = api.EndpointsList{
+		JSONBase: api.JSONBase{ResourceVersion: 1},
+		Items:    []api.Endpoints{{JSONBase: api.JSONBase{ID: "s", ResourceVersion: 1}}},
+	}
+	endpoints, err := waitForServiceEndpoints(f, "s", time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e, a := []string{"1.2.3.5:80"}, endpoints.Endpoints; !reflect.DeepEqual(e, a) {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+}
+
+func TestWaitForPodRunningResumesWatch(t *testing.T) {
+	first := fakeWatch(watch.Event{Type: watch.Modified, Object: podInState("a", 5, api.PodWaiting)})
+	first.Stop()
+	second := fakeWatch(watch.Event{Type: watch.Modified, Object: podInState("a", 6, api.PodRunning)})
+	f := &fakeListerWatcher{watches: []*watch.FakeWatcher{first, second}}
+	f.Pods = api.PodList{JSONBase: api.JSONBase{ResourceVersion: 1}}
+
+	pod, err := waitForPodRunning(f, "a", time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e, a := uint64(6), pod.ResourceVersion; e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+	if e, a := []uint64{1, 6}, f.watchedFrom; !reflect.DeepEqual(e, a) {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+	var lists int
+	for _, action := range f.FakeActions() {
+		if action.Action == "list-pods" {
+			lists++
+		}
+	}
+	if lists != 1 {
+		t.Errorf("expected the watch to be resumed without listing again, got %d lists", lists)
+	}
+}
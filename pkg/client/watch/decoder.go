@@ -34,7 +34,7 @@ func (d *APIEventDecoder) Decode() (action watch.EventType, object runtime.Objec
 		return action, nil, err
 	}
 	switch got.Type {
-	case watch.Added, watch.Modified, watch.Deleted:
+	case watch.Added, watch.Modified, watch.Deleted, watch.Error:
 		return got.Type, got.Object.Object, err
 	}
 	return action, nil, fmt.Errorf("got invalid watch event type: %v", got.Type)
@@ -44,3 +44,45 @@ func (d *APIEventDecoder) Decode() (action watch.EventType, object runtime.Objec
 func (d *APIEventDecoder) Close() {
 	d.stream.Close()
 }
+
+// ObjectEventDecoder is like APIEventDecoder, but decodes the objects of events
+// with a function of its own, so that objects of kinds api.Scheme doesn't know
+// can be decoded too.
+type ObjectEventDecoder struct {
+	stream  io.ReadCloser
+	decoder *json.Decoder
+	decode  func(data []byte) (runtime.Object, error)
+}
+
+// NewObjectEventDecoder creates an ObjectEventDecoder for the given stream, which
+// decodes objects with decode.
+func NewObjectEventDecoder(stream io.ReadCloser, decode func(data []byte) (runtime.Object, error)) *ObjectEventDecoder {
+	return &ObjectEventDecoder{
+		stream:  stream,
+		decoder: json.NewDecoder(stream),
+		decode:  decode,
+	}
+}
+
+// Decode blocks until it can return the next object in the stream. Returns an error
+// if the stream is closed or an object can't be decoded.
+func (d *ObjectEventDecoder) Decode() (action watch.EventType, object runtime.Object, err error) {
+	var got struct {
+		Type   watch.EventType
+		Object json.RawMessage
+	}
+	if err := d.decoder.Decode(&got); err != nil {
+		return action, nil, err
+	}
+	switch got.Type {
+	case watch.Added, watch.Modified, watch.Deleted, watch.Error:
+		object, err := d.decode(got.Object)
+		return got.Type, object, err
+	}
+	return action, nil, fmt.Errorf("got invalid watch event type: %v", got.Type)
+}
+
+// Close closes the underlying stream.
+func (d *ObjectEventDecoder) Close() {
+	d.stream.Close()
+}
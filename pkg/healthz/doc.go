@@ -1,4 +1,8 @@
 // Package healthz implements basic http server checking.
 // Usage:
 //   import _ "healthz" registers a handler on the path '/healthz', that serves 200s
+//
+// Additional named checks may be registered with AddHealthzCheck or
+// AddHealthzFunc. /healthz fails if any of them fails, /healthz/<name> runs a
+// single check, and /healthz?verbose lists the result of every check.
 package healthz
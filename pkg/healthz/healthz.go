@@ -1,24 +1,184 @@
 package healthz
 
 import (
+	"bytes"
+	"fmt"
 	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 )
 
 type mux interface {
 	HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request))
 }
 
+// HealthzChecker is a named healthz check.
+type HealthzChecker interface {
+	// Name is used in the url /healthz/<name> and in verbose output.
+	Name() string
+	// Check returns nil if the component is healthy, or an error describing
+	// why it isn't.
+	Check(req *http.Request) error
+}
+
+// protects checks
+var checksLock = sync.Mutex{}
+var checks = map[string]HealthzChecker{}
+
+// draining is set to 1 once the process has started shutting down. Access only
+// using functions from atomic.
+var draining int32
+
 func init() {
-	http.HandleFunc("/healthz", handleHealthz)
+	AddHealthzCheck(drainingCheck{})
+	http.HandleFunc("/healthz", handleRootHealthz)
+	http.HandleFunc("/healthz/", handleHealthz)
+}
+
+// AddHealthzCheck registers checks, so that they are run by /healthz and can be
+// run individually at /healthz/<name>. Panics if a name is already registered.
+func AddHealthzCheck(newChecks ...HealthzChecker) {
+	checksLock.Lock()
+	defer checksLock.Unlock()
+	for _, check := range newChecks {
+		if _, found := checks[check.Name()]; found {
+			panic(fmt.Sprintf("healthz check already defined for name %s", check.Name()))
+		}
+		checks[check.Name()] = check
+	}
+}
+
+// AddHealthzFunc is a shorthand for AddHealthzCheck(NamedCheck(name, check)).
+func AddHealthzFunc(name string, check func(req *http.Request) error) {
+	AddHealthzCheck(NamedCheck(name, check))
+}
+
+// NamedCheck returns a HealthzChecker for the given name and function.
+func NamedCheck(name string, check func(req *http.Request) error) HealthzChecker {
+	return &healthzCheck{name, check}
+}
+
+// healthzCheck implements HealthzChecker on an arbitrary name and check function.
+type healthzCheck struct {
+	name  string
+	check func(req *http.Request) error
+}
+
+func (c *healthzCheck) Name() string {
+	return c.name
+}
+
+func (c *healthzCheck) Check(req *http.Request) error {
+	return c.check(req)
+}
+
+// SetDraining marks the process as shutting down. While draining, /healthz fails
+// so that load balancers stop sending new traffic before connections are closed.
+func SetDraining(d bool) {
+	var v int32
+	if d {
+		v = 1
+	}
+	atomic.StoreInt32(&draining, v)
+}
+
+// drainingCheck fails once SetDraining(true) has been called.
+type drainingCheck struct{}
+
+func (drainingCheck) Name() string {
+	return "draining"
+}
+
+func (drainingCheck) Check(req *http.Request) error {
+	if atomic.LoadInt32(&draining) != 0 {
+		return fmt.Errorf("the server is shutting down")
+	}
+	return nil
+}
+
+// failureStatus returns the HTTP status reporting the failure of check: 503 while
+// draining, so that load balancers take the server out of rotation, and 500
+// otherwise.
+func failureStatus(check HealthzChecker) int {
+	if _, ok := check.(drainingCheck); ok {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// sortedChecks returns a snapshot of the registered checks, ordered by name.
+func sortedChecks() []HealthzChecker {
+	checksLock.Lock()
+	defer checksLock.Unlock()
+	names := make([]string, 0, len(checks))
+	for name := range checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	result := make([]HealthzChecker, 0, len(names))
+	for _, name := range names {
+		result = append(result, checks[name])
+	}
+	return result
+}
+
+// handleRootHealthz runs every registered check. With the "verbose" query
+// parameter, the result of each check is listed.
+func handleRootHealthz(w http.ResponseWriter, r *http.Request) {
+	failed := 0
+	verboseOut := &bytes.Buffer{}
+	for _, check := range sortedChecks() {
+		if err := check.Check(r); err != nil {
+			fmt.Fprintf(verboseOut, "[-]%s failed: %v\n", check.Name(), err)
+			if status := failureStatus(check); status > failed {
+				failed = status
+			}
+		} else {
+			fmt.Fprintf(verboseOut, "[+]%s ok\n", check.Name())
+		}
+	}
+	_, verbose := r.URL.Query()["verbose"]
+	if failed != 0 {
+		w.WriteHeader(failed)
+		fmt.Fprintf(w, "%vhealthz check failed\n", verboseOut.String())
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if verbose {
+		fmt.Fprintf(w, "%vhealthz check passed\n", verboseOut.String())
+		return
+	}
+	w.Write([]byte("ok"))
 }
 
+// handleHealthz runs the single check named by the path /healthz/<name>.
 func handleHealthz(w http.ResponseWriter, r *http.Request) {
-	// TODO Support user supplied health functions too.
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/healthz/"), "/")
+	if name == "" {
+		handleRootHealthz(w, r)
+		return
+	}
+	checksLock.Lock()
+	check, ok := checks[name]
+	checksLock.Unlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if err := check.Check(r); err != nil {
+		w.WriteHeader(failureStatus(check))
+		fmt.Fprintf(w, "%s failed: %v\n", name, err)
+		return
+	}
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("ok"))
 }
 
-// InstallHandler registers a handler for health checking on the path "/healthz" to mux.
+// InstallHandler registers the handlers for health checking on the paths
+// "/healthz" and "/healthz/<name>" to mux.
 func InstallHandler(mux mux) {
-	mux.HandleFunc("/healthz", handleHealthz)
+	mux.HandleFunc("/healthz", handleRootHealthz)
+	mux.HandleFunc("/healthz/", handleHealthz)
 }
@@ -0,0 +1,81 @@
+package healthz
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestInstallHandler(t *testing.T) {
+	mux := http.NewServeMux()
+	InstallHandler(mux)
+
+	table := []struct {
+		path   string
+		code   int
+		substr string
+	}{
+		{"/healthz", http.StatusOK, "ok"},
+		{"/healthz/draining", http.StatusOK, "ok"},
+		{"/healthz?verbose", http.StatusOK, "[+]draining ok"},
+		{"/healthz/missing", http.StatusNotFound, ""},
+	}
+	for _, item := range table {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", item.path, nil))
+		if w.Code != item.code {
+			t.Errorf("%s: expected %v, got %v", item.path, item.code, w.Code)
+		}
+		if !strings.Contains(w.Body.String(), item.substr) {
+			t.Errorf("%s: expected %q in %q", item.path, item.substr, w.Body.String())
+		}
+	}
+}
+
+func TestFailingCheck(t *testing.T) {
+	mux := http.NewServeMux()
+	InstallHandler(mux)
+	AddHealthzFunc("broken", func(req *http.Request) error { return errors.New("boom") })
+	defer func() {
+		checksLock.Lock()
+		defer checksLock.Unlock()
+		delete(checks, "broken")
+	}()
+
+	for _, path := range []string{"/healthz", "/healthz/broken"} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != http.StatusInternalServerError {
+			t.Errorf("%s: expected %v, got %v", path, http.StatusInternalServerError, w.Code)
+		}
+		if !strings.Contains(w.Body.String(), "boom") {
+			t.Errorf("%s: expected error in %q", path, w.Body.String())
+		}
+	}
+}
+
+func TestDraining(t *testing.T) {
+	mux := http.NewServeMux()
+	InstallHandler(mux)
+	SetDraining(true)
+	defer SetDraining(false)
+
+	for _, path := range []string{"/healthz", "/healthz/draining"} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("%s: expected %v, got %v", path, http.StatusServiceUnavailable, w.Code)
+		}
+	}
+}
+
+func TestAddHealthzCheckTwicePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected a panic")
+		}
+	}()
+	AddHealthzCheck(drainingCheck{})
+}
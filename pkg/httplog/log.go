@@ -83,16 +83,13 @@ func NewLogged(req *http.Request, w *http.ResponseWriter) *respLogger {
 
 // LogOf returns the logger hiding in w. If there is not an existing logger
 // then a passthroughLogger will be created which will log to stdout immediately
-// when Addf is called.
+// when Addf is called. Writers which wrap a logged writer may implement Addf by
+// forwarding to it, to be found as well.
 func LogOf(req *http.Request, w http.ResponseWriter) logger {
-	if _, exists := w.(*respLogger); !exists {
-		pl := &passthroughLogger{}
-		return pl
-	}
-	if rl, ok := w.(*respLogger); ok {
+	if rl, ok := w.(logger); ok {
 		return rl
 	}
-	panic("Unable to find or create the logger!")
+	return &passthroughLogger{}
 }
 
 // Unlogged returns the original ResponseWriter, or w if it is not our inserted logger.
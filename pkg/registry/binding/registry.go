@@ -9,4 +9,7 @@ type Registry interface {
 	// ApplyBinding should apply the binding. Thas is, it should actually
 	// assign or place pod binding.PodID on machine binding.Host.
 	ApplyBinding(binding *api.Binding) error
+	// GetPod should return the pod with the given ID, or an error for which
+	// errors.IsNotFound is true if there is none.
+	GetPod(podID string) (*api.Pod, error)
 }
@@ -44,12 +44,30 @@ func (*REST) New() runtime.Object {
 	return &api.Binding{}
 }
 
+// validateBinding checks that binding names a pod and a host.
+func validateBinding(binding *api.Binding) error {
+	allErrs := errors.ErrorList{}
+	if binding.PodID == "" {
+		allErrs = append(allErrs, errors.NewFieldRequired("podID", binding.PodID))
+	}
+	if binding.Host == "" {
+		allErrs = append(allErrs, errors.NewFieldRequired("host", binding.Host))
+	}
+	if len(allErrs) > 0 {
+		return errors.NewInvalid("binding", binding.ID, allErrs)
+	}
+	return nil
+}
+
 // Create attempts to make the assignment indicated by the binding it receives.
 func (b *REST) Create(obj runtime.Object) (<-chan runtime.Object, error) {
 	binding, ok := obj.(*api.Binding)
 	if !ok {
 		return nil, fmt.Errorf("incorrect type: %#v", obj)
 	}
+	if err := validateBinding(binding); err != nil {
+		return nil, err
+	}
 	return apiserver.MakeAsync(func() (runtime.Object, error) {
 		if err := b.registry.ApplyBinding(binding); err != nil {
 			return nil, err
@@ -62,3 +80,28 @@ func (b *REST) Create(obj runtime.Object) (<-chan runtime.Object, error) {
 func (*REST) Update(obj runtime.Object) (<-chan runtime.Object, error) {
 	return nil, fmt.Errorf("Bindings may not be changed")
 }
+
+// DryRunCreate checks a binding, and that its pod exists, without applying it.
+func (b *REST) DryRunCreate(obj runtime.Object) (runtime.Object, error) {
+	binding, ok := obj.(*api.Binding)
+	if !ok {
+		return nil, fmt.Errorf("incorrect type: %#v", obj)
+	}
+	if err := validateBinding(binding); err != nil {
+		return nil, err
+	}
+	if _, err := b.registry.GetPod(binding.PodID); err != nil {
+		return nil, err
+	}
+	return binding, nil
+}
+
+// DryRunUpdate returns an error-- this object may not be updated.
+func (*REST) DryRunUpdate(obj runtime.Object) (runtime.Object, error) {
+	return nil, fmt.Errorf("Bindings may not be changed")
+}
+
+// DryRunDelete returns an error because bindings are write-only objects.
+func (*REST) DryRunDelete(id string) (runtime.Object, error) {
+	return nil, errors.NewNotFound("binding", id)
+}
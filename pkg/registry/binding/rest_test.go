@@ -0,0 +1,63 @@
+package binding
+
+import (
+	"testing"
+
+	"github.com/ryutah/kubernetes-transcribe/pkg/api"
+	"github.com/ryutah/kubernetes-transcribe/pkg/api/errors"
+)
+
+// fakeRegistry holds one pod, and records the bindings applied.
+type fakeRegistry struct {
+	pod      *api.Pod
+	bindings []*api.Binding
+}
+
+func (r *fakeRegistry) ApplyBinding(binding *api.Binding) error {
+	r.bindings = append(r.bindings, binding)
+	return nil
+}
+
+func (r *fakeRegistry) GetPod(podID string) (*api.Pod, error) {
+	if r.pod == nil || r.pod.ID != podID {
+		return nil, errors.NewNotFound("pod", podID)
+	}
+	return r.pod, nil
+}
+
+func TestDryRunCreate(t *testing.T) {
+	table := map[string]struct {
+		binding *api.Binding
+		check   func(error) bool
+	}{
+		"valid":       {&api.Binding{PodID: "foo", Host: "machine"}, func(err error) bool { return err == nil }},
+		"no pod ID":   {&api.Binding{Host: "machine"}, errors.IsInvalid},
+		"no host":     {&api.Binding{PodID: "foo"}, errors.IsInvalid},
+		"missing pod": {&api.Binding{PodID: "bar", Host: "machine"}, errors.IsNotFound},
+	}
+	for name, item := range table {
+		registry := &fakeRegistry{pod: &api.Pod{JSONBase: api.JSONBase{ID: "foo"}}}
+		storage := NewREST(registry)
+		obj, err := storage.DryRunCreate(item.binding)
+		if !item.check(err) {
+			t.Errorf("%s: unexpected error: %v", name, err)
+		}
+		if err == nil && obj != item.binding {
+			t.Errorf("%s: expected %#v, got %#v", name, item.binding, obj)
+		}
+		if len(registry.bindings) != 0 {
+			t.Errorf("%s: expected nothing to be applied, got %#v", name, registry.bindings)
+		}
+	}
+}
+
+func TestCreateValidates(t *testing.T) {
+	registry := &fakeRegistry{}
+	storage := NewREST(registry)
+	if _, err := storage.Create(&api.Binding{PodID: "foo"}); !errors.IsInvalid(err) {
+		t.Errorf("expected an invalid error, got %v", err)
+	}
+	if len(registry.bindings) != 0 {
+		t.Errorf("expected nothing to be applied, got %#v", registry.bindings)
+	}
+}
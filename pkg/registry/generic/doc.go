@@ -0,0 +1,4 @@
+// Package generic contains RESTStorage which isn't specific to any kind of object.
+// Etcd stores objects of one kind in etcd, and can be used directly for kinds which
+// need no logic beyond storing them.
+package generic
@@ -0,0 +1,198 @@
+package generic
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/ryutah/kubernetes-transcribe/pkg/api"
+	"github.com/ryutah/kubernetes-transcribe/pkg/api/errors"
+	"github.com/ryutah/kubernetes-transcribe/pkg/apiserver"
+	"github.com/ryutah/kubernetes-transcribe/pkg/labels"
+	"github.com/ryutah/kubernetes-transcribe/pkg/runtime"
+	"github.com/ryutah/kubernetes-transcribe/pkg/tools"
+)
+
+// Etcd implements the RESTStorage interface for objects of one kind, which are kept
+// in etcd under Prefix, keyed by their ID.
+type Etcd struct {
+	Helper tools.EtcdHelper
+	// Prefix is the etcd directory of the objects, e.g. "/registry/minions".
+	Prefix string
+	// Kind is the kind of the objects, as used in errors, e.g. "minion".
+	Kind string
+	// NewFunc returns a new object of the kind.
+	NewFunc func() runtime.Object
+	// NewListFunc returns a new list of the kind, which has an Items slice.
+	NewListFunc func() runtime.Object
+	// Labels returns the labels of an object, to select on in List. If it is nil,
+	// List accepts only an empty label selector.
+	Labels func(runtime.Object) labels.Set
+	// GracePeriodSeconds is how long DeleteWithOptions keeps an object when the
+	// options don't say. Zero deletes it at once.
+	GracePeriodSeconds int64
+}
+
+func (e *Etcd) key(id string) string {
+	return e.Prefix + "/" + id
+}
+
+// New returns a new object fit for having data unmarshalled into it.
+func (e *Etcd) New() runtime.Object {
+	return e.NewFunc()
+}
+
+// List returns the objects whose labels match label. Field selectors aren't supported.
+func (e *Etcd) List(label, field labels.Selector) (runtime.Object, error) {
+	if !field.Empty() {
+		return nil, fmt.Errorf("field selectors are not supported for %s", e.Kind)
+	}
+	if e.Labels == nil && !label.Empty() {
+		return nil, fmt.Errorf("label selectors are not supported for %s", e.Kind)
+	}
+	list := e.NewListFunc()
+	items := reflect.ValueOf(list).Elem().FieldByName("Items")
+	if !items.IsValid() || items.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("%T has no Items slice", list)
+	}
+	var version uint64
+	if err := e.Helper.ExtractList(e.Prefix, items.Addr().Interface(), &version); err != nil {
+		return nil, err
+	}
+	if !label.Empty() {
+		matched := reflect.MakeSlice(items.Type(), 0, items.Len())
+		for i := 0; i < items.Len(); i++ {
+			if label.Matches(e.Labels(items.Index(i).Addr().Interface().(runtime.Object))) {
+				matched = reflect.Append(matched, items.Index(i))
+			}
+		}
+		items.Set(matched)
+	}
+	jsonBase, err := runtime.FindJSONBase(list)
+	if err != nil {
+		return nil, err
+	}
+	jsonBase.SetResourceVersion(version)
+	return list, nil
+}
+
+// Get returns the object with the given id.
+func (e *Etcd) Get(id string) (runtime.Object, error) {
+	obj := e.NewFunc()
+	if err := e.Helper.ExtractObj(e.key(id), obj, false); err != nil {
+		return nil, e.interpretError(err, id)
+	}
+	return obj, nil
+}
+
+// Create stores a new object, which must have an ID.
+func (e *Etcd) Create(obj runtime.Object) (<-chan runtime.Object, error) {
+	jsonBase, err := runtime.FindJSONBase(obj)
+	if err != nil {
+		return nil, err
+	}
+	id := jsonBase.ID()
+	if id == "" {
+		return nil, errors.NewInvalid(e.Kind, id, errors.ErrorList{errors.NewFieldRequired("id", id)})
+	}
+	return apiserver.MakeAsync(func() (runtime.Object, error) {
+		if err := e.Helper.CreateObj(e.key(id), obj); err != nil {
+			return nil, e.interpretError(err, id)
+		}
+		return e.Get(id)
+	}), nil
+}
+
+// Update replaces a stored object. If obj has a resourceVersion, the update fails
+// unless the stored object is at that version.
+func (e *Etcd) Update(obj runtime.Object) (<-chan runtime.Object, error) {
+	jsonBase, err := runtime.FindJSONBase(obj)
+	if err != nil {
+		return nil, err
+	}
+	id := jsonBase.ID()
+	if id == "" {
+		return nil, errors.NewInvalid(e.Kind, id, errors.ErrorList{errors.NewFieldRequired("id", id)})
+	}
+	return apiserver.MakeAsync(func() (runtime.Object, error) {
+		var err error
+		if jsonBase.ResourceVersion() == 0 {
+			// Without a version to compare with, the update is unconditional.
+			err = e.Helper.AtomicUpdate(e.key(id), e.NewFunc(), func(stored runtime.Object) (runtime.Object, error) {
+				if storedBase, err := runtime.FindJSONBase(stored); err != nil || storedBase.ResourceVersion() == 0 {
+					// AtomicUpdate passes an empty object if there is none.
+					return nil, errors.NewNotFound(e.Kind, id)
+				}
+				return obj, nil
+			})
+		} else {
+			err = e.Helper.SetObj(e.key(id), obj)
+		}
+		if err != nil {
+			return nil, e.interpretError(err, id)
+		}
+		return e.Get(id)
+	}), nil
+}
+
+// Delete removes the object with the given id at once.
+func (e *Etcd) Delete(id string) (<-chan runtime.Object, error) {
+	return apiserver.MakeAsync(func() (runtime.Object, error) {
+		if err := e.Helper.Delete(e.key(id), false); err != nil {
+			return nil, e.interpretError(err, id)
+		}
+		return &api.Status{Status: api.StatusSuccess}, nil
+	}), nil
+}
+
+// DeleteIfVersion removes the object with the given id at once, if it was last
+// modified at resourceVersion. Otherwise the etcd error is passed on, which the
+// apiserver reports as a conflict.
+func (e *Etcd) DeleteIfVersion(id string, resourceVersion uint64) (<-chan runtime.Object, error) {
+	return apiserver.MakeAsync(func() (runtime.Object, error) {
+		if err := e.Helper.CompareAndDelete(e.key(id), resourceVersion); err != nil {
+			return nil, e.interpretError(err, id)
+		}
+		return &api.Status{Status: api.StatusSuccess}, nil
+	}), nil
+}
+
+// DeleteWithOptions deletes the object with the given id once its grace period is
+// over, and returns it with its DeletionTimestamp set. Until then it can still be
+// read and updated.
+func (e *Etcd) DeleteWithOptions(id string, options *api.DeleteOptions) (<-chan runtime.Object, error) {
+	gracePeriod := e.GracePeriodSeconds
+	if options.GracePeriodSeconds != nil {
+		gracePeriod = *options.GracePeriodSeconds
+	}
+	if gracePeriod < 0 {
+		return nil, errors.NewInvalid(e.Kind, id, errors.ErrorList{errors.NewFieldInvalid("gracePeriodSeconds", gracePeriod)})
+	}
+	var resourceVersion uint64
+	if options.Preconditions != nil {
+		resourceVersion = options.Preconditions.ResourceVersion
+	}
+	return apiserver.MakeAsync(func() (runtime.Object, error) {
+		err := e.Helper.DeleteObjGracefully(e.key(id), e.NewFunc(), resourceVersion, uint64(gracePeriod), func(obj runtime.Object) (runtime.Object, error) {
+			return obj, nil
+		})
+		if err != nil {
+			return nil, e.interpretError(err, id)
+		}
+		if gracePeriod == 0 {
+			return &api.Status{Status: api.StatusSuccess}, nil
+		}
+		return e.Get(id)
+	}), nil
+}
+
+// interpretError turns the etcd errors which a client can act on into API errors.
+// A failed compare is left alone, as the apiserver reports it as a conflict.
+func (e *Etcd) interpretError(err error, id string) error {
+	switch {
+	case tools.IsEtcdNotFound(err):
+		return errors.NewNotFound(e.Kind, id)
+	case tools.IsEtcdNodeExist(err):
+		return errors.NewAlreadyExists(e.Kind, id)
+	}
+	return err
+}
@@ -0,0 +1,262 @@
+package generic
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/coreos/go-etcd/etcd"
+	"github.com/ryutah/kubernetes-transcribe/pkg/api"
+	"github.com/ryutah/kubernetes-transcribe/pkg/api/errors"
+	"github.com/ryutah/kubernetes-transcribe/pkg/apiserver"
+	"github.com/ryutah/kubernetes-transcribe/pkg/labels"
+	"github.com/ryutah/kubernetes-transcribe/pkg/runtime"
+	"github.com/ryutah/kubernetes-transcribe/pkg/tools"
+)
+
+// The versioned API types are not registered yet, so tests use the internal version.
+var codec = runtime.CodecFor(api.Scheme, "")
+
+func newTestEtcd(t *testing.T) (*tools.FakeEtcdClient, *Etcd) {
+	fakeClient := tools.NewFakeEtcdClient(t)
+	fakeClient.TestIndex = true
+	return fakeClient, &Etcd{
+		Helper:      tools.EtcdHelper{Client: fakeClient, Codec: codec, ResourceVersioner: runtime.NewJSONBaseResourceVersioner()},
+		Prefix:      "/registry/pods",
+		Kind:        "pod",
+		NewFunc:     func() runtime.Object { return &api.Pod{} },
+		NewListFunc: func() runtime.Object { return &api.PodList{} },
+		// The internal Pod.Labels don't survive decoding, so select on the host.
+		Labels: func(obj runtime.Object) labels.Set {
+			return labels.Set{"host": obj.(*api.Pod).DesiredState.Host}
+		},
+	}
+}
+
+func setPod(t *testing.T, fakeClient *tools.FakeEtcdClient, pod *api.Pod, index uint64) *etcd.Node {
+	data, err := codec.Encode(pod)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	node := &etcd.Node{Key: "/registry/pods/" + pod.ID, Value: string(data), ModifiedIndex: index, CreatedIndex: index}
+	fakeClient.Data[node.Key] = tools.EtcdResponseWithError{R: &etcd.Response{Node: node}}
+	return node
+}
+
+func statusOf(obj runtime.Object) *api.Status {
+	if status, ok := obj.(*api.Status); ok && status.Status != api.StatusSuccess {
+		return status
+	}
+	return nil
+}
+
+func TestEtcdCreateAndGet(t *testing.T) {
+	fakeClient, storage := newTestEtcd(t)
+	ch, err := storage.Create(&api.Pod{JSONBase: api.JSONBase{ID: "foo"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pod, ok := (<-ch).(*api.Pod)
+	if !ok {
+		t.Fatalf("expected a pod")
+	}
+	if e, a := uint64(1), pod.ResourceVersion; e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+
+	ch, err = storage.Create(&api.Pod{JSONBase: api.JSONBase{ID: "foo"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status := statusOf(<-ch); status == nil || status.Reason != api.StatusReasonAlreadyExists {
+		t.Errorf("expected an already exists status, got %#v", status)
+	}
+
+	if _, err := storage.Create(&api.Pod{}); !errors.IsInvalid(err) {
+		t.Errorf("expected an invalid error, got %v", err)
+	}
+
+	fakeClient.ExpectNotFoundGet("/registry/pods/bar")
+	if _, err := storage.Get("bar"); !errors.IsNotFound(err) {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+}
+
+func TestEtcdList(t *testing.T) {
+	fakeClient, storage := newTestEtcd(t)
+	fakeClient.Data["/registry/pods"] = tools.EtcdResponseWithError{
+		R: &etcd.Response{
+			EtcdIndex: 3,
+			Node: &etcd.Node{
+				Nodes: []*etcd.Node{
+					setPod(t, fakeClient, &api.Pod{JSONBase: api.JSONBase{ID: "foo"}, DesiredState: api.PodState{Host: "machine1"}}, 1),
+					setPod(t, fakeClient, &api.Pod{JSONBase: api.JSONBase{ID: "bar"}, DesiredState: api.PodState{Host: "machine2"}}, 2),
+				},
+			},
+		},
+	}
+
+	table := map[string]struct {
+		label    labels.Selector
+		expected []string
+	}{
+		"everything": {labels.Everything(), []string{"foo", "bar"}},
+		"selected":   {labels.SelectorFromSet(labels.Set{"host": "machine2"}), []string{"bar"}},
+		"none":       {labels.SelectorFromSet(labels.Set{"host": "machine3"}), []string{}},
+	}
+	for name, item := range table {
+		obj, err := storage.List(item.label, labels.Everything())
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		list := obj.(*api.PodList)
+		if e, a := uint64(3), list.ResourceVersion; e != a {
+			t.Errorf("%s: expected %v, got %v", name, e, a)
+		}
+		ids := []string{}
+		for _, pod := range list.Items {
+			ids = append(ids, pod.ID)
+		}
+		if e, a := item.expected, ids; !reflect.DeepEqual(e, a) {
+			t.Errorf("%s: expected %v, got %v", name, e, a)
+		}
+	}
+
+	if _, err := storage.List(labels.Everything(), labels.SelectorFromSet(labels.Set{"id": "foo"})); err == nil {
+		t.Errorf("expected field selectors to be refused")
+	}
+}
+
+func TestEtcdUpdate(t *testing.T) {
+	fakeClient, storage := newTestEtcd(t)
+	fakeClient.ChangeIndex = 3
+	setPod(t, fakeClient, &api.Pod{JSONBase: api.JSONBase{ID: "foo"}}, 2)
+
+	ch, err := storage.Update(&api.Pod{JSONBase: api.JSONBase{ID: "foo", ResourceVersion: 1}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status := statusOf(<-ch); status == nil || status.Code != 409 {
+		t.Errorf("expected a conflict, got %#v", status)
+	}
+
+	ch, err = storage.Update(&api.Pod{JSONBase: api.JSONBase{ID: "foo", ResourceVersion: 2}, DesiredState: api.PodState{Host: "machine1"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pod, ok := (<-ch).(*api.Pod)
+	if !ok {
+		t.Fatalf("expected a pod")
+	}
+	if e, a := "machine1", pod.DesiredState.Host; e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+
+	fakeClient.ExpectNotFoundGet("/registry/pods/bar")
+	ch, err = storage.Update(&api.Pod{JSONBase: api.JSONBase{ID: "bar"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status := statusOf(<-ch); status == nil || status.Reason != api.StatusReasonNotFound {
+		t.Errorf("expected a not found status, got %#v", status)
+	}
+}
+
+func TestEtcdDeleteWithOptions(t *testing.T) {
+	fakeClient, storage := newTestEtcd(t)
+	storage.GracePeriodSeconds = 30
+	fakeClient.ChangeIndex = 2
+	setPod(t, fakeClient, &api.Pod{JSONBase: api.JSONBase{ID: "foo"}}, 1)
+
+	ch, err := storage.DeleteWithOptions("foo", &api.DeleteOptions{Preconditions: &api.Preconditions{ResourceVersion: 5}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status := statusOf(<-ch); status == nil || status.Code != 409 {
+		t.Errorf("expected a conflict, got %#v", status)
+	}
+
+	before := time.Now()
+	ch, err = storage.DeleteWithOptions("foo", &api.DeleteOptions{Preconditions: &api.Preconditions{ResourceVersion: 1}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pod, ok := (<-ch).(*api.Pod)
+	if !ok {
+		t.Fatalf("expected the pod being deleted")
+	}
+	if pod.DeletionTimestamp == nil {
+		t.Fatalf("expected a deletion timestamp")
+	}
+	if d := pod.DeletionTimestamp.Sub(before); d < 29*time.Second || d > 31*time.Second {
+		t.Errorf("expected the default grace period, got %v", d)
+	}
+	if e, a := int64(30), fakeClient.Data["/registry/pods/foo"].R.Node.TTL; e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+
+	// An update in the grace period keeps the pod expiring.
+	ch, err = storage.Update(&api.Pod{JSONBase: api.JSONBase{ID: "foo", ResourceVersion: pod.ResourceVersion}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status := statusOf(<-ch); status != nil {
+		t.Fatalf("unexpected status: %#v", status)
+	}
+	if ttl := fakeClient.Data["/registry/pods/foo"].R.Node.TTL; ttl < 29 || ttl > 30 {
+		t.Errorf("expected the rest of the grace period as TTL, got %v", ttl)
+	}
+
+	gracePeriod := int64(0)
+	ch, err = storage.DeleteWithOptions("foo", &api.DeleteOptions{GracePeriodSeconds: &gracePeriod})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status := statusOf(<-ch); status != nil {
+		t.Fatalf("unexpected status: %#v", status)
+	}
+	if e, a := []string{"/registry/pods/foo"}, fakeClient.DeleteKeys; !reflect.DeepEqual(e, a) {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+
+	gracePeriod = -1
+	if _, err := storage.DeleteWithOptions("foo", &api.DeleteOptions{GracePeriodSeconds: &gracePeriod}); !errors.IsInvalid(err) {
+		t.Errorf("expected an invalid error, got %v", err)
+	}
+}
+
+func TestEtcdDeleteIfVersion(t *testing.T) {
+	fakeClient, storage := newTestEtcd(t)
+	setPod(t, fakeClient, &api.Pod{JSONBase: api.JSONBase{ID: "foo"}}, 1)
+	var deleter apiserver.ConditionalDeleter = storage
+
+	ch, err := deleter.DeleteIfVersion("foo", 5)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status := statusOf(<-ch); status == nil || status.Code != 409 {
+		t.Errorf("expected a conflict, got %#v", status)
+	}
+	if len(fakeClient.DeleteKeys) != 0 {
+		t.Errorf("unexpected deletion of %v", fakeClient.DeleteKeys)
+	}
+
+	ch, err = deleter.DeleteIfVersion("foo", 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status := statusOf(<-ch); status != nil {
+		t.Fatalf("unexpected status: %#v", status)
+	}
+	if e, a := []string{"/registry/pods/foo"}, fakeClient.DeleteKeys; !reflect.DeepEqual(e, a) {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+
+	ch, err = deleter.DeleteIfVersion("foo", 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status := statusOf(<-ch); status == nil || status.Code != 404 {
+		t.Errorf("expected not found, got %#v", status)
+	}
+}
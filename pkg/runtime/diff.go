@@ -0,0 +1,137 @@
+package runtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// FieldDiff is a difference between two objects in a single field.
+type FieldDiff struct {
+	// Path is the field's path, made of JSON field names, e.g.
+	// "desiredState.manifest.containers[0].image".
+	Path string
+	// A and B are the values of the field in each object; nil if it is missing
+	// from one of them, like an element past the end of a list.
+	A, B interface{}
+}
+
+var jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+// FieldDiffs returns the fields in which a and b differ, which must be objects of
+// the same type. Unlike ObjectDiff, which is meant for reading test failures, it
+// reports every changed field on its own. Nil and empty lists and maps are equal.
+func FieldDiffs(a, b Object) ([]FieldDiff, error) {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if va.Type() != vb.Type() {
+		return nil, fmt.Errorf("can't compare %v with %v", va.Type(), vb.Type())
+	}
+	diffs := []FieldDiff{}
+	fieldDiffs("", va, vb, &diffs)
+	return diffs, nil
+}
+
+func fieldDiffs(path string, a, b reflect.Value, diffs *[]FieldDiff) {
+	switch {
+	case !a.IsValid() && !b.IsValid():
+		return
+	case !a.IsValid() || !b.IsValid():
+		*diffs = append(*diffs, FieldDiff{path, interfaceOf(a), interfaceOf(b)})
+		return
+	}
+
+	t := a.Type()
+	if t.Implements(jsonMarshalerType) || reflect.PtrTo(t).Implements(jsonMarshalerType) {
+		// Types like util.Time are only meaningful as a whole.
+		if !reflect.DeepEqual(a.Interface(), b.Interface()) {
+			*diffs = append(*diffs, FieldDiff{path, a.Interface(), b.Interface()})
+		}
+		return
+	}
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if a.IsNil() && b.IsNil() {
+			return
+		}
+		if a.IsNil() || b.IsNil() || a.Elem().Type() != b.Elem().Type() {
+			*diffs = append(*diffs, FieldDiff{path, interfaceOf(a.Elem()), interfaceOf(b.Elem())})
+			return
+		}
+		fieldDiffs(path, a.Elem(), b.Elem(), diffs)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			name, inline := jsonFieldName(field)
+			if name == "-" {
+				continue
+			}
+			fieldPath := path
+			if !inline {
+				fieldPath = joinPath(path, name)
+			}
+			fieldDiffs(fieldPath, a.Field(i), b.Field(i), diffs)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < a.Len() || i < b.Len(); i++ {
+			var ai, bi reflect.Value
+			if i < a.Len() {
+				ai = a.Index(i)
+			}
+			if i < b.Len() {
+				bi = b.Index(i)
+			}
+			fieldDiffs(fmt.Sprintf("%s[%d]", path, i), ai, bi, diffs)
+		}
+	case reflect.Map:
+		keys := map[string]reflect.Value{}
+		for _, key := range append(a.MapKeys(), b.MapKeys()...) {
+			keys[fmt.Sprint(key.Interface())] = key
+		}
+		names := make([]string, 0, len(keys))
+		for name := range keys {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fieldDiffs(fmt.Sprintf("%s[%s]", path, name), a.MapIndex(keys[name]), b.MapIndex(keys[name]), diffs)
+		}
+	default:
+		if !reflect.DeepEqual(a.Interface(), b.Interface()) {
+			*diffs = append(*diffs, FieldDiff{path, a.Interface(), b.Interface()})
+		}
+	}
+}
+
+// jsonFieldName returns the name a struct field is encoded with in JSON, and
+// whether it is inlined into its parent.
+func jsonFieldName(field reflect.StructField) (name string, inline bool) {
+	tag := strings.Split(field.Tag.Get("json"), ",")
+	for _, option := range tag[1:] {
+		if option == "inline" {
+			return "", true
+		}
+	}
+	if tag[0] != "" {
+		return tag[0], false
+	}
+	return field.Name, field.Anonymous
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func interfaceOf(v reflect.Value) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+	return v.Interface()
+}
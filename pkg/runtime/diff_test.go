@@ -0,0 +1,52 @@
+package runtime_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ryutah/kubernetes-transcribe/pkg/runtime"
+	"github.com/ryutah/kubernetes-transcribe/pkg/util"
+)
+
+func TestFieldDiffs(t *testing.T) {
+	a := &InternalComplex{
+		JSONBase: JSONBase{Kind: "Complex"},
+		Name:     "foo",
+		Labels:   map[string]string{"a": "b", "c": "d"},
+		Items:    []ComplexItem{{Value: "x"}},
+		Created:  util.Time{Time: time.Date(2014, 7, 1, 12, 0, 0, 0, time.UTC)},
+	}
+	b := &InternalComplex{
+		JSONBase: JSONBase{Kind: "Complex"},
+		Name:     "bar",
+		Labels:   map[string]string{"a": "b", "e": "f"},
+		Items:    []ComplexItem{{Value: "y"}, {}},
+		Matrix:   [][]int{},
+		Parent:   &ComplexItem{},
+		Created:  util.Time{Time: time.Date(2014, 7, 2, 12, 0, 0, 0, time.UTC)},
+	}
+	diffs, err := runtime.FieldDiffs(a, b)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []runtime.FieldDiff{
+		{"name", "foo", "bar"},
+		{"labels[c]", "d", nil},
+		{"labels[e]", nil, "f"},
+		{"items[0].value", "x", "y"},
+		{"items[1]", nil, ComplexItem{}},
+		{"parent", nil, ComplexItem{}},
+		{"created", a.Created, b.Created},
+	}
+	if !reflect.DeepEqual(expected, diffs) {
+		t.Errorf("Expected:\n %#v,\n Got:\n %#v", expected, diffs)
+	}
+
+	if diffs, err := runtime.FieldDiffs(a, a); err != nil || len(diffs) != 0 {
+		t.Errorf("Expected no differences, got %#v, %v", diffs, err)
+	}
+	if _, err := runtime.FieldDiffs(a, &InternalSimple{}); err == nil {
+		t.Errorf("Expected an error comparing different types")
+	}
+}
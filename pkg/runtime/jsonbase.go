@@ -3,6 +3,8 @@ package runtime
 import (
 	"fmt"
 	"reflect"
+
+	"github.com/ryutah/kubernetes-transcribe/pkg/util"
 )
 
 // NewJSONBaseResourceVersioner returns a resourceVersioner that can set or
@@ -41,13 +43,16 @@ type JSONBaseInterface interface {
 	SetKind(kind string)
 	ResourceVersion() uint64
 	SetResourceVersion(version uint64)
+	DeletionTimestamp() *util.Time
+	SetDeletionTimestamp(timestamp *util.Time)
 }
 
 type genericJSONBase struct {
-	id              *string
-	apiVersion      *string
-	kind            *string
-	resourceVersion *uint64
+	id                *string
+	apiVersion        *string
+	kind              *string
+	resourceVersion   *uint64
+	deletionTimestamp **util.Time
 }
 
 func (g genericJSONBase) ID() string {
@@ -82,6 +87,14 @@ func (g genericJSONBase) SetResourceVersion(version uint64) {
 	*g.resourceVersion = version
 }
 
+func (g genericJSONBase) DeletionTimestamp() *util.Time {
+	return *g.deletionTimestamp
+}
+
+func (g genericJSONBase) SetDeletionTimestamp(timestamp *util.Time) {
+	*g.deletionTimestamp = timestamp
+}
+
 // fieldPtr puts the address of fieldName, which must be a member of v,
 // into dest, which must be an address of a variable to which this field's
 // address can be assigned.
@@ -124,5 +137,8 @@ func newGenericJSONBase(v reflect.Value) (genericJSONBase, error) {
 	if err := fieldPtr(v, "ResourceVersion", &g.resourceVersion); err != nil {
 		return g, err
 	}
+	if err := fieldPtr(v, "DeletionTimestamp", &g.deletionTimestamp); err != nil {
+		return g, err
+	}
 	return g, nil
 }
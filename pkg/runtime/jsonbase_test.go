@@ -9,12 +9,13 @@ import (
 
 func TestGenericJSONBase(t *testing.T) {
 	type JSONBase struct {
-		Kind              string    `json:"kind,omitempty" yaml:"kind,omitempty"`
-		ID                string    `json:"id,omitempty" yaml:"id,omitempty"`
-		CreationTimestamp util.Time `json:"creationTimestamp,omitempty" yaml:"creationTimestamp,omitempty"`
-		SelfLink          string    `json:"selfLink,omitempty" yaml:"selfLink,omitempty"`
-		ResourceVersion   uint64    `json:"resourceVersion,omitempty" yaml:"resourceVersion,omitempty"`
-		APIVersion        string    `json:"apiVersion,omitempty" yaml:"apiVersion,omitempty"`
+		Kind              string     `json:"kind,omitempty" yaml:"kind,omitempty"`
+		ID                string     `json:"id,omitempty" yaml:"id,omitempty"`
+		CreationTimestamp util.Time  `json:"creationTimestamp,omitempty" yaml:"creationTimestamp,omitempty"`
+		SelfLink          string     `json:"selfLink,omitempty" yaml:"selfLink,omitempty"`
+		ResourceVersion   uint64     `json:"resourceVersion,omitempty" yaml:"resourceVersion,omitempty"`
+		APIVersion        string     `json:"apiVersion,omitempty" yaml:"apiVersion,omitempty"`
+		DeletionTimestamp *util.Time `json:"deletionTimestamp,omitempty" yaml:"deletionTimestamp,omitempty"`
 	}
 	j := JSONBase{
 		ID:              "foo",
@@ -40,11 +41,16 @@ func TestGenericJSONBase(t *testing.T) {
 	if e, a := uint64(1), jbi.ResourceVersion(); e != a {
 		t.Errorf("expected %v, got %v", e, a)
 	}
+	if a := jbi.DeletionTimestamp(); a != nil {
+		t.Errorf("expected no deletion timestamp, got %v", a)
+	}
 
 	jbi.SetID("bar")
 	jbi.SetAPIVersion("c")
 	jbi.SetKind("d")
 	jbi.SetResourceVersion(2)
+	deleted := util.Now()
+	jbi.SetDeletionTimestamp(&deleted)
 
 	// Prove that jbi changes the original object.
 	if e, a := "bar", j.ID; e != a {
@@ -59,6 +65,9 @@ func TestGenericJSONBase(t *testing.T) {
 	if e, a := uint64(2), j.ResourceVersion; e != a {
 		t.Errorf("expected %v, got %v", e, a)
 	}
+	if e, a := &deleted, j.DeletionTimestamp; e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
 }
 
 type MyAPIObject struct {
@@ -0,0 +1,177 @@
+package runtime
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+)
+
+// protobufPrefix starts every object written by a protobuf codec, so that it can
+// be told apart from JSON and YAML.
+var protobufPrefix = []byte("k8s\x00")
+
+// protobufEnvelope is the message which wraps every encoded object. The object
+// itself has its Kind and APIVersion cleared, as in memory.
+type protobufEnvelope struct {
+	APIVersion string `protobuf:"1"`
+	Kind       string `protobuf:"2"`
+	Raw        []byte `protobuf:"3"`
+}
+
+// protobufCodec encodes objects of a scheme in the protobuf wire format; see
+// wire.go. Conversions between versions are done by the scheme, just as for JSON.
+type protobufCodec struct {
+	scheme  *Scheme
+	version string
+}
+
+// ProtobufCodecFor returns a Codec which encodes objects in the given version in
+// a compact binary format, instead of JSON. It decodes both, so that data written
+// as JSON stays readable once a store switches to it.
+func ProtobufCodecFor(scheme *Scheme, version string) Codec {
+	return &protobufCodec{scheme, version}
+}
+
+// IsProtobuf returns true if data was encoded by a protobuf codec.
+func IsProtobuf(data []byte) bool {
+	return bytes.HasPrefix(data, protobufPrefix)
+}
+
+// Encode implements Encoder.
+func (c *protobufCodec) Encode(obj Object) ([]byte, error) {
+	objVersion, kind, err := c.scheme.raw.ObjectVersionAndKind(obj)
+	if err != nil {
+		return nil, err
+	}
+	if objVersion != c.version {
+		out, err := c.scheme.New(c.version, kind)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.scheme.Convert(obj, out); err != nil {
+			return nil, err
+		}
+		obj = out
+	}
+	raw, err := marshalProto(obj)
+	if err != nil {
+		return nil, err
+	}
+	data, err := marshalProto(&protobufEnvelope{c.version, kind, raw})
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]byte{}, protobufPrefix...), data...), nil
+}
+
+// Decode implements Decoder. The object is converted to the internal version.
+func (c *protobufCodec) Decode(data []byte) (Object, error) {
+	if !IsProtobuf(data) {
+		return c.scheme.Decode(data)
+	}
+	envelope, err := c.decodeEnvelope(data)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := c.scheme.New(envelope.APIVersion, envelope.Kind)
+	if err != nil {
+		return nil, err
+	}
+	if err := unmarshalProto(envelope.Raw, obj); err != nil {
+		return nil, err
+	}
+	if envelope.APIVersion == c.scheme.raw.InternalVersion {
+		return obj, nil
+	}
+	out, err := c.scheme.New(c.scheme.raw.InternalVersion, envelope.Kind)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.scheme.Convert(obj, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DecodeInto implements Decoder. If obj is of another version than data, the
+// decoded object is converted to it.
+func (c *protobufCodec) DecodeInto(data []byte, obj Object) error {
+	if !IsProtobuf(data) {
+		return c.scheme.DecodeInto(data, obj)
+	}
+	envelope, err := c.decodeEnvelope(data)
+	if err != nil {
+		return err
+	}
+	objVersion, objKind, err := c.scheme.raw.ObjectVersionAndKind(obj)
+	if err != nil {
+		return err
+	}
+	if envelope.Kind != objKind {
+		return fmt.Errorf("data of kind '%v', obj of type '%v'", envelope.Kind, objKind)
+	}
+	if envelope.APIVersion == objVersion {
+		return unmarshalProto(envelope.Raw, obj)
+	}
+	external, err := c.scheme.New(envelope.APIVersion, envelope.Kind)
+	if err != nil {
+		return err
+	}
+	if err := unmarshalProto(envelope.Raw, external); err != nil {
+		return err
+	}
+	return c.scheme.Convert(external, obj)
+}
+
+func (c *protobufCodec) decodeEnvelope(data []byte) (*protobufEnvelope, error) {
+	envelope := &protobufEnvelope{}
+	if err := unmarshalProto(data[len(protobufPrefix):], envelope); err != nil {
+		return nil, err
+	}
+	if envelope.Kind == "" {
+		return nil, fmt.Errorf("kind not set in protobuf data")
+	}
+	return envelope, nil
+}
+
+// base64Codec wraps a codec whose output is binary.
+type base64Codec struct {
+	Codec
+}
+
+// Base64Codec returns a Codec which base64 encodes the output of codec, for stores
+// which only hold text, like etcd. Data which isn't base64, such as JSON written
+// before the switch, is passed to codec as it is.
+func Base64Codec(codec Codec) Codec {
+	return base64Codec{codec}
+}
+
+// Encode implements Encoder.
+func (c base64Codec) Encode(obj Object) ([]byte, error) {
+	data, err := c.Codec.Encode(obj)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, base64.StdEncoding.EncodedLen(len(data)))
+	base64.StdEncoding.Encode(out, data)
+	return out, nil
+}
+
+// Decode implements Decoder.
+func (c base64Codec) Decode(data []byte) (Object, error) {
+	return c.Codec.Decode(c.unwrap(data))
+}
+
+// DecodeInto implements Decoder.
+func (c base64Codec) DecodeInto(data []byte, obj Object) error {
+	return c.Codec.DecodeInto(c.unwrap(data), obj)
+}
+
+func (c base64Codec) unwrap(data []byte) []byte {
+	out := make([]byte, base64.StdEncoding.DecodedLen(len(data)))
+	n, err := base64.StdEncoding.Decode(out, data)
+	if err != nil {
+		return data
+	}
+	return out[:n]
+}
@@ -0,0 +1,169 @@
+package runtime_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ryutah/kubernetes-transcribe/pkg/runtime"
+	"github.com/ryutah/kubernetes-transcribe/pkg/util"
+)
+
+type InternalComplex struct {
+	JSONBase   `json:",inline" yaml:",inline" protobuf:"1"`
+	Name       string            `json:"name" yaml:"name" protobuf:"2"`
+	Count      int               `json:"count" yaml:"count" protobuf:"3"`
+	Offset     int64             `json:"offset" yaml:"offset" protobuf:"4"`
+	Ratio      float64           `json:"ratio" yaml:"ratio" protobuf:"5"`
+	Enabled    bool              `json:"enabled" yaml:"enabled" protobuf:"6"`
+	Labels     map[string]string `json:"labels" yaml:"labels" protobuf:"7"`
+	Items      []ComplexItem     `json:"items" yaml:"items" protobuf:"8"`
+	Matrix     [][]int           `json:"matrix" yaml:"matrix" protobuf:"9"`
+	Parent     *ComplexItem      `json:"parent" yaml:"parent" protobuf:"10"`
+	Data       []byte            `json:"data" yaml:"data" protobuf:"11"`
+	Port       util.IntOrString  `json:"port" yaml:"port" protobuf:"12"`
+	Created    util.Time         `json:"created" yaml:"created" protobuf:"13"`
+	unexported int
+}
+
+type ExternalComplex InternalComplex
+
+type ComplexItem struct {
+	Value string `json:"value" yaml:"value" protobuf:"1"`
+	Zero  int    `json:"zero" yaml:"zero" protobuf:"2"`
+}
+
+func (*InternalComplex) IsAnAPIObject() {}
+func (*ExternalComplex) IsAnAPIObject() {}
+
+func TestProtobufCodec(t *testing.T) {
+	scheme := runtime.NewScheme()
+	scheme.AddKnownTypeWithName("", "Complex", &InternalComplex{})
+	scheme.AddKnownTypeWithName("externalVersion", "Complex", &ExternalComplex{})
+	codec := runtime.ProtobufCodecFor(scheme, "externalVersion")
+
+	complex := &InternalComplex{
+		Name:    "foo",
+		Count:   -3,
+		Offset:  1 << 40,
+		Ratio:   0.5,
+		Enabled: true,
+		Labels:  map[string]string{"a": "b", "c": ""},
+		Items:   []ComplexItem{{Value: "x"}, {}},
+		Matrix:  [][]int{{1, 0}, nil, {2}},
+		Parent:  &ComplexItem{},
+		Data:    []byte{0, 1, 2},
+		Port:    util.NewIntOrStringFromString("http"),
+		Created: util.Time{Time: time.Date(2014, 7, 1, 12, 0, 0, 0, time.UTC)},
+	}
+	data, err := codec.Encode(complex)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !runtime.IsProtobuf(data) {
+		t.Errorf("Expected protobuf data, got %q", data)
+	}
+
+	obj, err := codec.Decode(data)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(complex, obj) {
+		t.Errorf("Expected:\n %#v,\n Got:\n %#v", complex, obj)
+	}
+	external := &ExternalComplex{}
+	if err := codec.DecodeInto(data, external); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if e, a := ExternalComplex(*complex), *external; !reflect.DeepEqual(e, a) {
+		t.Errorf("Expected:\n %#v,\n Got:\n %#v", e, a)
+	}
+}
+
+func TestProtobufCodecReadsJSON(t *testing.T) {
+	scheme := runtime.NewScheme()
+	scheme.AddKnownTypeWithName("", "Simple", &InternalSimple{})
+	scheme.AddKnownTypeWithName("externalVersion", "Simple", &ExternalSimple{})
+	codec := runtime.Base64Codec(runtime.ProtobufCodecFor(scheme, "externalVersion"))
+
+	simple := &InternalSimple{TestString: "foo"}
+	for _, data := range [][]byte{
+		[]byte(`{"kind":"Simple","apiVersion":"externalVersion","testString":"foo"}`),
+		[]byte(runtime.EncodeOrDie(codec, simple)),
+	} {
+		obj, err := codec.Decode(data)
+		if err != nil {
+			t.Fatalf("Unexpected error decoding %q: %v", data, err)
+		}
+		if !reflect.DeepEqual(simple, obj) {
+			t.Errorf("Expected:\n %#v,\n Got:\n %#v", simple, obj)
+		}
+	}
+}
+
+type InternalNumbered struct {
+	JSONBase `json:",inline" yaml:",inline"`
+	First    string         `json:"first" yaml:"first"`
+	Second   int            `json:"second" yaml:"second"`
+	Item     UnnumberedItem `json:"item" yaml:"item"`
+}
+
+type ExternalNumbered struct {
+	JSONBase `json:",inline" yaml:",inline" protobuf:"1"`
+	First    string         `json:"first" yaml:"first" protobuf:"2"`
+	Second   int            `json:"second" yaml:"second" protobuf:"3"`
+	Item     UnnumberedItem `json:"item" yaml:"item" protobuf:"4"`
+}
+
+// ReorderedNumbered is ExternalNumbered with its fields moved around.
+type ReorderedNumbered struct {
+	Item     UnnumberedItem `json:"item" yaml:"item" protobuf:"4"`
+	Second   int            `json:"second" yaml:"second" protobuf:"3"`
+	JSONBase `json:",inline" yaml:",inline" protobuf:"1"`
+	First    string `json:"first" yaml:"first" protobuf:"2"`
+}
+
+// UnnumberedItem stands for the types of other projects, which are written as JSON.
+type UnnumberedItem struct {
+	Value string `json:"value" yaml:"value"`
+}
+
+type PartlyNumbered struct {
+	JSONBase `json:",inline" yaml:",inline" protobuf:"1"`
+	First    string         `json:"first" yaml:"first" protobuf:"2"`
+	Second   int            `json:"second" yaml:"second"`
+	Item     UnnumberedItem `json:"item" yaml:"item" protobuf:"4"`
+}
+
+func (*InternalNumbered) IsAnAPIObject()  {}
+func (*ExternalNumbered) IsAnAPIObject()  {}
+func (*ReorderedNumbered) IsAnAPIObject() {}
+func (*PartlyNumbered) IsAnAPIObject()    {}
+
+func TestProtobufFieldNumbers(t *testing.T) {
+	newCodec := func(external runtime.Object) runtime.Codec {
+		scheme := runtime.NewScheme()
+		scheme.AddKnownTypeWithName("", "Numbered", &InternalNumbered{})
+		scheme.AddKnownTypeWithName("externalVersion", "Numbered", external)
+		return runtime.ProtobufCodecFor(scheme, "externalVersion")
+	}
+	numbered := &InternalNumbered{First: "foo", Second: 2, Item: UnnumberedItem{Value: "bar"}}
+	data, err := newCodec(&ExternalNumbered{}).Encode(numbered)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	obj, err := newCodec(&ReorderedNumbered{}).Decode(data)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(numbered, obj) {
+		t.Errorf("Expected:\n %#v,\n Got:\n %#v", numbered, obj)
+	}
+
+	_, err = newCodec(&PartlyNumbered{}).Encode(numbered)
+	if err == nil || !strings.Contains(err.Error(), "PartlyNumbered.Second: no protobuf field number") {
+		t.Errorf("Expected an error for the unnumbered field, got %v", err)
+	}
+}
@@ -20,7 +20,7 @@ type codecWrapper struct {
 }
 
 func (c *codecWrapper) Encode(obj Object) ([]byte, error) {
-	return nil, nil
+	return c.EncodeToVersion(obj, c.version)
 }
 
 // CodecFor returns a Codec that invokes Encode with the provided version.
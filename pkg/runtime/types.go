@@ -18,12 +18,13 @@ import (
 // JSONBase is provided here for convenience. You may use it directly from this package or define
 // your own with the same fields.
 type JSONBase struct {
-	Kind              string    `json:"kind,omitempty" yaml:"kind,omitempty"`
-	ID                string    `json:"id,omitempty" yaml:"id,omitempty"`
-	CreationTimestamp util.Time `json:"creationTimestamp,omitempty" yaml:"creationTimestamp,omitempty"`
-	SelfLink          string    `json:"selfLink,omitempty" yaml:"selfLink,omitempty"`
-	ResourceVersion   uint64    `json:"resourceVersion,omitempty" yaml:"resourceVersion,omitempty"`
-	APIVersion        string    `json:"apiVersion,omitempty" yaml:"apiVersion,omitempty"`
+	Kind              string     `json:"kind,omitempty" yaml:"kind,omitempty"`
+	ID                string     `json:"id,omitempty" yaml:"id,omitempty"`
+	CreationTimestamp util.Time  `json:"creationTimestamp,omitempty" yaml:"creationTimestamp,omitempty"`
+	SelfLink          string     `json:"selfLink,omitempty" yaml:"selfLink,omitempty"`
+	ResourceVersion   uint64     `json:"resourceVersion,omitempty" yaml:"resourceVersion,omitempty"`
+	APIVersion        string     `json:"apiVersion,omitempty" yaml:"apiVersion,omitempty"`
+	DeletionTimestamp *util.Time `json:"deletionTimestamp,omitempty" yaml:"deletionTimestamp,omitempty"`
 }
 
 // PluginBase is like JSONBase, but it's intended for plugin objects that won't ever be encoded
@@ -102,3 +103,23 @@ type Unknown struct {
 }
 
 func (*Unknown) IsAnAPIObject() {}
+
+// Unstructured holds an API object of a kind not registered with the scheme at
+// hand, as the map JSON decodes it into, so that it can be read, changed and
+// passed on without knowing its type. JSONBase mirrors the corresponding keys of
+// Object: it is set when decoding, and takes precedence over them when encoding.
+type Unstructured struct {
+	JSONBase `yaml:",inline" json:",inline"`
+	Object   map[string]interface{}
+}
+
+func (*Unstructured) IsAnAPIObject() {}
+
+// UnstructuredList is a list of Unstructured objects, for lists of a kind not
+// registered with the scheme at hand.
+type UnstructuredList struct {
+	JSONBase `yaml:",inline" json:",inline"`
+	Items    []Unstructured `yaml:"items,omitempty" json:"items,omitempty"`
+}
+
+func (*UnstructuredList) IsAnAPIObject() {}
@@ -0,0 +1,82 @@
+package runtime
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// UnmarshalJSON decodes b into Object, and the fields of JSONBase.
+func (u *Unstructured) UnmarshalJSON(b []byte) error {
+	object, err := decodeMap(b)
+	if err != nil {
+		return err
+	}
+	var jsonBase JSONBase
+	if err := json.Unmarshal(b, &jsonBase); err != nil {
+		return err
+	}
+	u.Object, u.JSONBase = object, jsonBase
+	return nil
+}
+
+// MarshalJSON encodes Object, with the fields of JSONBase which are set in place
+// of its corresponding keys.
+func (u Unstructured) MarshalJSON() ([]byte, error) {
+	object := make(map[string]interface{}, len(u.Object)+6)
+	for key, value := range u.Object {
+		object[key] = value
+	}
+	base, err := json.Marshal(u.JSONBase)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := decodeMap(base)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range fields {
+		if value != nil {
+			object[key] = value
+		}
+	}
+	return json.Marshal(object)
+}
+
+// decodeMap decodes the JSON object b, keeping numbers, such as resource versions,
+// exact.
+func decodeMap(b []byte) (map[string]interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	var object map[string]interface{}
+	if err := decoder.Decode(&object); err != nil {
+		return nil, err
+	}
+	return object, nil
+}
+
+// DecodeUnstructured decodes the JSON object data into an *UnstructuredList if
+// it has an "items" array or a kind ending in "List", since lists omit their
+// items when they are empty, or an *Unstructured otherwise.
+func DecodeUnstructured(data []byte) (Object, error) {
+	var probe struct {
+		Kind  string      `json:"kind"`
+		Items interface{} `json:"items"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, fmt.Errorf("couldn't decode unstructured object: %v", err)
+	}
+	if _, ok := probe.Items.([]interface{}); ok || strings.HasSuffix(probe.Kind, "List") {
+		list := &UnstructuredList{}
+		if err := json.Unmarshal(data, list); err != nil {
+			return nil, err
+		}
+		return list, nil
+	}
+	obj := &Unstructured{}
+	if err := json.Unmarshal(data, obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
@@ -0,0 +1,92 @@
+package runtime
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeUnstructured(t *testing.T) {
+	data := []byte(`{"kind":"Widget","id":"foo","resourceVersion":9007199254740993,"spec":{"size":3}}`)
+	obj, err := DecodeUnstructured(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	u, ok := obj.(*Unstructured)
+	if !ok {
+		t.Fatalf("expected *Unstructured, got %#v", obj)
+	}
+	if e, a := "foo", u.ID; e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+	if e, a := uint64(9007199254740993), u.ResourceVersion; e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+	jsonBase, err := FindJSONBase(u)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e, a := "Widget", jsonBase.Kind(); e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+
+	u.ID = "bar"
+	out, err := json.Marshal(u)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]interface{}{
+		"kind":            "Widget",
+		"id":              "bar",
+		"resourceVersion": json.Number("9007199254740993"),
+		"spec":            map[string]interface{}{"size": json.Number("3")},
+	}
+	got, err := decodeMap(out)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected %#v, got %#v", expected, got)
+	}
+}
+
+func TestDecodeUnstructuredList(t *testing.T) {
+	data := []byte(`{"kind":"WidgetList","resourceVersion":5,"items":[{"id":"a"},{"id":"b"}]}`)
+	obj, err := DecodeUnstructured(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	list, ok := obj.(*UnstructuredList)
+	if !ok {
+		t.Fatalf("expected *UnstructuredList, got %#v", obj)
+	}
+	if e, a := uint64(5), list.ResourceVersion; e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+	items, err := ExtractList(list)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 2 || items[1].(*Unstructured).ID != "b" {
+		t.Errorf("unexpected items: %#v", items)
+	}
+}
+
+func TestDecodeUnstructuredEmptyList(t *testing.T) {
+	// Lists omit their items when there are none.
+	obj, err := DecodeUnstructured([]byte(`{"kind":"WidgetList","resourceVersion":5}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	list, ok := obj.(*UnstructuredList)
+	if !ok {
+		t.Fatalf("expected *UnstructuredList, got %#v", obj)
+	}
+	items, err := ExtractList(list)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 0 {
+		t.Errorf("unexpected items: %#v", items)
+	}
+}
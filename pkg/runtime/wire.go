@@ -0,0 +1,432 @@
+package runtime
+
+import (
+	"encoding"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// This file implements the protobuf wire format for go structs, driven by
+// reflection rather than by generated code. Each exported field of a struct
+// gives its field number in a tag, as in
+//   Host string `json:"host,omitempty" protobuf:"3"`
+// Numbers must never be reused or changed once data has been written with them,
+// just as in a .proto file, but fields may be reordered freely. A struct whose
+// fields have no numbers at all, like those of other projects, is written as its
+// JSON encoding instead; one which numbers only some of its fields is an error.
+//
+// Go types are mapped to protobuf types as follows:
+//   bool                      bool
+//   int, int8, ..., int64     sint64 (zigzag varint)
+//   uint, uint8, ..., uint64  uint64
+//   float32, float64          float, double
+//   string                    string
+//   []byte                    bytes
+//   struct, *struct           embedded message
+//   []T                       repeated T (not packed)
+//   map[K]V                   repeated message {K key = 1; V value = 2;}
+// Types implementing encoding.BinaryMarshaler, like time.Time, are written as
+// bytes. As in proto3, zero values are not written.
+
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+var (
+	binaryMarshalerType   = reflect.TypeOf((*encoding.BinaryMarshaler)(nil)).Elem()
+	binaryUnmarshalerType = reflect.TypeOf((*encoding.BinaryUnmarshaler)(nil)).Elem()
+
+	errTruncated = errors.New("protobuf: unexpected end of data")
+
+	// protoFieldCache holds the []protoField of each struct type.
+	protoFieldCache sync.Map
+)
+
+// protoField is an exported struct field and its protobuf field number.
+type protoField struct {
+	index int
+	num   int
+}
+
+// protoFields returns the exported fields of the struct type t with their numbers,
+// or nil if none of them is numbered.
+func protoFields(t reflect.Type) ([]protoField, error) {
+	if fields, ok := protoFieldCache.Load(t); ok {
+		return fields.([]protoField), nil
+	}
+	var fields, unnumbered []protoField
+	seen := map[int]string{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			// Unexported.
+			continue
+		}
+		tag, ok := field.Tag.Lookup("protobuf")
+		if !ok {
+			unnumbered = append(unnumbered, protoField{index: i})
+			continue
+		}
+		num, err := strconv.Atoi(tag)
+		if err != nil || num < 1 {
+			return nil, fmt.Errorf("%v.%v: invalid protobuf field number %q", t.Name(), field.Name, tag)
+		}
+		if other, ok := seen[num]; ok {
+			return nil, fmt.Errorf("%v.%v: protobuf field number %d is already used by %v", t.Name(), field.Name, num, other)
+		}
+		seen[num] = field.Name
+		fields = append(fields, protoField{i, num})
+	}
+	if len(fields) > 0 && len(unnumbered) > 0 {
+		return nil, fmt.Errorf("%v.%v: no protobuf field number", t.Name(), t.Field(unnumbered[0].index).Name)
+	}
+	protoFieldCache.Store(t, fields)
+	return fields, nil
+}
+
+// marshalProto encodes the struct pointed to by obj as a protobuf message.
+func marshalProto(obj interface{}) ([]byte, error) {
+	v := reflect.ValueOf(obj)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("expected pointer to struct, but got %v", v.Type())
+	}
+	return appendMessage(nil, v.Elem())
+}
+
+// unmarshalProto decodes a protobuf message into the struct pointed to by obj.
+func unmarshalProto(data []byte, obj interface{}) error {
+	v := reflect.ValueOf(obj)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("expected pointer to struct, but got %v", v.Type())
+	}
+	return readMessage(data, v.Elem())
+}
+
+func appendMessage(buf []byte, v reflect.Value) ([]byte, error) {
+	t := v.Type()
+	fields, err := protoFields(t)
+	if err != nil {
+		return nil, err
+	}
+	if fields == nil {
+		data, err := json.Marshal(v.Interface())
+		if err != nil {
+			return nil, err
+		}
+		return append(buf, data...), nil
+	}
+	for _, field := range fields {
+		buf, err = appendField(buf, field.num, v.Field(field.index), true)
+		if err != nil {
+			return nil, fmt.Errorf("%v.%v: %v", t.Name(), t.Field(field.index).Name, err)
+		}
+	}
+	return buf, nil
+}
+
+// appendField appends field num holding v to buf. Zero values are skipped if
+// omitZero is set; it isn't for the elements of repeated fields, whose positions
+// must be kept.
+func appendField(buf []byte, num int, v reflect.Value, omitZero bool) ([]byte, error) {
+	if omitZero && isZero(v) {
+		return buf, nil
+	}
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return buf, nil
+		}
+		// A pointer to a zero value is still set.
+		return appendField(buf, num, v.Elem(), false)
+	}
+	if v.Type().Implements(binaryMarshalerType) {
+		data, err := v.Interface().(encoding.BinaryMarshaler).MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		return appendBytes(buf, num, data), nil
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		x := uint64(0)
+		if v.Bool() {
+			x = 1
+		}
+		return appendVarint(appendTag(buf, num, wireVarint), x), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		x := v.Int()
+		return appendVarint(appendTag(buf, num, wireVarint), uint64(x<<1)^uint64(x>>63)), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return appendVarint(appendTag(buf, num, wireVarint), v.Uint()), nil
+	case reflect.Float32:
+		var b [4]byte
+		binary.LittleEndian.PutUint32(b[:], math.Float32bits(float32(v.Float())))
+		return append(appendTag(buf, num, wireFixed32), b[:]...), nil
+	case reflect.Float64:
+		var b [8]byte
+		binary.LittleEndian.PutUint64(b[:], math.Float64bits(v.Float()))
+		return append(appendTag(buf, num, wireFixed64), b[:]...), nil
+	case reflect.String:
+		return appendBytes(buf, num, []byte(v.String())), nil
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return appendBytes(buf, num, bytesOf(v)), nil
+		}
+		for i := 0; i < v.Len(); i++ {
+			var err error
+			if buf, err = appendElement(buf, num, v.Index(i)); err != nil {
+				return nil, err
+			}
+		}
+		return buf, nil
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface()) })
+		for _, key := range keys {
+			entry, err := appendField(nil, 1, key, true)
+			if err != nil {
+				return nil, err
+			}
+			if entry, err = appendField(entry, 2, v.MapIndex(key), true); err != nil {
+				return nil, err
+			}
+			buf = appendBytes(buf, num, entry)
+		}
+		return buf, nil
+	case reflect.Struct:
+		msg, err := appendMessage(nil, v)
+		if err != nil {
+			return nil, err
+		}
+		return appendBytes(buf, num, msg), nil
+	}
+	return nil, fmt.Errorf("unsupported type %v", v.Type())
+}
+
+// appendElement appends an element of a repeated field. Lists and maps can't be
+// repeated themselves, so they are wrapped in a message as its field 1.
+func appendElement(buf []byte, num int, v reflect.Value) ([]byte, error) {
+	if !isNested(v.Type()) {
+		return appendField(buf, num, v, false)
+	}
+	msg, err := appendField(nil, 1, v, true)
+	if err != nil {
+		return nil, err
+	}
+	return appendBytes(buf, num, msg), nil
+}
+
+func readMessage(data []byte, v reflect.Value) error {
+	t := v.Type()
+	fields, err := protoFields(t)
+	if err != nil {
+		return err
+	}
+	if fields == nil {
+		return json.Unmarshal(data, v.Addr().Interface())
+	}
+	indexes := map[int]int{}
+	for _, field := range fields {
+		indexes[field.num] = field.index
+	}
+	for len(data) > 0 {
+		tag, n := binary.Uvarint(data)
+		if n <= 0 {
+			return errTruncated
+		}
+		data = data[n:]
+		num, wireType := int(tag>>3), int(tag&7)
+		value, rest, err := splitValue(data, wireType)
+		if err != nil {
+			return err
+		}
+		data = rest
+		i, ok := indexes[num]
+		if !ok {
+			// Unknown field, probably written by a newer version.
+			continue
+		}
+		if err := readField(value, wireType, v.Field(i)); err != nil {
+			return fmt.Errorf("%v.%v: %v", t.Name(), t.Field(i).Name, err)
+		}
+	}
+	return nil
+}
+
+// splitValue splits the value of a field with the given wire type off the front of
+// data. Varints are returned as they are.
+func splitValue(data []byte, wireType int) (value, rest []byte, err error) {
+	switch wireType {
+	case wireVarint:
+		_, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, nil, errTruncated
+		}
+		return data[:n], data[n:], nil
+	case wireFixed64:
+		if len(data) < 8 {
+			return nil, nil, errTruncated
+		}
+		return data[:8], data[8:], nil
+	case wireFixed32:
+		if len(data) < 4 {
+			return nil, nil, errTruncated
+		}
+		return data[:4], data[4:], nil
+	case wireBytes:
+		l, n := binary.Uvarint(data)
+		if n <= 0 || uint64(len(data)-n) < l {
+			return nil, nil, errTruncated
+		}
+		end := n + int(l)
+		return data[n:end], data[end:], nil
+	}
+	return nil, nil, fmt.Errorf("protobuf: unsupported wire type %d", wireType)
+}
+
+// readField decodes value, read with wireType, into v. Repeated fields are
+// appended to, and message fields merged into.
+func readField(value []byte, wireType int, v reflect.Value) error {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return readField(value, wireType, v.Elem())
+	}
+	if v.CanAddr() && v.Addr().Type().Implements(binaryUnmarshalerType) {
+		if wireType != wireBytes {
+			return fmt.Errorf("unexpected wire type %d", wireType)
+		}
+		return v.Addr().Interface().(encoding.BinaryUnmarshaler).UnmarshalBinary(value)
+	}
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+		elem := reflect.New(v.Type().Elem()).Elem()
+		if err := readElement(value, wireType, elem); err != nil {
+			return err
+		}
+		v.Set(reflect.Append(v, elem))
+		return nil
+	}
+	want := wireBytes
+	switch v.Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		want = wireVarint
+	case reflect.Float32:
+		want = wireFixed32
+	case reflect.Float64:
+		want = wireFixed64
+	}
+	if wireType != want {
+		return fmt.Errorf("unexpected wire type %d for %v", wireType, v.Type())
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		x, _ := binary.Uvarint(value)
+		v.SetBool(x != 0)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		x, _ := binary.Uvarint(value)
+		v.SetInt(int64(x>>1) ^ -int64(x&1))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		x, _ := binary.Uvarint(value)
+		v.SetUint(x)
+	case reflect.Float32:
+		v.SetFloat(float64(math.Float32frombits(binary.LittleEndian.Uint32(value))))
+	case reflect.Float64:
+		v.SetFloat(math.Float64frombits(binary.LittleEndian.Uint64(value)))
+	case reflect.String:
+		v.SetString(string(value))
+	case reflect.Slice:
+		v.SetBytes(append([]byte{}, value...))
+	case reflect.Array:
+		if v.Type().Elem().Kind() != reflect.Uint8 {
+			return fmt.Errorf("unsupported type %v", v.Type())
+		}
+		reflect.Copy(v, reflect.ValueOf(value))
+	case reflect.Map:
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		entry := reflect.New(reflect.StructOf([]reflect.StructField{
+			{Name: "Key", Type: v.Type().Key(), Tag: `protobuf:"1"`},
+			{Name: "Value", Type: v.Type().Elem(), Tag: `protobuf:"2"`},
+		})).Elem()
+		if err := readMessage(value, entry); err != nil {
+			return err
+		}
+		v.SetMapIndex(entry.Field(0), entry.Field(1))
+	case reflect.Struct:
+		return readMessage(value, v)
+	default:
+		return fmt.Errorf("unsupported type %v", v.Type())
+	}
+	return nil
+}
+
+// readElement is the reverse of appendElement.
+func readElement(value []byte, wireType int, v reflect.Value) error {
+	if !isNested(v.Type()) {
+		return readField(value, wireType, v)
+	}
+	wrapper := reflect.New(reflect.StructOf([]reflect.StructField{
+		{Name: "Value", Type: v.Type(), Tag: `protobuf:"1"`},
+	})).Elem()
+	if err := readMessage(value, wrapper); err != nil {
+		return err
+	}
+	v.Set(wrapper.Field(0))
+	return nil
+}
+
+// isNested returns true for lists and maps, other than bytes.
+func isNested(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Map:
+		return true
+	case reflect.Slice, reflect.Array:
+		return t.Elem().Kind() != reflect.Uint8
+	}
+	return false
+}
+
+func isZero(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
+
+func bytesOf(v reflect.Value) []byte {
+	if v.Kind() == reflect.Slice {
+		return v.Bytes()
+	}
+	b := make([]byte, v.Len())
+	reflect.Copy(reflect.ValueOf(b), v)
+	return b
+}
+
+func appendTag(buf []byte, num, wireType int) []byte {
+	return appendVarint(buf, uint64(num)<<3|uint64(wireType))
+}
+
+func appendVarint(buf []byte, x uint64) []byte {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(b[:], x)
+	return append(buf, b[:n]...)
+}
+
+func appendBytes(buf []byte, num int, data []byte) []byte {
+	buf = appendVarint(appendTag(buf, num, wireBytes), uint64(len(data)))
+	return append(buf, data...)
+}
@@ -4,9 +4,11 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"time"
 
 	"github.com/coreos/go-etcd/etcd"
 	"github.com/ryutah/kubernetes-transcribe/pkg/runtime"
+	"github.com/ryutah/kubernetes-transcribe/pkg/util"
 )
 
 const (
@@ -14,6 +16,7 @@ const (
 	EtcdErrorCodeTestFailed    = 101
 	EtcdErrorCodeNodeExist     = 105
 	EtcdErrorCodeValueRequired = 200
+	EtcdErrorCodeIndexCleared  = 401
 )
 
 var (
@@ -21,6 +24,7 @@ var (
 	EtcdErrorTestFailed    = &etcd.EtcdError{ErrorCode: EtcdErrorCodeTestFailed}
 	EtcdErrorNodeExist     = &etcd.EtcdError{ErrorCode: EtcdErrorCodeNodeExist}
 	EtcdErrorValueRequired = &etcd.EtcdError{ErrorCode: EtcdErrorCodeValueRequired}
+	EtcdErrorIndexCleared  = &etcd.EtcdError{ErrorCode: EtcdErrorCodeIndexCleared}
 )
 
 // EtcdClient is an injectable interface for testing.
@@ -31,6 +35,7 @@ type EtcdClient interface {
 	Create(key, value string, ttl uint64) (*etcd.Response, error)
 	CompareAndSwap(key, value string, ttl uint64, prevValue string, prevIndex uint64) (*etcd.Response, error)
 	Delete(key string, recursive bool) (*etcd.Response, error)
+	CompareAndDelete(key string, prevValue string, prevIndex uint64) (*etcd.Response, error)
 	// I'd like to use directional channels here (e.g. <-chan) but this interface mimics
 	// the etcd client interface which doesn't, and it doesn't seem worth it to wrap the api.
 	Watch(prefix string, waitIndex uint64, recursive bool, receiver chan *etcd.Response, stop chan bool) (*etcd.Response, error)
@@ -43,6 +48,7 @@ type EtcdGetSet interface {
 	Create(key, value string, ttl uint64) (*etcd.Response, error)
 	Delete(key string, recursive bool) (*etcd.Response, error)
 	CompareAndSwap(key, value string, ttl uint64, prevValue string, prevIndex uint64) (*etcd.Response, error)
+	CompareAndDelete(key string, prevValue string, prevIndex uint64) (*etcd.Response, error)
 	Watch(prefix string, waitIndex uint64, recursive bool, receiver chan *etcd.Response, stop chan bool) (*etcd.Response, error)
 }
 
@@ -69,6 +75,12 @@ func IsEtcdTestFailed(err error) bool {
 	return isEtcdErrorNum(err, EtcdErrorCodeTestFailed)
 }
 
+// IsEtcdIndexCleared returns true if err means that the index to watch from is
+// older than the history etcd keeps.
+func IsEtcdIndexCleared(err error) bool {
+	return isEtcdErrorNum(err, EtcdErrorCodeIndexCleared)
+}
+
 // IsEtcdWatchStoppedbyUser returns true if err is client triggered stop.
 func IsEtcdWatchStoppedbyUser(err error) bool {
 	return etcd.ErrWatchStoppedByUser == err
@@ -190,18 +202,102 @@ func (h *EtcdHelper) Delete(key string, recursive bool) error {
 	return err
 }
 
+// CompareAndDelete removes the specified key, if it was last modified at resourceVersion.
+// Otherwise an error for which IsEtcdTestFailed is true is returned. A resourceVersion
+// of 0 removes the key unconditionally.
+func (h *EtcdHelper) CompareAndDelete(key string, resourceVersion uint64) error {
+	if resourceVersion == 0 {
+		return h.Delete(key, false)
+	}
+	_, err := h.Client.CompareAndDelete(key, "", resourceVersion)
+	return err
+}
+
+// DeleteObjGracefully marks the object stored under key as being deleted, by setting
+// its DeletionTimestamp, if it has one, to the end of gracePeriod and passing it
+// through markDeleted, and lets etcd remove it after gracePeriod seconds. The
+// removal is seen by watchers as a deletion. A gracePeriod of 0 removes the key at
+// once. If resourceVersion isn't 0 and the object was last modified at another
+// version, an error for which IsEtcdTestFailed is true is returned.
+func (h *EtcdHelper) DeleteObjGracefully(key string, ptrToType runtime.Object, resourceVersion, gracePeriod uint64, markDeleted EtcdUpdateFunc) error {
+	if gracePeriod == 0 {
+		return h.CompareAndDelete(key, resourceVersion)
+	}
+	origBody, index, err := h.bodyAndExtractObj(key, ptrToType, false)
+	if err != nil {
+		return err
+	}
+	if resourceVersion != 0 && resourceVersion != index {
+		return EtcdErrorTestFailed
+	}
+	jsonBase, err := runtime.FindJSONBase(ptrToType)
+	if err != nil {
+		return err
+	}
+	jsonBase.SetDeletionTimestamp(&util.Time{Time: time.Now().Add(time.Duration(gracePeriod) * time.Second)})
+	ret, err := markDeleted(ptrToType)
+	if err != nil {
+		return err
+	}
+	data, err := h.Codec.Encode(ret)
+	if err != nil {
+		return err
+	}
+	_, err = h.Client.CompareAndSwap(key, string(data), gracePeriod, origBody, index)
+	return err
+}
+
+// deletionTimestamp returns the DeletionTimestamp of obj, or nil if it has none.
+func deletionTimestamp(obj runtime.Object) *util.Time {
+	jsonBase, err := runtime.FindJSONBase(obj)
+	if err != nil {
+		return nil
+	}
+	return jsonBase.DeletionTimestamp()
+}
+
+// keepGracePeriod carries deletionTimestamp, that of the object in etcd, over to obj,
+// which is about to replace it, and returns the TTL to store obj with. As the grace
+// period of DeleteObjGracefully is an etcd TTL, storing obj without one would cancel the
+// deletion of an object which is being deleted.
+func keepGracePeriod(deletionTimestamp *util.Time, obj runtime.Object) uint64 {
+	if deletionTimestamp == nil {
+		return 0
+	}
+	if jsonBase, err := runtime.FindJSONBase(obj); err == nil {
+		jsonBase.SetDeletionTimestamp(deletionTimestamp)
+	}
+	remaining := deletionTimestamp.Sub(time.Now())
+	if remaining <= 0 {
+		// A TTL of 0 means none, so let an overdue object expire as soon as possible.
+		return 1
+	}
+	return uint64((remaining + time.Second - 1) / time.Second)
+}
+
 // SetObj marshals obj via json, and stores under key. Will do an
 // atomic update if obj's ResourceVersioner field is set.
 func (h *EtcdHelper) SetObj(key string, obj runtime.Object) error {
+	var version, ttl uint64
+	if h.ResourceVersioner != nil {
+		if v, err := h.ResourceVersioner.ResourceVersion(obj); err == nil {
+			version = v
+		}
+	}
+	if version != 0 {
+		stored := reflect.New(reflect.TypeOf(obj).Elem()).Interface().(runtime.Object)
+		if err := h.ExtractObj(key, stored, false); err != nil {
+			return err
+		}
+		ttl = keepGracePeriod(deletionTimestamp(stored), obj)
+	}
 	data, err := h.Codec.Encode(obj)
 	if err != nil {
 		return err
 	}
-	if h.ResourceVersioner != nil {
-		if version, err := h.ResourceVersioner.ResourceVersion(obj); err == nil && version != 0 {
-			_, err = h.Client.CompareAndSwap(key, string(data), 0, "", version)
-			return err // err is shadowd!
-		}
+	if version != 0 {
+		_, err = h.Client.CompareAndSwap(key, string(data), ttl, "", version)
+		return err
 	}
 
 	// Create will faild if a key already exists.
@@ -244,10 +340,13 @@ func (h *EtcdHelper) AtomicUpdate(key string, ptrToType runtime.Object, tryUpdat
 			return err
 		}
 
+		// tryUpdate may modify obj in place, so look at its DeletionTimestamp first.
+		deleting := deletionTimestamp(obj)
 		ret, err := tryUpdate(obj)
 		if err != nil {
 			return err
 		}
+		ttl := keepGracePeriod(deleting, ret)
 
 		data, err := h.Codec.Encode(ret)
 		if err != nil {
@@ -267,7 +366,7 @@ func (h *EtcdHelper) AtomicUpdate(key string, ptrToType runtime.Object, tryUpdat
 			return nil
 		}
 
-		_, err = h.Client.CompareAndSwap(key, string(data), 0, origBody, index)
+		_, err = h.Client.CompareAndSwap(key, string(data), ttl, origBody, index)
 		if IsEtcdTestFailed(err) {
 			continue
 		}
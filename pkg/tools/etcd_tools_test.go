@@ -0,0 +1,111 @@
+package tools
+
+import (
+	"testing"
+	"time"
+
+	"github.com/coreos/go-etcd/etcd"
+	"github.com/ryutah/kubernetes-transcribe/pkg/api"
+	"github.com/ryutah/kubernetes-transcribe/pkg/runtime"
+	"github.com/ryutah/kubernetes-transcribe/pkg/util"
+)
+
+func TestDeleteObjGracefully(t *testing.T) {
+	fakeClient := NewFakeEtcdClient(t)
+	fakeClient.TestIndex = true
+	fakeClient.ChangeIndex = 1
+	h := EtcdHelper{fakeClient, codec, runtime.NewJSONBaseResourceVersioner()}
+	data, err := codec.Encode(&api.Pod{JSONBase: api.JSONBase{ID: "foo"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fakeClient.Data["/some/key"] = EtcdResponseWithError{
+		R: &etcd.Response{Node: &etcd.Node{Value: string(data), ModifiedIndex: 1}},
+	}
+
+	before := time.Now()
+	marked := false
+	err = h.DeleteObjGracefully("/some/key", &api.Pod{}, 1, 30, func(obj runtime.Object) (runtime.Object, error) {
+		marked = true
+		return obj, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !marked {
+		t.Errorf("expected the object to be marked as deleted")
+	}
+	pod := &api.Pod{}
+	if err := codec.DecodeInto([]byte(fakeClient.Data["/some/key"].R.Node.Value), pod); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pod.DeletionTimestamp == nil {
+		t.Fatalf("expected a deletion timestamp")
+	}
+	if d := pod.DeletionTimestamp.Sub(before); d < 29*time.Second || d > 31*time.Second {
+		t.Errorf("expected the deletion timestamp to be 30s away, got %v", d)
+	}
+	if e, a := int64(30), fakeClient.Data["/some/key"].R.Node.TTL; e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+
+	if err := h.DeleteObjGracefully("/some/key", &api.Pod{}, 1, 30, nil); !IsEtcdTestFailed(err) {
+		t.Errorf("expected a failed test, got %v", err)
+	}
+}
+
+func TestUpdateDuringGracePeriod(t *testing.T) {
+	fakeClient := NewFakeEtcdClient(t)
+	fakeClient.TestIndex = true
+	fakeClient.ChangeIndex = 1
+	h := EtcdHelper{fakeClient, codec, runtime.NewJSONBaseResourceVersioner()}
+	data, err := codec.Encode(&api.Pod{JSONBase: api.JSONBase{ID: "foo"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fakeClient.Data["/some/key"] = EtcdResponseWithError{
+		R: &etcd.Response{Node: &etcd.Node{Value: string(data), ModifiedIndex: 1}},
+	}
+	if err := h.DeleteObjGracefully("/some/key", &api.Pod{}, 0, 30, func(obj runtime.Object) (runtime.Object, error) {
+		return obj, nil
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	deletionTimestamp := func() *util.Time {
+		pod := &api.Pod{}
+		if err := codec.DecodeInto([]byte(fakeClient.Data["/some/key"].R.Node.Value), pod); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return pod.DeletionTimestamp
+	}
+	expected := deletionTimestamp()
+
+	// The update doesn't carry the DeletionTimestamp, but mustn't cancel the deletion.
+	pod := &api.Pod{JSONBase: api.JSONBase{ID: "foo", ResourceVersion: fakeClient.Data["/some/key"].R.Node.ModifiedIndex}}
+	pod.Labels = map[string]string{"updated": "set"}
+	if err := h.SetObj("/some/key", pod); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ttl := fakeClient.Data["/some/key"].R.Node.TTL; ttl < 29 || ttl > 30 {
+		t.Errorf("expected the rest of the grace period as TTL, got %v", ttl)
+	}
+	if a := deletionTimestamp(); a == nil || !a.Equal(expected.Time) {
+		t.Errorf("expected %v, got %v", expected, a)
+	}
+
+	err = h.AtomicUpdate("/some/key", &api.Pod{}, func(obj runtime.Object) (runtime.Object, error) {
+		pod := obj.(*api.Pod)
+		pod.Labels = map[string]string{"updated": "atomic"}
+		pod.DeletionTimestamp = nil
+		return pod, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ttl := fakeClient.Data["/some/key"].R.Node.TTL; ttl < 29 || ttl > 30 {
+		t.Errorf("expected the rest of the grace period as TTL, got %v", ttl)
+	}
+	if a := deletionTimestamp(); a == nil || !a.Equal(expected.Time) {
+		t.Errorf("expected %v, got %v", expected, a)
+	}
+}
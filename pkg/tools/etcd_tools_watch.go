@@ -1,10 +1,13 @@
 package tools
 
 import (
+	"fmt"
+	"net/http"
 	"sync"
 
 	"github.com/coreos/go-etcd/etcd"
 	"github.com/golang/glog"
+	"github.com/ryutah/kubernetes-transcribe/pkg/api"
 	"github.com/ryutah/kubernetes-transcribe/pkg/runtime"
 	"github.com/ryutah/kubernetes-transcribe/pkg/util"
 	"github.com/ryutah/kubernetes-transcribe/pkg/watch"
@@ -70,6 +73,9 @@ type etcdWatcher struct {
 	etcdIncoming  chan *etcd.Response
 	etcdStop      chan bool
 	etcdCallEnded chan struct{}
+	// etcdErr is the error the etcd watch ended with, if it wasn't stopped. It is
+	// set before etcdCallEnded is closed.
+	etcdErr error
 
 	outgoing chan watch.Event
 	userStop chan struct{}
@@ -111,10 +117,11 @@ func (w *etcdWatcher) etcdWatch(client EtcdGetSet, key string, resourceVersion u
 			return
 		}
 		resourceVersion = latest + 1
-		_, err := client.Watch(key, resourceVersion, w.list, w.etcdIncoming, w.etcdStop) // XXX(ryutah): Maybe blocking here.
-		if err != etcd.ErrWatchStoppedByUser {
-			glog.Errorf("etcd.Watch stopped unexpectedly: %v (%#v)", err, key)
-		}
+	}
+	_, err := client.Watch(key, resourceVersion, w.list, w.etcdIncoming, w.etcdStop)
+	if err != etcd.ErrWatchStoppedByUser {
+		glog.Errorf("etcd.Watch stopped unexpectedly: %v (%#v)", err, key)
+		w.etcdErr = err
 	}
 }
 
@@ -158,12 +165,16 @@ func (w *etcdWatcher) translate() {
 	for {
 		select {
 		case <-w.etcdCallEnded:
+			w.sendError()
 			return
 		case <-w.userStop:
 			w.etcdStop <- true
 			return
 		case res, ok := <-w.etcdIncoming:
 			if !ok {
+				// The etcd watch is ending; find out why.
+				<-w.etcdCallEnded
+				w.sendError()
 				return
 			}
 			w.sendResult(res)
@@ -299,13 +310,33 @@ func (w *etcdWatcher) sendDelete(res *etcd.Response) {
 	})
 }
 
+// sendError sends an event of type watch.Error, with an api.Status whose reason is
+// api.StatusReasonExpired, if the etcd watch ended because the index it was made
+// from is no longer in etcd's history. Watching again can't help then: the user
+// must list again.
+func (w *etcdWatcher) sendError() {
+	if !IsEtcdIndexCleared(w.etcdErr) {
+		return
+	}
+	w.emit(watch.Event{
+		Type: watch.Error,
+		Object: &api.Status{
+			Status:  api.StatusFailure,
+			Code:    http.StatusGone,
+			Reason:  api.StatusReasonExpired,
+			Message: fmt.Sprintf("resource version is too old to watch from: %v", w.etcdErr),
+		},
+	})
+}
+
 func (w *etcdWatcher) sendResult(res *etcd.Response) {
 	switch res.Action {
 	case "create", "get":
 		w.sendAdd(res)
 	case "set", "compareAndSwap":
 		w.sendModify(res)
-	case "delete":
+	case "delete", "compareAndDelete", "expire":
+		// Keys expire at the end of a graceful deletion.
 		w.sendDelete(res)
 	default:
 		glog.Errorf("unknown action: %v", res.Action)
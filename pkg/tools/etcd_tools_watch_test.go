@@ -0,0 +1,82 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/coreos/go-etcd/etcd"
+	"github.com/ryutah/kubernetes-transcribe/pkg/api"
+	"github.com/ryutah/kubernetes-transcribe/pkg/runtime"
+	"github.com/ryutah/kubernetes-transcribe/pkg/watch"
+)
+
+// The versioned API types are not registered yet, so tests use the internal version.
+var codec = runtime.CodecFor(api.Scheme, "")
+
+func TestWatchDeleteActions(t *testing.T) {
+	pod := &api.Pod{JSONBase: api.JSONBase{ID: "foo"}}
+	data, err := codec.Encode(pod)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, action := range []string{"delete", "compareAndDelete", "expire"} {
+		w := newEtcdWatcher(false, Everything, codec, runtime.NewJSONBaseResourceVersioner(), nil)
+		var events []watch.Event
+		w.emit = func(e watch.Event) { events = append(events, e) }
+		w.sendResult(&etcd.Response{
+			Action:   action,
+			Node:     &etcd.Node{ModifiedIndex: 3},
+			PrevNode: &etcd.Node{Value: string(data), ModifiedIndex: 2},
+		})
+		w.Stop()
+		if len(events) != 1 {
+			t.Fatalf("%s: expected 1 event, got %#v", action, events)
+		}
+		if e, a := watch.Deleted, events[0].Type; e != a {
+			t.Errorf("%s: expected %v, got %v", action, e, a)
+		}
+		got := events[0].Object.(*api.Pod)
+		if got.ID != "foo" || got.ResourceVersion != 3 {
+			t.Errorf("%s: unexpected object %#v", action, got)
+		}
+	}
+}
+
+func TestWatchIndexCleared(t *testing.T) {
+	fakeClient := NewFakeEtcdClient(t)
+	h := EtcdHelper{fakeClient, codec, runtime.NewJSONBaseResourceVersioner()}
+	w, err := h.Watch("/some/key", 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fakeClient.WaitForWatchCompletion()
+	fakeClient.WatchInjectError <- EtcdErrorIndexCleared
+
+	event, ok := <-w.ResultChan()
+	if !ok {
+		t.Fatalf("expected an error event")
+	}
+	if e, a := watch.Error, event.Type; e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+	status, ok := event.Object.(*api.Status)
+	if !ok || status.Reason != api.StatusReasonExpired || status.Code != 410 {
+		t.Errorf("expected an expired status, got %#v", event.Object)
+	}
+	if _, ok := <-w.ResultChan(); ok {
+		t.Errorf("expected the watch to end")
+	}
+}
+
+func TestWatchOtherErrorsEndQuietly(t *testing.T) {
+	fakeClient := NewFakeEtcdClient(t)
+	h := EtcdHelper{fakeClient, codec, runtime.NewJSONBaseResourceVersioner()}
+	w, err := h.Watch("/some/key", 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fakeClient.WaitForWatchCompletion()
+	fakeClient.WatchInjectError <- EtcdErrorNotFound
+	if event, ok := <-w.ResultChan(); ok {
+		t.Errorf("expected the watch to end, got %#v", event)
+	}
+}
@@ -64,6 +64,12 @@ func NewFakeEtcdClient(t TestLogger) *FakeEtcdClient {
 	return ret
 }
 
+// WaitForWatchCompletion waits until Watch has been called, after which the watch
+// channels may be used.
+func (f *FakeEtcdClient) WaitForWatchCompletion() {
+	<-f.watchCompletedChan
+}
+
 func (f *FakeEtcdClient) ExpectNotFoundGet(key string) {
 	f.expectNotFoundGetSet[key] = struct{}{}
 }
@@ -132,6 +138,7 @@ func (f *FakeEtcdClient) setLocked(key, value string, ttl uint64) (*etcd.Respons
 					Value:         value,
 					CreatedIndex:  createdIndex,
 					ModifiedIndex: i,
+					TTL:           int64(ttl),
 				},
 			},
 		}
@@ -146,6 +153,7 @@ func (f *FakeEtcdClient) setLocked(key, value string, ttl uint64) (*etcd.Respons
 				Value:         value,
 				CreatedIndex:  i,
 				ModifiedIndex: i,
+				TTL:           int64(ttl),
 			},
 		},
 	}
@@ -228,6 +236,50 @@ func (f *FakeEtcdClient) Delete(key string, recursive bool) (*etcd.Response, err
 	return &etcd.Response{}, nil
 }
 
+func (f *FakeEtcdClient) CompareAndDelete(key string, prevValue string, prevIndex uint64) (*etcd.Response, error) {
+	if f.Err != nil {
+		return nil, f.Err
+	}
+
+	if !f.TestIndex {
+		f.t.Errorf("Enable TestIndex for test involving CompareAndDelete")
+		return nil, errors.New("Enable TestIndex for test involving CompareAndDelete")
+	}
+
+	if prevValue == "" && prevIndex == 0 {
+		return nil, errors.New("Either prevValue or prevIndex must be specified.")
+	}
+
+	f.Mutex.Lock()
+	defer f.Mutex.Unlock()
+
+	if !f.nodeExists(key) {
+		f.t.Logf("c&d: node doesn't exist")
+		return nil, EtcdErrorNotFound
+	}
+
+	prevNode := f.Data[key].R.Node
+
+	if prevValue != "" && prevValue != prevNode.Value {
+		f.t.Logf("body didn't match")
+		return nil, EtcdErrorTestFailed
+	}
+
+	if prevIndex != 0 && prevIndex != prevNode.ModifiedIndex {
+		f.t.Logf("got index %v but needed %v", prevIndex, prevNode.ModifiedIndex)
+		return nil, EtcdErrorTestFailed
+	}
+
+	f.Data[key] = EtcdResponseWithError{
+		R: &etcd.Response{
+			Node: nil,
+		},
+		E: EtcdErrorNotFound,
+	}
+	f.DeleteKeys = append(f.DeleteKeys, key)
+	return &etcd.Response{}, nil
+}
+
 func (f *FakeEtcdClient) Watch(prefix string, waitIndex uint64, recursive bool, receiver chan *etcd.Response, stop chan bool) (*etcd.Response, error) {
 	f.WatchResponse = receiver
 	f.WatchStop = stop
@@ -34,12 +34,27 @@ func HandleCrash() {
 
 // Forever loops forever running f ever d. Catches any panics, and keeps going.
 func Forever(f func(), period time.Duration) {
+	Until(f, period, nil)
+}
+
+// Until loops until stopCh is closed, running f every period. Catches any panics,
+// and keeps going. A nil stopCh is never closed.
+func Until(f func(), period time.Duration, stopCh <-chan struct{}) {
 	for {
+		select {
+		case <-stopCh:
+			return
+		default:
+		}
 		func() {
 			defer HandleCrash()
 			f()
 		}()
-		time.Sleep(period)
+		select {
+		case <-stopCh:
+			return
+		case <-time.After(period):
+		}
 	}
 }
 
@@ -57,9 +72,9 @@ const (
 // When used in JSON or YAML marshalling and unmarshalling, it produces or consumes the
 // inner type. This allows you to have, for example, a JSON field that can accept a name or number.
 type IntOrString struct {
-	Kind   IntstrKind
-	IntVal int
-	StrVal string
+	Kind   IntstrKind `protobuf:"1"`
+	IntVal int        `protobuf:"2"`
+	StrVal string     `protobuf:"3"`
 }
 
 // NewIntOrStringFromInt creates an IntOrString object with an int value.
@@ -1,5 +1,119 @@
 package wait
 
+import (
+	"errors"
+	"math/rand"
+	"time"
+)
+
+// ErrWaitTimeout is returned when the condition was not satisfied in time.
+var ErrWaitTimeout = errors.New("timed out waiting for the condition")
+
 // ConditionFunc returns true if the condition is satisfied, or an error
 // if the loop should be aborted.
 type ConditionFunc func() (done bool, err error)
+
+// Poll checks condition every interval, starting after the first interval, until
+// it is satisfied, fails, or timeout elapses, in which case ErrWaitTimeout is
+// returned.
+func Poll(interval, timeout time.Duration, condition ConditionFunc) error {
+	stopCh, stop := timeoutChannel(timeout)
+	defer stop()
+	return poll(false, interval, condition, stopCh)
+}
+
+// PollImmediate is like Poll, but checks condition right away as well.
+func PollImmediate(interval, timeout time.Duration, condition ConditionFunc) error {
+	stopCh, stop := timeoutChannel(timeout)
+	defer stop()
+	return poll(true, interval, condition, stopCh)
+}
+
+// PollUntil checks condition every interval, starting after the first interval,
+// until it is satisfied, fails, or stopCh is closed, in which case ErrWaitTimeout
+// is returned.
+func PollUntil(interval time.Duration, condition ConditionFunc, stopCh <-chan struct{}) error {
+	return poll(false, interval, condition, stopCh)
+}
+
+// timeoutChannel returns a channel closed once timeout elapses, and a function
+// which releases its timer if it's no longer needed.
+func timeoutChannel(timeout time.Duration) (<-chan struct{}, func() bool) {
+	ch := make(chan struct{})
+	timer := time.AfterFunc(timeout, func() { close(ch) })
+	return ch, timer.Stop
+}
+
+func poll(immediate bool, interval time.Duration, condition ConditionFunc, stopCh <-chan struct{}) error {
+	if immediate {
+		if done, err := condition(); err != nil || done {
+			return err
+		}
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if done, err := condition(); err != nil || done {
+				return err
+			}
+		case <-stopCh:
+			return ErrWaitTimeout
+		}
+	}
+}
+
+// Jitter returns a duration between duration and duration*(1+maxFactor), at
+// random. A maxFactor of 0 or less means 1.
+func Jitter(duration time.Duration, maxFactor float64) time.Duration {
+	if maxFactor <= 0 {
+		maxFactor = 1
+	}
+	return duration + time.Duration(rand.Float64()*maxFactor*float64(duration))
+}
+
+// Backoff describes the waits of ExponentialBackoff. The first wait is Duration,
+// and each one after that is Factor times longer, up to Cap if that is not zero.
+// Jitter, if above zero, adds up to that fraction of each wait to it, at random.
+// Steps is the number of times the condition is checked.
+type Backoff struct {
+	Duration time.Duration
+	Factor   float64
+	Jitter   float64
+	Steps    int
+	Cap      time.Duration
+}
+
+// Step returns the wait before the given check, counting from 1 for the wait
+// after the first check.
+func (b Backoff) Step(n int) time.Duration {
+	d := b.Duration
+	for i := 1; i < n && (b.Cap == 0 || d < b.Cap); i++ {
+		if b.Factor > 0 {
+			d = time.Duration(float64(d) * b.Factor)
+		}
+	}
+	if b.Cap != 0 && d > b.Cap {
+		d = b.Cap
+	}
+	if b.Jitter > 0 {
+		d = Jitter(d, b.Jitter)
+	}
+	return d
+}
+
+// ExponentialBackoff checks condition right away, then waits as backoff says
+// between further checks, until it is satisfied, fails, or has been checked
+// backoff.Steps times, in which case ErrWaitTimeout is returned.
+func ExponentialBackoff(backoff Backoff, condition ConditionFunc) error {
+	for n := 1; n <= backoff.Steps; n++ {
+		if done, err := condition(); err != nil || done {
+			return err
+		}
+		if n < backoff.Steps {
+			time.Sleep(backoff.Step(n))
+		}
+	}
+	return ErrWaitTimeout
+}
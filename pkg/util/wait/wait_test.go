@@ -0,0 +1,187 @@
+package wait
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// countingCondition returns a condition which is satisfied on its nth check, and
+// a pointer to the number of checks.
+func countingCondition(n int) (ConditionFunc, *int) {
+	count := 0
+	return func() (bool, error) {
+		count++
+		return count >= n, nil
+	}, &count
+}
+
+func TestPoll(t *testing.T) {
+	condition, count := countingCondition(3)
+	if err := Poll(time.Millisecond, time.Minute, condition); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e, a := 3, *count; e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+}
+
+func TestPollWaitsBeforeTheFirstCheck(t *testing.T) {
+	condition, count := countingCondition(1)
+	if err := Poll(time.Hour, 10*time.Millisecond, condition); err != ErrWaitTimeout {
+		t.Errorf("expected %v, got %v", ErrWaitTimeout, err)
+	}
+	if e, a := 0, *count; e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+}
+
+func TestPollImmediate(t *testing.T) {
+	condition, count := countingCondition(1)
+	if err := PollImmediate(time.Hour, time.Minute, condition); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e, a := 1, *count; e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+}
+
+func TestPollTimeout(t *testing.T) {
+	never := func() (bool, error) { return false, nil }
+	if err := Poll(time.Millisecond, 20*time.Millisecond, never); err != ErrWaitTimeout {
+		t.Errorf("expected %v, got %v", ErrWaitTimeout, err)
+	}
+}
+
+func TestPollError(t *testing.T) {
+	expected := errors.New("failed")
+	count := 0
+	err := PollImmediate(time.Millisecond, time.Minute, func() (bool, error) {
+		count++
+		return false, expected
+	})
+	if err != expected {
+		t.Errorf("expected %v, got %v", expected, err)
+	}
+	if e, a := 1, count; e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+}
+
+func TestPollUntil(t *testing.T) {
+	stopCh := make(chan struct{})
+	checked := make(chan struct{}, 1)
+	result := make(chan error)
+	go func() {
+		result <- PollUntil(time.Millisecond, func() (bool, error) {
+			select {
+			case checked <- struct{}{}:
+			default:
+			}
+			return false, nil
+		}, stopCh)
+	}()
+	<-checked
+	close(stopCh)
+	if err := <-result; err != ErrWaitTimeout {
+		t.Errorf("expected %v, got %v", ErrWaitTimeout, err)
+	}
+
+	condition, _ := countingCondition(2)
+	if err := PollUntil(time.Millisecond, condition, make(chan struct{})); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestTimeoutChannelStop(t *testing.T) {
+	ch, stop := timeoutChannel(10 * time.Millisecond)
+	if !stop() {
+		t.Fatalf("expected the timer to be stopped")
+	}
+	select {
+	case <-ch:
+		t.Errorf("expected the channel to stay open")
+	case <-time.After(30 * time.Millisecond):
+	}
+}
+
+func TestJitter(t *testing.T) {
+	table := map[string]struct {
+		maxFactor float64
+		max       time.Duration
+	}{
+		"factor":      {0.5, 150 * time.Millisecond},
+		"zero factor": {0, 200 * time.Millisecond},
+		"negative":    {-1, 200 * time.Millisecond},
+	}
+	for name, item := range table {
+		for i := 0; i < 100; i++ {
+			d := Jitter(100*time.Millisecond, item.maxFactor)
+			if d < 100*time.Millisecond || d > item.max {
+				t.Errorf("%s: expected a duration between 100ms and %v, got %v", name, item.max, d)
+			}
+		}
+	}
+}
+
+func TestBackoffStep(t *testing.T) {
+	table := map[string]struct {
+		backoff  Backoff
+		expected []time.Duration
+	}{
+		"exponential": {
+			Backoff{Duration: time.Second, Factor: 2},
+			[]time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second},
+		},
+		"capped": {
+			Backoff{Duration: time.Second, Factor: 3, Cap: 5 * time.Second},
+			[]time.Duration{time.Second, 3 * time.Second, 5 * time.Second, 5 * time.Second},
+		},
+		"constant": {
+			Backoff{Duration: time.Second},
+			[]time.Duration{time.Second, time.Second, time.Second},
+		},
+	}
+	for name, item := range table {
+		for i, e := range item.expected {
+			if a := item.backoff.Step(i + 1); e != a {
+				t.Errorf("%s: step %d: expected %v, got %v", name, i+1, e, a)
+			}
+		}
+	}
+}
+
+func TestBackoffStepJitter(t *testing.T) {
+	backoff := Backoff{Duration: time.Second, Factor: 2, Jitter: 0.5}
+	for i := 0; i < 100; i++ {
+		if d := backoff.Step(2); d < 2*time.Second || d > 3*time.Second {
+			t.Errorf("expected a duration between 2s and 3s, got %v", d)
+		}
+	}
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	backoff := Backoff{Duration: time.Millisecond, Factor: 2, Steps: 4}
+
+	condition, count := countingCondition(3)
+	if err := ExponentialBackoff(backoff, condition); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e, a := 3, *count; e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+
+	condition, count = countingCondition(5)
+	if err := ExponentialBackoff(backoff, condition); err != ErrWaitTimeout {
+		t.Errorf("expected %v, got %v", ErrWaitTimeout, err)
+	}
+	if e, a := 4, *count; e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+
+	expected := errors.New("failed")
+	err := ExponentialBackoff(backoff, func() (bool, error) { return false, expected })
+	if err != expected {
+		t.Errorf("expected %v, got %v", expected, err)
+	}
+}
@@ -0,0 +1,6 @@
+// Package wsstream carries the streams of processes and connections in containers
+// over websockets, as used to exec and attach into containers, and to forward
+// ports, through the apiserver. The standard streams of a process are multiplexed
+// over a single websocket: every message is binary, and starts with a byte naming
+// its channel. A forwarded connection is tunneled as it is.
+package wsstream
@@ -0,0 +1,134 @@
+package wsstream
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/net/websocket"
+)
+
+// The channels of a stream. Messages on StdinChannel go to the process, the
+// others come from it.
+const (
+	StdinChannel byte = iota
+	StdoutChannel
+	StderrChannel
+	// ErrorChannel carries a message explaining why the process could not be
+	// run, or failed, just before the stream is closed.
+	ErrorChannel
+)
+
+// Write sends data as a single message on channel. An empty message on
+// StdinChannel means the end of input.
+func Write(ws *websocket.Conn, channel byte, data []byte) error {
+	return websocket.Message.Send(ws, append([]byte{channel}, data...))
+}
+
+// Read receives the next message, and returns its channel and data.
+func Read(ws *websocket.Conn) (channel byte, data []byte, err error) {
+	var message []byte
+	if err := websocket.Message.Receive(ws, &message); err != nil {
+		return 0, nil, err
+	}
+	if len(message) == 0 {
+		return 0, nil, errors.New("message with no channel")
+	}
+	return message[0], message[1:], nil
+}
+
+// CopyIn sends everything read from r on StdinChannel, followed by an empty
+// message once r is exhausted.
+func CopyIn(ws *websocket.Conn, r io.Reader) error {
+	buf := make([]byte, 4096)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			if err := Write(ws, StdinChannel, buf[:n]); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return Write(ws, StdinChannel, nil)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// CopyOut writes the messages received on StdoutChannel and StderrChannel to
+// stdout and stderr until the stream is closed. Either may be nil to discard
+// its output. A message on ErrorChannel is returned as an error.
+func CopyOut(ws *websocket.Conn, stdout, stderr io.Writer) error {
+	for {
+		channel, data, err := Read(ws)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var w io.Writer
+		switch channel {
+		case StdoutChannel:
+			w = stdout
+		case StderrChannel:
+			w = stderr
+		case ErrorChannel:
+			return fmt.Errorf("%s", data)
+		default:
+			return fmt.Errorf("unexpected message on channel %d", channel)
+		}
+		if w == nil {
+			continue
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+}
+
+// Relay passes messages between a and b, unchanged, until either is closed.
+func Relay(a, b *websocket.Conn) {
+	done := make(chan struct{}, 2)
+	relay := func(from, to *websocket.Conn) {
+		for {
+			var message []byte
+			if err := websocket.Message.Receive(from, &message); err != nil {
+				break
+			}
+			if err := websocket.Message.Send(to, message); err != nil {
+				break
+			}
+		}
+		done <- struct{}{}
+	}
+	go relay(a, b)
+	go relay(b, a)
+	<-done
+	// Ends the other direction.
+	a.Close()
+	b.Close()
+	<-done
+}
+
+// Tunnel copies data between ws and conn, in both directions, until either is
+// closed, then closes both. Websocket messages carry the data as it is.
+func Tunnel(ws *websocket.Conn, conn io.ReadWriteCloser) {
+	ws.PayloadType = websocket.BinaryFrame
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(conn, ws)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(ws, conn)
+		done <- struct{}{}
+	}()
+	<-done
+	// Ends the other direction.
+	ws.Close()
+	conn.Close()
+	<-done
+}
@@ -25,6 +25,9 @@ const (
 	Added    EventType = "ADDED"
 	Modified EventType = "MODIFIED"
 	Deleted  EventType = "DELETED"
+	// Error means the watch failed; Object describes why, and is usually an
+	// *api.Status.
+	Error EventType = "ERROR"
 )
 
 // Event represents a single event to a watched resource.
@@ -49,6 +52,14 @@ func NewFake() *FakeWatcher {
 	}
 }
 
+// NewFakeWithChanSize returns a FakeWatcher which can hold size events before
+// they are received, so that they can be sent before anyone receives them.
+func NewFakeWithChanSize(size int) *FakeWatcher {
+	return &FakeWatcher{
+		result: make(chan Event, size),
+	}
+}
+
 func (f *FakeWatcher) Stop() {
 	f.Lock()
 	defer f.Unlock()
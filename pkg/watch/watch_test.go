@@ -52,3 +52,24 @@ func TestFake(t *testing.T) {
 	go sender()
 	consumer(f)
 }
+
+func TestFakeWithChanSize(t *testing.T) {
+	f := NewFakeWithChanSize(2)
+	// Neither blocks, as the channel holds both events.
+	f.Add(testType("foo"))
+	f.Modify(testType("bar"))
+	f.Stop()
+
+	for _, expect := range []EventType{Added, Modified} {
+		got, ok := <-f.ResultChan()
+		if !ok {
+			t.Fatalf("closed early")
+		}
+		if e, a := expect, got.Type; e != a {
+			t.Errorf("Expected %v, got %v", e, a)
+		}
+	}
+	if _, stillOpen := <-f.ResultChan(); stillOpen {
+		t.Errorf("Never stopped")
+	}
+}